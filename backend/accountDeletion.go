@@ -0,0 +1,233 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/Shayaan-Kashif/Database-Project/internal/database"
+	"github.com/Shayaan-Kashif/Database-Project/internal/mail"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultAccountDeletionGraceDays = 30
+	// how often the server deletes the accounts whose grace period is over
+	accountDeletionInterval = time.Hour
+)
+
+// accountDeletionGraceDays reads ACCOUNT_DELETION_GRACE_DAYS, how many days a
+// user can cancel the deletion of their account.
+func accountDeletionGraceDays() (int, error) {
+	raw := os.Getenv("ACCOUNT_DELETION_GRACE_DAYS")
+	if raw == "" {
+		return defaultAccountDeletionGraceDays, nil
+	}
+
+	days, err := strconv.Atoi(raw)
+	if err != nil || days < 0 {
+		return 0, errors.New("ACCOUNT_DELETION_GRACE_DAYS must be a whole number of days")
+	}
+
+	return days, nil
+}
+
+// deleteUser schedules the deletion of the signed in user's account, it is
+// deleted by deleteScheduledUsers once the grace period is over.
+func (cfg *apiConfig) deleteUser(res http.ResponseWriter, req *http.Request) {
+	userID := req.Context().Value(ctxUserID).(uuid.UUID)
+
+	graceDays, err := accountDeletionGraceDays()
+
+	if err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	scheduled, err := cfg.dbQueries.ScheduleUserDeletion(req.Context(), database.ScheduleUserDeletionParams{
+		GraceDays: int32(graceDays),
+		ID:        userID,
+	})
+
+	if err == sql.ErrNoRows {
+		respondWithError(res, http.StatusConflict, "the deletion of the account is already scheduled")
+		return
+	} else if err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	scheduledAt := scheduled.Time
+
+	auditAfter(req.Context(), cfg.dbQueries, auditEntry{
+		Action:     "user.deletion_schedule",
+		TargetType: "user",
+		TargetID:   userID,
+		After: struct {
+			DeletionScheduledAt time.Time `json:"deletionScheduledAt"`
+		}{scheduledAt},
+	})
+
+	//without a grace period there is nothing to wait for
+	if graceDays == 0 {
+		if _, err := cfg.deleteScheduledUser(req.Context(), userID); err != nil {
+			respondWithError(res, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		respondWithJSON(res, http.StatusOK, struct {
+			Status string `json:"status"`
+		}{"The user has been deleted"})
+		return
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		if err := cfg.sendAccountDeletionNotice(ctx, userID, scheduledAt); err != nil {
+			log.Printf("Error sending the account deletion email: %s", err)
+		}
+	}()
+
+	respondWithJSON(res, http.StatusAccepted, struct {
+		Status              string    `json:"status"`
+		DeletionScheduledAt time.Time `json:"deletionScheduledAt"`
+	}{
+		Status:              "The account will be deleted at deletionScheduledAt unless the deletion is canceled",
+		DeletionScheduledAt: scheduledAt,
+	})
+}
+
+func (cfg *apiConfig) sendAccountDeletionNotice(ctx context.Context, userID uuid.UUID, scheduledAt time.Time) error {
+	userDB, err := cfg.dbQueries.GetUserFromID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	return cfg.mail.Send(ctx, mail.Message{
+		To:      userDB.Email,
+		Subject: "Your ParkingGO account will be deleted",
+		Body: fmt.Sprintf(
+			"The deletion of your ParkingGO account was requested. It will be deleted on %s UTC, with your reviews and sessions. Your parking logs are kept without your account.\n\nUntil then you can download your data, or login and cancel the deletion to keep your account:\n%s\n",
+			scheduledAt.UTC().Format("January 2, 2006 15:04"), appURL(),
+		),
+	})
+}
+
+func (cfg *apiConfig) cancelAccountDeletion(res http.ResponseWriter, req *http.Request) {
+	userID := req.Context().Value(ctxUserID).(uuid.UUID)
+
+	rows, err := cfg.dbQueries.CancelUserDeletion(req.Context(), userID)
+
+	if err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if rows == 0 {
+		respondWithError(res, http.StatusConflict, "the deletion of the account is not scheduled")
+		return
+	}
+
+	auditAfter(req.Context(), cfg.dbQueries, auditEntry{
+		Action:     "user.deletion_cancel",
+		TargetType: "user",
+		TargetID:   userID,
+	})
+
+	respondWithJSON(res, http.StatusOK, struct {
+		Status string `json:"status"`
+	}{"The deletion of the account has been canceled"})
+}
+
+// deleteScheduledUser deletes the user if their deletion is due, exiting the
+// lot first if they are parked. Their parking logs are kept without the user.
+// It reports false when the deletion was canceled or the user is already gone.
+func (cfg *apiConfig) deleteScheduledUser(ctx context.Context, userID uuid.UUID) (bool, error) {
+	tx, err := cfg.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+
+	defer tx.Rollback()
+
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	//locked so a cancel cannot slip in between the check and the delete
+	userDB, err := qtx.LockUserDueForDeletion(ctx, userID)
+	if err == sql.ErrNoRows {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	//the slot would stay occupied otherwise
+	if err := exitLot(ctx, qtx, userDB); err != nil {
+		return false, err
+	}
+
+	if _, err := qtx.DeleteUser(ctx, userDB.ID); err != nil {
+		return false, err
+	}
+
+	if err := auditUser(ctx, qtx, "user.scheduled_delete", userDB); err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+
+	cfg.tokenVersions.forget(userDB.ID)
+
+	return true, nil
+}
+
+// deleteScheduledUsers deletes every user whose grace period is over and
+// returns how many were deleted.
+func (cfg *apiConfig) deleteScheduledUsers(ctx context.Context) (int, error) {
+	userIDs, err := cfg.dbQueries.GetUsersDueForDeletion(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	deleted := 0
+
+	for _, userID := range userIDs {
+		ok, err := cfg.deleteScheduledUser(ctx, userID)
+		if err != nil {
+			return deleted, err
+		}
+
+		if ok {
+			deleted++
+		}
+	}
+
+	return deleted, nil
+}
+
+// maintainAccountDeletions deletes the accounts whose grace period is over
+// every accountDeletionInterval while the server runs.
+func (cfg *apiConfig) maintainAccountDeletions(ctx context.Context) {
+	ctx = taskContext(ctx, "account deletion")
+
+	for {
+		if _, err := cfg.deleteScheduledUsers(ctx); err != nil {
+			log.Printf("Error deleting scheduled accounts: %s", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(accountDeletionInterval):
+		}
+	}
+}
@@ -0,0 +1,443 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+
+	"github.com/Shayaan-Kashif/Database-Project/internal/auth"
+	"github.com/Shayaan-Kashif/Database-Project/internal/database"
+	"github.com/google/uuid"
+)
+
+// notSelf answers 409 when an admin tries an action on their own account that
+// would lock them out, ok is false if it did.
+func notSelf(res http.ResponseWriter, req *http.Request, userDB database.User) bool {
+	if userDB.ID == req.Context().Value(ctxUserID).(uuid.UUID) {
+		respondWithError(res, http.StatusConflict, "admins cannot do this to their own account")
+		return false
+	}
+
+	return true
+}
+
+// exitLot records the user leaving the lot they are parked at, like an exit
+// through /api/parkingLogs. Nothing happens if they are not parked. userDB has
+// to be read with the row locked, or a concurrent exit would free the slot and
+// log the exit twice.
+func exitLot(ctx context.Context, qtx *database.Queries, userDB database.User) error {
+	if !userDB.ParkingLotID.Valid {
+		return nil
+	}
+
+	err := qtx.UpdateOccupiedSlot(ctx, database.UpdateOccupiedSlotParams{
+		Occupiedslots: -1,
+		ID:            userDB.ParkingLotID.UUID,
+	})
+
+	if err != nil {
+		return err
+	}
+
+	err = qtx.UpdateUserParkingLot(ctx, database.UpdateUserParkingLotParams{
+		ParkingLotID: uuid.NullUUID{},
+		ID:           userDB.ID,
+	})
+
+	if err != nil {
+		return err
+	}
+
+	_, err = qtx.CreateLog(ctx, database.CreateLogParams{
+		UserID:       uuid.NullUUID{UUID: userDB.ID, Valid: true},
+		ParkingLotID: userDB.ParkingLotID.UUID,
+		EventType:    "exit",
+	})
+
+	return err
+}
+
+// updateUserRole changes the role of a user. Lot managers are made with
+// /api/users/{userID}/managedLots since they need lots.
+func (cfg *apiConfig) updateUserRole(res http.ResponseWriter, req *http.Request) {
+	userDB, ok := cfg.managedUser(res, req)
+	if !ok || !notSelf(res, req, userDB) {
+		return
+	}
+
+	reqStruct := struct {
+		Role     *string    `json:"role"`
+		CampusID *uuid.UUID `json:"campusID"`
+	}{}
+
+	if err := decodeJSON(req, &reqStruct); err != nil {
+		respondWithError(res, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if reqStruct.Role == nil {
+		respondWithError(res, http.StatusBadRequest, "invalid JSON structure")
+		return
+	}
+
+	if *reqStruct.Role != "admin" && *reqStruct.Role != "user" && *reqStruct.Role != "enforcement" {
+		respondWithError(res, http.StatusBadRequest, "role must be admin, user or enforcement")
+		return
+	}
+
+	adminID := req.Context().Value(ctxUserID).(uuid.UUID)
+
+	scope, err := cfg.adminCampus(req.Context(), adminID)
+	if err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	campusID := uuid.NullUUID{}
+	if reqStruct.CampusID != nil {
+		campusID = uuid.NullUUID{UUID: *reqStruct.CampusID, Valid: true}
+	}
+
+	//campus admins can make admins of their own campus, enforcement spans every campus
+	if scope.Valid {
+		if *reqStruct.Role == "enforcement" || (campusID.Valid && campusID != scope) {
+			respondWithError(res, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+
+		if *reqStruct.Role == "admin" {
+			campusID = scope
+		}
+	}
+
+	if campusID.Valid && *reqStruct.Role != "admin" {
+		respondWithError(res, http.StatusBadRequest, "only admins can have a campusID")
+		return
+	}
+
+	tx, err := cfg.db.BeginTx(req.Context(), nil)
+
+	if err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	defer tx.Rollback()
+
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	if campusID.Valid {
+		err = qtx.SetUserAdminCampus(req.Context(), database.SetUserAdminCampusParams{
+			AdminCampusID: campusID,
+			ID:            userDB.ID,
+		})
+	} else {
+		_, err = qtx.UpdateUserRole(req.Context(), database.UpdateUserRoleParams{
+			Role: *reqStruct.Role,
+			ID:   userDB.ID,
+		})
+	}
+
+	if err != nil {
+		hasPgErr, message := handlePgConstraints(err)
+
+		if hasPgErr {
+			respondWithError(res, http.StatusBadRequest, message)
+			return
+		}
+
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if err := qtx.DeleteManagedLots(req.Context(), userDB.ID); err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	//the role is in the access token, the next refresh gets one with the new role
+	if err := qtx.BumpTokenVersion(req.Context(), userDB.ID); err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if err := auditUser(req.Context(), qtx, "user.role", userDB); err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	cfg.tokenVersions.forget(userDB.ID)
+
+	respondWithJSON(res, http.StatusOK, struct {
+		Status string `json:"status"`
+	}{"The role has been updated"})
+}
+
+func (cfg *apiConfig) disableUser(res http.ResponseWriter, req *http.Request) {
+	userDB, ok := cfg.managedUser(res, req)
+	if !ok || !notSelf(res, req, userDB) {
+		return
+	}
+
+	tx, err := cfg.db.BeginTx(req.Context(), nil)
+
+	if err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	defer tx.Rollback()
+
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	if _, err := qtx.DisableUser(req.Context(), userDB.ID); err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	//disabled users should not be able to refresh their access token either
+	if err := qtx.RevokeAllUserTokens(req.Context(), userDB.ID); err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if err := qtx.BumpTokenVersion(req.Context(), userDB.ID); err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if err := auditUser(req.Context(), qtx, "user.disable", userDB); err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	cfg.tokenVersions.forget(userDB.ID)
+
+	respondWithJSON(res, http.StatusOK, struct {
+		Status string `json:"status"`
+	}{"The user has been disabled"})
+}
+
+func (cfg *apiConfig) enableUser(res http.ResponseWriter, req *http.Request) {
+	userDB, ok := cfg.managedUser(res, req)
+	if !ok {
+		return
+	}
+
+	tx, err := cfg.db.BeginTx(req.Context(), nil)
+
+	if err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	defer tx.Rollback()
+
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	if _, err := qtx.EnableUser(req.Context(), userDB.ID); err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if err := auditUser(req.Context(), qtx, "user.enable", userDB); err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondWithJSON(res, http.StatusOK, struct {
+		Status string `json:"status"`
+	}{"The user has been enabled"})
+}
+
+// forceExitUser takes a user out of their lot, for cars that left without
+// logging the exit.
+func (cfg *apiConfig) forceExitUser(res http.ResponseWriter, req *http.Request) {
+	userDB, ok := cfg.managedUser(res, req)
+	if !ok {
+		return
+	}
+
+	tx, err := cfg.db.BeginTx(req.Context(), nil)
+
+	if err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	defer tx.Rollback()
+
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	//locked until commit, the user may leave or be exited meanwhile
+	userDB, err = qtx.GetUserFromIDForUpdate(req.Context(), userDB.ID)
+
+	if err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if !userDB.ParkingLotID.Valid {
+		respondWithError(res, http.StatusConflict, "the user is not parked at a lot")
+		return
+	}
+
+	if err := exitLot(req.Context(), qtx, userDB); err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if err := auditUser(req.Context(), qtx, "user.force_exit", userDB); err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondWithJSON(res, http.StatusOK, struct {
+		Status string `json:"status"`
+	}{"The user has been exited from the lot"})
+}
+
+// adminResetPassword replaces the password with a random one nobody knows,
+// logs the user out everywhere and emails them a reset link.
+func (cfg *apiConfig) adminResetPassword(res http.ResponseWriter, req *http.Request) {
+	userDB, ok := cfg.managedUser(res, req)
+	if !ok {
+		return
+	}
+
+	password, err := auth.MakeRefreshToken()
+	if err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	hashedPassword, err := auth.Hashpassword(password)
+	if err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	tx, err := cfg.db.BeginTx(req.Context(), nil)
+
+	if err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	defer tx.Rollback()
+
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	err = qtx.UpdateUserPassword(req.Context(), database.UpdateUserPasswordParams{
+		HashedPassword: hashedPassword,
+		ID:             userDB.ID,
+	})
+
+	if err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if err := qtx.RevokeAllUserTokens(req.Context(), userDB.ID); err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if err := qtx.BumpTokenVersion(req.Context(), userDB.ID); err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if err := auditUser(req.Context(), qtx, "user.reset_password", userDB); err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	cfg.tokenVersions.forget(userDB.ID)
+
+	//the password is reset either way, the user can still ask for a link with /api/password/forgot
+	if err := cfg.sendPasswordReset(req.Context(), userDB.Email); err != nil {
+		log.Printf("Error sending the password reset email: %s", err)
+	}
+
+	respondWithJSON(res, http.StatusOK, struct {
+		Status string `json:"status"`
+	}{"The password has been reset, the user has been emailed a link to choose a new one"})
+}
+
+func (cfg *apiConfig) adminDeleteUser(res http.ResponseWriter, req *http.Request) {
+	userDB, ok := cfg.managedUser(res, req)
+	if !ok || !notSelf(res, req, userDB) {
+		return
+	}
+
+	tx, err := cfg.db.BeginTx(req.Context(), nil)
+
+	if err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	defer tx.Rollback()
+
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	userDB, err = qtx.GetUserFromIDForUpdate(req.Context(), userDB.ID)
+
+	if err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	//the slot would stay occupied otherwise
+	if err := exitLot(req.Context(), qtx, userDB); err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if _, err := qtx.DeleteUser(req.Context(), userDB.ID); err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if err := auditUser(req.Context(), qtx, "user.delete", userDB); err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	cfg.tokenVersions.forget(userDB.ID)
+
+	respondWithJSON(res, http.StatusOK, struct {
+		Status string `json:"status"`
+	}{"The user has been deleted"})
+}
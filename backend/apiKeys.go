@@ -0,0 +1,369 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"log"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Shayaan-Kashif/Database-Project/internal/auth"
+	"github.com/Shayaan-Kashif/Database-Project/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	// requests each API key may make per apiKeyRateWindow, users are not
+	// counted against it
+	apiKeyRateLimit  = 120
+	apiKeyRateWindow = time.Minute
+	maxAPIKeyTTL     = 365 * 24 * time.Hour
+)
+
+var errInvalidAPIKey = errors.New("invalid API key")
+
+// rateLimiter counts requests per key in fixed windows. It is kept in memory,
+// so every server instance has its own count.
+type rateLimiter struct {
+	limit  int
+	window time.Duration
+
+	mu      sync.Mutex
+	windows map[uuid.UUID]rateWindow
+}
+
+type rateWindow struct {
+	start    time.Time
+	requests int
+}
+
+func newRateLimiter(limit int, window time.Duration) *rateLimiter {
+	return &rateLimiter{
+		limit:   limit,
+		window:  window,
+		windows: map[uuid.UUID]rateWindow{},
+	}
+}
+
+// allow counts a request of id, wait is how long until the next window when it
+// is over the limit.
+func (l *rateLimiter) allow(id uuid.UUID, now time.Time) (ok bool, wait time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	current := l.windows[id]
+
+	if now.Sub(current.start) >= l.window {
+		//forget keys that stopped calling, so the map does not grow forever
+		if len(l.windows) > 1000 {
+			for key, w := range l.windows {
+				if now.Sub(w.start) >= l.window {
+					delete(l.windows, key)
+				}
+			}
+		}
+
+		current = rateWindow{start: now}
+	}
+
+	if current.requests >= l.limit {
+		return false, current.start.Add(l.window).Sub(now)
+	}
+
+	current.requests++
+	l.windows[id] = current
+
+	return true, 0
+}
+
+// parseScopes is the inverse of strings.Join(scopes, " ").
+func parseScopes(scopes string) []permission {
+	out := []permission{}
+	for _, scope := range strings.Fields(scopes) {
+		out = append(out, permission(scope))
+	}
+	return out
+}
+
+// checkAPIKey returns the key and its creator's role, as long as the key is
+// not revoked or expired and the creator is not disabled.
+func (cfg *apiConfig) checkAPIKey(ctx context.Context, key, prefix string) (database.GetAPIKeyFromPrefixRow, error) {
+	keyDB, err := cfg.dbQueries.GetAPIKeyFromPrefix(ctx, prefix)
+
+	if err == sql.ErrNoRows {
+		return database.GetAPIKeyFromPrefixRow{}, errInvalidAPIKey
+	} else if err != nil {
+		return database.GetAPIKeyFromPrefixRow{}, err
+	}
+
+	if subtle.ConstantTimeCompare([]byte(auth.HashToken(key)), []byte(keyDB.KeyHash)) != 1 {
+		return database.GetAPIKeyFromPrefixRow{}, errInvalidAPIKey
+	}
+
+	if keyDB.RevokedAt.Valid || (keyDB.ExpiresAt.Valid && keyDB.ExpiresAt.Time.Before(time.Now())) || keyDB.DisabledAt.Valid {
+		return database.GetAPIKeyFromPrefixRow{}, errInvalidAPIKey
+	}
+
+	return keyDB, nil
+}
+
+// serveWithAPIKey is authenticate for API keys. The request runs as the key's
+// creator, authorize then limits it to the key's scopes.
+func (cfg *apiConfig) serveWithAPIKey(res http.ResponseWriter, req *http.Request, key, prefix string, next http.Handler) {
+	keyDB, err := cfg.checkAPIKey(req.Context(), key, prefix)
+
+	if err == errInvalidAPIKey {
+		respondWithError(res, http.StatusUnauthorized, err.Error())
+		return
+	} else if err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if ok, wait := cfg.apiKeyLimiter.allow(keyDB.ID, time.Now()); !ok {
+		res.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		respondWithError(res, http.StatusTooManyRequests, "API key rate limit reached, try again later")
+		return
+	}
+
+	//a failed update only loses the timestamp, the request can go ahead
+	if err := cfg.dbQueries.TouchAPIKey(req.Context(), keyDB.ID); err != nil {
+		log.Printf("Error updating the last use of API key %s: %s", keyDB.ID, err)
+	}
+
+	ctx := req.Context()
+
+	ctx = context.WithValue(ctx, ctxUserID, keyDB.CreatedBy)
+	ctx = context.WithValue(ctx, ctxRole, keyDB.Role)
+	ctx = context.WithValue(ctx, ctxAPIKeyScopes, parseScopes(keyDB.Scopes))
+	ctx = context.WithValue(ctx, ctxAuditSource, requestAuditSource(req, keyDB.CreatedBy, keyDB.Role, uuid.NullUUID{UUID: keyDB.ID, Valid: true}))
+
+	next.ServeHTTP(res, req.WithContext(ctx))
+}
+
+type apiKeyJSON struct {
+	ID         uuid.UUID    `json:"id"`
+	Name       string       `json:"name"`
+	Prefix     string       `json:"prefix"`
+	Scopes     []permission `json:"scopes"`
+	CreatedBy  uuid.UUID    `json:"createdBy"`
+	ExpiresAt  *time.Time   `json:"expiresAt"`
+	LastUsedAt *time.Time   `json:"lastUsedAt"`
+	RevokedAt  *time.Time   `json:"revokedAt"`
+	CreatedAt  time.Time    `json:"createdAt"`
+}
+
+func toAPIKeyJSON(k database.ApiKey) apiKeyJSON {
+	out := apiKeyJSON{
+		ID:        k.ID,
+		Name:      k.Name,
+		Prefix:    k.KeyPrefix,
+		Scopes:    parseScopes(k.Scopes),
+		CreatedBy: k.CreatedBy,
+		CreatedAt: k.CreatedAt,
+	}
+
+	if k.ExpiresAt.Valid {
+		out.ExpiresAt = &k.ExpiresAt.Time
+	}
+	if k.LastUsedAt.Valid {
+		out.LastUsedAt = &k.LastUsedAt.Time
+	}
+	if k.RevokedAt.Valid {
+		out.RevokedAt = &k.RevokedAt.Time
+	}
+
+	return out
+}
+
+func (cfg *apiConfig) createAPIKey(res http.ResponseWriter, req *http.Request) {
+	reqStruct := struct {
+		Name          *string       `json:"name"`
+		Scopes        *[]permission `json:"scopes"`
+		ExpiresInDays *int          `json:"expiresInDays"`
+	}{}
+
+	if err := decodeJSON(req, &reqStruct); err != nil {
+		respondWithError(res, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if reqStruct.Name == nil || reqStruct.Scopes == nil {
+		respondWithError(res, http.StatusBadRequest, "invalid JSON structure")
+		return
+	}
+
+	if *reqStruct.Name == "" || len(*reqStruct.Scopes) == 0 {
+		respondWithError(res, http.StatusBadRequest, "name and scopes cannot be empty")
+		return
+	}
+
+	role := req.Context().Value(ctxRole).(string)
+
+	scopes := []string{}
+	for _, scope := range *reqStruct.Scopes {
+		//a key that can make keys could outlive its own revocation
+		if scope == permAPIKeysManage || !hasPermission(role, scope) {
+			respondWithError(res, http.StatusBadRequest, "scope "+string(scope)+" cannot be granted")
+			return
+		}
+
+		if !slices.Contains(scopes, string(scope)) {
+			scopes = append(scopes, string(scope))
+		}
+	}
+
+	expiresAt := sql.NullTime{}
+	if reqStruct.ExpiresInDays != nil {
+		ttl := time.Duration(*reqStruct.ExpiresInDays) * 24 * time.Hour
+
+		if ttl <= 0 || ttl > maxAPIKeyTTL {
+			respondWithError(res, http.StatusBadRequest, "expiresInDays must be between 1 and 365")
+			return
+		}
+
+		expiresAt = sql.NullTime{Time: time.Now().Add(ttl), Valid: true}
+	}
+
+	key, prefix, err := auth.MakeAPIKey()
+	if err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	userID := req.Context().Value(ctxUserID).(uuid.UUID)
+
+	keyDB, err := cfg.dbQueries.CreateAPIKey(req.Context(), database.CreateAPIKeyParams{
+		Name:      *reqStruct.Name,
+		KeyPrefix: prefix,
+		KeyHash:   auth.HashToken(key),
+		Scopes:    strings.Join(scopes, " "),
+		CreatedBy: userID,
+		ExpiresAt: expiresAt,
+	})
+
+	if err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	//campus admins only see their own keys, and their entries
+	scope, err := cfg.adminCampus(req.Context(), userID)
+	if err != nil {
+		log.Printf("Error reading the campus of user %s: %s", userID, err)
+	}
+
+	auditAfter(req.Context(), cfg.dbQueries, auditEntry{
+		Action:     "apikey.create",
+		TargetType: "apikey",
+		TargetID:   keyDB.ID,
+		CampusID:   scope,
+		After:      toAPIKeyJSON(keyDB),
+	})
+
+	respondWithJSON(res, http.StatusCreated, struct {
+		apiKeyJSON
+		Key string `json:"key"`
+	}{
+		apiKeyJSON: toAPIKeyJSON(keyDB),
+		Key:        key,
+	})
+}
+
+func (cfg *apiConfig) getAPIKeys(res http.ResponseWriter, req *http.Request) {
+	userID := req.Context().Value(ctxUserID).(uuid.UUID)
+
+	scope, err := cfg.adminCampus(req.Context(), userID)
+	if err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	//campus admins only see their own keys
+	createdBy := uuid.NullUUID{}
+	if scope.Valid {
+		createdBy = uuid.NullUUID{UUID: userID, Valid: true}
+	}
+
+	keysDB, err := cfg.dbQueries.GetAPIKeys(req.Context(), createdBy)
+
+	if err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	response := make([]apiKeyJSON, 0, len(keysDB))
+	for _, k := range keysDB {
+		response = append(response, toAPIKeyJSON(k))
+	}
+
+	respondWithJSON(res, http.StatusOK, response)
+}
+
+func (cfg *apiConfig) revokeAPIKey(res http.ResponseWriter, req *http.Request) {
+	keyID, err := uuid.Parse(req.PathValue("keyID"))
+
+	if err != nil {
+		respondWithError(res, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	keyDB, err := cfg.dbQueries.GetAPIKeyFromID(req.Context(), keyID)
+
+	if err == sql.ErrNoRows {
+		respondWithError(res, http.StatusNotFound, "no API key exist for that keyID")
+		return
+	} else if err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	userID := req.Context().Value(ctxUserID).(uuid.UUID)
+
+	scope, err := cfg.adminCampus(req.Context(), userID)
+	if err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if scope.Valid && keyDB.CreatedBy != userID {
+		respondWithError(res, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	revoked, err := cfg.dbQueries.RevokeAPIKey(req.Context(), keyID)
+
+	if err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if revoked == 0 {
+		respondWithError(res, http.StatusConflict, "the API key has already been revoked")
+		return
+	}
+
+	after := toAPIKeyJSON(keyDB)
+	now := time.Now()
+	after.RevokedAt = &now
+
+	auditAfter(req.Context(), cfg.dbQueries, auditEntry{
+		Action:     "apikey.revoke",
+		TargetType: "apikey",
+		TargetID:   keyID,
+		CampusID:   scope,
+		Before:     toAPIKeyJSON(keyDB),
+		After:      after,
+	})
+
+	respondWithJSON(res, http.StatusOK, struct {
+		Status string `json:"status"`
+	}{"The API key has been revoked"})
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Shayaan-Kashif/Database-Project/internal/auth"
+	"github.com/Shayaan-Kashif/Database-Project/internal/database"
+	"github.com/google/uuid"
+)
+
+// TestCheckAPIKey checks only an unrevoked, unexpired key of an enabled
+// creator is accepted, and only with its full secret.
+func TestCheckAPIKey(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+
+	qtx := database.New(db).WithTx(tx)
+	cfg := &apiConfig{dbQueries: qtx}
+
+	userDB, err := qtx.CreateUser(ctx, database.CreateUserParams{
+		Name:           "API key test",
+		Email:          uuid.NewString() + "@example.com",
+		HashedPassword: "unused",
+		Role:           "admin",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	makeKey := func(expiresAt sql.NullTime) (database.ApiKey, string) {
+		t.Helper()
+
+		key, prefix, err := auth.MakeAPIKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		keyDB, err := qtx.CreateAPIKey(ctx, database.CreateAPIKeyParams{
+			Name:      "test",
+			KeyPrefix: prefix,
+			KeyHash:   auth.HashToken(key),
+			Scopes:    string(permLotsExport),
+			CreatedBy: userDB.ID,
+			ExpiresAt: expiresAt,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return keyDB, key
+	}
+
+	check := func(name, key string, wantErr error) {
+		t.Helper()
+
+		prefix, ok := auth.APIKeyLookupPrefix(key)
+		if !ok {
+			t.Fatalf("%s: %q is not shaped like an API key", name, key)
+		}
+
+		if _, err := cfg.checkAPIKey(ctx, key, prefix); err != wantErr {
+			t.Errorf("%s: checkAPIKey = %v, want %v", name, err, wantErr)
+		}
+	}
+
+	keyDB, key := makeKey(sql.NullTime{Time: time.Now().Add(time.Hour), Valid: true})
+	check("valid", key, nil)
+
+	otherKey, _, err := auth.MakeAPIKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	check("wrong secret", keyDB.KeyPrefix+otherKey[strings.LastIndex(otherKey, "_"):], errInvalidAPIKey)
+	check("unknown prefix", otherKey, errInvalidAPIKey)
+
+	_, expired := makeKey(sql.NullTime{Time: time.Now().Add(-time.Minute), Valid: true})
+	check("expired", expired, errInvalidAPIKey)
+
+	revokedDB, revoked := makeKey(sql.NullTime{})
+	if _, err := qtx.RevokeAPIKey(ctx, revokedDB.ID); err != nil {
+		t.Fatal(err)
+	}
+	check("revoked", revoked, errInvalidAPIKey)
+
+	if _, err := qtx.DisableUser(ctx, userDB.ID); err != nil {
+		t.Fatal(err)
+	}
+	check("disabled creator", key, errInvalidAPIKey)
+}
@@ -0,0 +1,466 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Shayaan-Kashif/Database-Project/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultAuditPageSize = 50
+	maxAuditPageSize     = 500
+)
+
+// auditSource is who made a change and how, every audit log entry written with
+// the context records it. withAuditSource sets it for requests and
+// authenticate adds the actor, commandContext sets it for the command line.
+type auditSource struct {
+	ActorID   uuid.NullUUID
+	ActorRole sql.NullString
+	APIKeyID  uuid.NullUUID
+	IP        string
+	UserAgent string
+	Method    string
+	Path      string
+}
+
+func requestAuditSource(req *http.Request, userID uuid.UUID, role string, apiKeyID uuid.NullUUID) auditSource {
+	return auditSource{
+		ActorID:   uuid.NullUUID{UUID: userID, Valid: true},
+		ActorRole: sql.NullString{String: role, Valid: true},
+		APIKeyID:  apiKeyID,
+		IP:        clientIP(req),
+		UserAgent: req.UserAgent(),
+		Method:    req.Method,
+		Path:      req.URL.Path,
+	}
+}
+
+// withAuditSource gives every request an audit source without an actor, so
+// the entries of requests that are not authenticated still record where they
+// came from.
+func withAuditSource(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		ctx := context.WithValue(req.Context(), ctxAuditSource, auditSource{
+			IP:        clientIP(req),
+			UserAgent: req.UserAgent(),
+			Method:    req.Method,
+			Path:      req.URL.Path,
+		})
+
+		next.ServeHTTP(res, req.WithContext(ctx))
+	})
+}
+
+// actorContext makes userID the actor of the audit source of ctx, for the
+// requests that act as a user without being authenticated like logins and
+// refreshes.
+func actorContext(ctx context.Context, userID uuid.UUID, role string) context.Context {
+	source, _ := ctx.Value(ctxAuditSource).(auditSource)
+
+	source.ActorID = uuid.NullUUID{UUID: userID, Valid: true}
+	source.ActorRole = sql.NullString{String: role, Valid: true}
+
+	return context.WithValue(ctx, ctxAuditSource, source)
+}
+
+// commandContext is the context of a command line command, its changes are
+// audited without an actor.
+func commandContext(command string) context.Context {
+	return context.WithValue(context.Background(), ctxAuditSource, auditSource{Method: "CLI", Path: command})
+}
+
+// taskContext is ctx for a background task of the server, its changes are
+// audited without an actor.
+func taskContext(ctx context.Context, task string) context.Context {
+	return context.WithValue(ctx, ctxAuditSource, auditSource{Method: "TASK", Path: task})
+}
+
+// auditEntry is one admin action. Before and After are the target as JSON,
+// nil when it did not exist before or after the action.
+type auditEntry struct {
+	Action     string
+	TargetType string
+	TargetID   uuid.UUID
+	CampusID   uuid.NullUUID
+	Before     any
+	After      any
+}
+
+// audit appends entry to the audit log. Pass the transaction of the change so
+// the entry is only kept when the change is.
+func audit(ctx context.Context, q *database.Queries, entry auditEntry) error {
+	source, _ := ctx.Value(ctxAuditSource).(auditSource)
+
+	before, err := json.Marshal(entry.Before)
+	if err != nil {
+		return err
+	}
+
+	after, err := json.Marshal(entry.After)
+	if err != nil {
+		return err
+	}
+
+	return q.CreateAuditEntry(ctx, database.CreateAuditEntryParams{
+		ActorID:    source.ActorID,
+		ActorRole:  source.ActorRole,
+		ApiKeyID:   source.APIKeyID,
+		Action:     entry.Action,
+		TargetType: entry.TargetType,
+		TargetID:   uuid.NullUUID{UUID: entry.TargetID, Valid: entry.TargetID != uuid.Nil},
+		CampusID:   entry.CampusID,
+		Before:     before,
+		After:      after,
+		Ip:         source.IP,
+		UserAgent:  source.UserAgent,
+		Method:     source.Method,
+		Path:       source.Path,
+	})
+}
+
+// auditAfter is audit for changes that are already committed, a failure is
+// only logged since the change cannot be undone anymore.
+func auditAfter(ctx context.Context, q *database.Queries, entry auditEntry) {
+	if err := audit(ctx, q, entry); err != nil {
+		log.Printf("Error writing the audit log entry %s of %s %s: %s", entry.Action, entry.TargetType, entry.TargetID, err)
+	}
+}
+
+// lotAudit is a parking lot as the audit log records it.
+type lotAudit struct {
+	ID            uuid.UUID `json:"id"`
+	Name          string    `json:"name"`
+	Slots         int32     `json:"slots"`
+	Occupiedslots int32     `json:"occupiedSlots"`
+	CampusID      uuid.UUID `json:"campusID"`
+}
+
+func toLotAudit(l database.Parkinglot) lotAudit {
+	return lotAudit{
+		ID:            l.ID,
+		Name:          l.Name,
+		Slots:         l.Slots,
+		Occupiedslots: l.Occupiedslots,
+		CampusID:      l.CampusID,
+	}
+}
+
+func lotAuditEntry(action string, before, after *database.Parkinglot) auditEntry {
+	entry := auditEntry{Action: action, TargetType: "lot"}
+
+	for _, l := range []*database.Parkinglot{before, after} {
+		if l != nil {
+			entry.TargetID = l.ID
+			entry.CampusID = uuid.NullUUID{UUID: l.CampusID, Valid: true}
+		}
+	}
+
+	if before != nil {
+		entry.Before = toLotAudit(*before)
+	}
+	if after != nil {
+		entry.After = toLotAudit(*after)
+	}
+
+	return entry
+}
+
+// userAudit is a user as the audit log records it. Names and emails are left
+// out, the id is enough to tell who it was while the user exists.
+type userAudit struct {
+	ID            uuid.UUID     `json:"id"`
+	Role          string        `json:"role"`
+	CampusID      uuid.NullUUID `json:"campusID"`
+	AdminCampusID uuid.NullUUID `json:"adminCampusID"`
+	ParkingLotID  uuid.NullUUID `json:"parkingLotID"`
+	Disabled      bool          `json:"disabled"`
+}
+
+func toUserAudit(u database.User) userAudit {
+	return userAudit{
+		ID:            u.ID,
+		Role:          u.Role,
+		CampusID:      u.CampusID,
+		AdminCampusID: u.AdminCampusID,
+		ParkingLotID:  u.ParkingLotID,
+		Disabled:      u.DisabledAt.Valid,
+	}
+}
+
+// userAuditEntry records action on a user, after is read again with q so it
+// has to run after the change in the same transaction. Deleted users have no
+// after.
+func userAuditEntry(ctx context.Context, q *database.Queries, action string, before database.User) (auditEntry, error) {
+	entry := auditEntry{
+		Action:     action,
+		TargetType: "user",
+		TargetID:   before.ID,
+		CampusID:   before.CampusID,
+		Before:     toUserAudit(before),
+	}
+
+	after, err := q.GetUserFromID(ctx, before.ID)
+
+	if err == nil {
+		entry.After = toUserAudit(after)
+	} else if err != sql.ErrNoRows {
+		return auditEntry{}, err
+	}
+
+	return entry, nil
+}
+
+// auditUser is userAuditEntry followed by audit.
+func auditUser(ctx context.Context, q *database.Queries, action string, before database.User) error {
+	entry, err := userAuditEntry(ctx, q, action, before)
+	if err != nil {
+		return err
+	}
+
+	return audit(ctx, q, entry)
+}
+
+// campusAudit is a campus as the audit log records it.
+type campusAudit struct {
+	ID       uuid.UUID `json:"id"`
+	Name     string    `json:"name"`
+	TimeZone string    `json:"timeZone"`
+}
+
+func toCampusAudit(c database.Campus) campusAudit {
+	return campusAudit{ID: c.ID, Name: c.Name, TimeZone: c.TimeZone}
+}
+
+type auditEntryJSON struct {
+	ID         uuid.UUID       `json:"id"`
+	ActorID    uuid.NullUUID   `json:"actorID"`
+	ActorRole  *string         `json:"actorRole"`
+	APIKeyID   uuid.NullUUID   `json:"apiKeyID"`
+	Action     string          `json:"action"`
+	TargetType string          `json:"targetType"`
+	TargetID   uuid.NullUUID   `json:"targetID"`
+	CampusID   uuid.NullUUID   `json:"campusID"`
+	Before     json.RawMessage `json:"before"`
+	After      json.RawMessage `json:"after"`
+	IP         string          `json:"ip"`
+	UserAgent  string          `json:"userAgent"`
+	Method     string          `json:"method"`
+	Path       string          `json:"path"`
+	CreatedAt  time.Time       `json:"createdAt"`
+}
+
+func toAuditEntryJSON(a database.AuditLog) auditEntryJSON {
+	out := auditEntryJSON{
+		ID:         a.ID,
+		ActorID:    a.ActorID,
+		APIKeyID:   a.ApiKeyID,
+		Action:     a.Action,
+		TargetType: a.TargetType,
+		TargetID:   a.TargetID,
+		CampusID:   a.CampusID,
+		Before:     a.Before,
+		After:      a.After,
+		IP:         a.Ip,
+		UserAgent:  a.UserAgent,
+		Method:     a.Method,
+		Path:       a.Path,
+		CreatedAt:  a.CreatedAt,
+	}
+
+	if a.ActorRole.Valid {
+		out.ActorRole = &a.ActorRole.String
+	}
+
+	return out
+}
+
+// auditFilterParams reads the filters shared by the list and the export.
+// Campus admins only ever see entries of their own campus.
+func (cfg *apiConfig) auditFilterParams(req *http.Request) (database.GetAuditEntriesForExportParams, error) {
+	query := req.URL.Query()
+	params := database.GetAuditEntriesForExportParams{}
+
+	campusID, err := cfg.adminCampusFilter(req)
+	if err != nil {
+		return params, err
+	}
+	params.CampusID = campusID
+
+	loc, err := cfg.campusLocation(req.Context(), campusID)
+	if err != nil {
+		return params, err
+	}
+
+	if params.FromTime, err = parseExportTime(query.Get("from"), false, loc); err != nil {
+		return params, err
+	}
+
+	if params.ToTime, err = parseExportTime(query.Get("to"), true, loc); err != nil {
+		return params, err
+	}
+
+	for name, dest := range map[string]*uuid.NullUUID{"actorID": &params.ActorID, "targetID": &params.TargetID} {
+		if raw := query.Get(name); raw != "" {
+			parsed, err := uuid.Parse(raw)
+			if err != nil {
+				return params, errors.New(name + " is not a valid uuid")
+			}
+			*dest = uuid.NullUUID{UUID: parsed, Valid: true}
+		}
+	}
+
+	if action := query.Get("action"); action != "" {
+		params.Action = sql.NullString{String: action, Valid: true}
+	}
+
+	if targetType := query.Get("targetType"); targetType != "" {
+		params.TargetType = sql.NullString{String: targetType, Valid: true}
+	}
+
+	return params, nil
+}
+
+// queryInt reads a non negative integer query parameter, def when it is unset.
+func queryInt(req *http.Request, name string, def int) (int, error) {
+	raw := req.URL.Query().Get(name)
+	if raw == "" {
+		return def, nil
+	}
+
+	value, err := strconv.Atoi(raw)
+	if err != nil || value < 0 {
+		return 0, errors.New(name + " must be a non negative integer")
+	}
+
+	return value, nil
+}
+
+func (cfg *apiConfig) getAuditLog(res http.ResponseWriter, req *http.Request) {
+	filters, err := cfg.auditFilterParams(req)
+
+	if err != nil {
+		respondWithError(res, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	limit, err := queryInt(req, "limit", defaultAuditPageSize)
+	if err == nil && (limit == 0 || limit > maxAuditPageSize) {
+		err = errors.New("limit must be between 1 and 500")
+	}
+
+	if err != nil {
+		respondWithError(res, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	offset, err := queryInt(req, "offset", 0)
+
+	if err != nil {
+		respondWithError(res, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	//one more than asked for tells whether there is a next page
+	entriesDB, err := cfg.dbQueries.GetAuditEntries(req.Context(), database.GetAuditEntriesParams{
+		ActorID:    filters.ActorID,
+		Action:     filters.Action,
+		TargetType: filters.TargetType,
+		TargetID:   filters.TargetID,
+		CampusID:   filters.CampusID,
+		FromTime:   filters.FromTime,
+		ToTime:     filters.ToTime,
+		PageSize:   int32(limit + 1),
+		PageOffset: int32(offset),
+	})
+
+	if err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	var nextOffset *int
+	if len(entriesDB) > limit {
+		entriesDB = entriesDB[:limit]
+		next := offset + limit
+		nextOffset = &next
+	}
+
+	entries := make([]auditEntryJSON, 0, len(entriesDB))
+	for _, a := range entriesDB {
+		entries = append(entries, toAuditEntryJSON(a))
+	}
+
+	respondWithJSON(res, http.StatusOK, struct {
+		Entries    []auditEntryJSON `json:"entries"`
+		NextOffset *int             `json:"nextOffset"`
+	}{
+		Entries:    entries,
+		NextOffset: nextOffset,
+	})
+}
+
+func (cfg *apiConfig) exportAuditLog(res http.ResponseWriter, req *http.Request) {
+	filters, err := cfg.auditFilterParams(req)
+
+	if err != nil {
+		respondWithError(res, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	res.Header().Set("Content-Type", "text/csv")
+	res.Header().Set("Content-Disposition", "attachment; filename=auditLog.csv")
+	res.WriteHeader(http.StatusOK)
+
+	csvWriter := csv.NewWriter(res)
+
+	err = csvWriter.Write([]string{"id", "createdAt", "actorID", "actorRole", "apiKeyID", "action", "targetType", "targetID", "campusID", "before", "after", "ip", "userAgent", "method", "path"})
+
+	if err == nil {
+		err = cfg.dbQueries.StreamAuditEntriesForExport(req.Context(), filters, func(a database.AuditLog) error {
+			return csvWriter.Write([]string{
+				a.ID.String(),
+				a.CreatedAt.Format(time.RFC3339),
+				nullUUIDString(a.ActorID),
+				a.ActorRole.String,
+				nullUUIDString(a.ApiKeyID),
+				a.Action,
+				a.TargetType,
+				nullUUIDString(a.TargetID),
+				nullUUIDString(a.CampusID),
+				string(a.Before),
+				string(a.After),
+				a.Ip,
+				a.UserAgent,
+				a.Method,
+				a.Path,
+			})
+		})
+	}
+
+	if err == nil {
+		csvWriter.Flush()
+		err = csvWriter.Error()
+	}
+
+	//the status is already sent, a failure can only cut the download short
+	if err != nil {
+		log.Printf("Error exporting the audit log: %s", err)
+	}
+}
+
+func nullUUIDString(id uuid.NullUUID) string {
+	if !id.Valid {
+		return ""
+	}
+	return id.UUID.String()
+}
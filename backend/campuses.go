@@ -0,0 +1,415 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/Shayaan-Kashif/Database-Project/internal/database"
+	"github.com/google/uuid"
+)
+
+// campusFilter reads the optional campusID query parameter. An invalid
+// NullUUID means the list should not be filtered.
+func campusFilter(req *http.Request) (uuid.NullUUID, error) {
+	rawCampusID := req.URL.Query().Get("campusID")
+	if rawCampusID == "" {
+		return uuid.NullUUID{}, nil
+	}
+
+	campusID, err := uuid.Parse(rawCampusID)
+	if err != nil {
+		return uuid.NullUUID{}, err
+	}
+
+	return uuid.NullUUID{UUID: campusID, Valid: true}, nil
+}
+
+// adminCampus returns the campus an admin is restricted to. An invalid
+// NullUUID means the admin manages every campus.
+func (cfg *apiConfig) adminCampus(ctx context.Context, userID uuid.UUID) (uuid.NullUUID, error) {
+	userDB, err := cfg.dbQueries.GetUserFromID(ctx, userID)
+	if err != nil {
+		return uuid.NullUUID{}, err
+	}
+
+	return userDB.AdminCampusID, nil
+}
+
+// canManageCampus reports whether the admin making the request may modify data
+// that belongs to campusID.
+func (cfg *apiConfig) canManageCampus(req *http.Request, campusID uuid.UUID) (bool, error) {
+	userID := req.Context().Value(ctxUserID).(uuid.UUID)
+
+	scope, err := cfg.adminCampus(req.Context(), userID)
+	if err != nil {
+		return false, err
+	}
+
+	return !scope.Valid || scope.UUID == campusID, nil
+}
+
+// canManageUser reports whether the admin making the request may act on the
+// account of userDB, campus admins only manage non admin users of their own
+// campus.
+func (cfg *apiConfig) canManageUser(req *http.Request, userDB database.User) (bool, error) {
+	userID := req.Context().Value(ctxUserID).(uuid.UUID)
+
+	scope, err := cfg.adminCampus(req.Context(), userID)
+	if err != nil {
+		return false, err
+	}
+
+	if !scope.Valid {
+		return true, nil
+	}
+
+	return userDB.Role != "admin" && userDB.CampusID.Valid && userDB.CampusID.UUID == scope.UUID, nil
+}
+
+// adminCampusFilter is campusFilter for admin only lists, campus admins only
+// ever see their own campus.
+func (cfg *apiConfig) adminCampusFilter(req *http.Request) (uuid.NullUUID, error) {
+	userID := req.Context().Value(ctxUserID).(uuid.UUID)
+
+	scope, err := cfg.adminCampus(req.Context(), userID)
+	if err != nil {
+		return uuid.NullUUID{}, err
+	}
+
+	if scope.Valid {
+		return scope, nil
+	}
+
+	return campusFilter(req)
+}
+
+// defaultCampusTimeZone is used for new campuses without a time zone and for
+// lists that span every campus.
+const defaultCampusTimeZone = "America/Toronto"
+
+// loadTimeZone only accepts IANA zone names, "" and "Local" would silently
+// depend on where the server runs.
+func loadTimeZone(name string) (*time.Location, error) {
+	if name == "" || name == "Local" {
+		return nil, errors.New("invalid time zone")
+	}
+
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, errors.New("invalid time zone")
+	}
+
+	return loc, nil
+}
+
+// campusLocation returns the time zone days are counted in for campusID, or
+// the default time zone when campusID is not set.
+func (cfg *apiConfig) campusLocation(ctx context.Context, campusID uuid.NullUUID) (*time.Location, error) {
+	if !campusID.Valid {
+		return loadTimeZone(defaultCampusTimeZone)
+	}
+
+	campusDB, err := cfg.dbQueries.GetCampusFromID(ctx, campusID.UUID)
+	if err != nil {
+		return nil, err
+	}
+
+	return loadTimeZone(campusDB.TimeZone)
+}
+
+func (cfg *apiConfig) getCampuses(res http.ResponseWriter, req *http.Request) {
+	campusesDB, err := cfg.dbQueries.GetCampuses(req.Context())
+
+	if err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	response := make([]struct {
+		ID        uuid.UUID `json:"id"`
+		Name      string    `json:"name"`
+		TimeZone  string    `json:"timeZone"`
+		CreatedAt time.Time `json:"createdAt"`
+	}, 0, len(campusesDB))
+
+	for _, u := range campusesDB {
+		response = append(response, struct {
+			ID        uuid.UUID `json:"id"`
+			Name      string    `json:"name"`
+			TimeZone  string    `json:"timeZone"`
+			CreatedAt time.Time `json:"createdAt"`
+		}{
+			ID:        u.ID,
+			Name:      u.Name,
+			TimeZone:  u.TimeZone,
+			CreatedAt: u.CreatedAt,
+		})
+	}
+
+	respondWithJSON(res, http.StatusOK, response)
+}
+
+func (cfg *apiConfig) createCampus(res http.ResponseWriter, req *http.Request) {
+	userID := req.Context().Value(ctxUserID).(uuid.UUID)
+
+	scope, err := cfg.adminCampus(req.Context(), userID)
+	if err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if scope.Valid {
+		respondWithError(res, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	reqStruct := struct {
+		Name     *string `json:"name"`
+		TimeZone *string `json:"timeZone"`
+	}{}
+
+	if err := decodeJSON(req, &reqStruct); err != nil {
+		respondWithError(res, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if reqStruct.Name == nil || *reqStruct.Name == "" {
+		respondWithError(res, http.StatusBadRequest, "name cannot be empty")
+		return
+	}
+
+	timeZone := defaultCampusTimeZone
+	if reqStruct.TimeZone != nil {
+		timeZone = *reqStruct.TimeZone
+	}
+
+	if _, err := loadTimeZone(timeZone); err != nil {
+		respondWithError(res, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	campusDB, err := cfg.dbQueries.CreateCampus(req.Context(), database.CreateCampusParams{
+		Name:     *reqStruct.Name,
+		TimeZone: timeZone,
+	})
+
+	if err != nil {
+		hasPgErr, message := handlePgConstraints(err)
+		if hasPgErr {
+			respondWithError(res, http.StatusBadRequest, message)
+			return
+		}
+
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	auditAfter(req.Context(), cfg.dbQueries, auditEntry{
+		Action:     "campus.create",
+		TargetType: "campus",
+		TargetID:   campusDB.ID,
+		CampusID:   uuid.NullUUID{UUID: campusDB.ID, Valid: true},
+		After:      toCampusAudit(campusDB),
+	})
+
+	respondWithJSON(res, http.StatusCreated, struct {
+		ID        uuid.UUID `json:"id"`
+		Name      string    `json:"name"`
+		TimeZone  string    `json:"timeZone"`
+		CreatedAt time.Time `json:"createdAt"`
+	}{
+		ID:        campusDB.ID,
+		Name:      campusDB.Name,
+		TimeZone:  campusDB.TimeZone,
+		CreatedAt: campusDB.CreatedAt,
+	})
+}
+
+func (cfg *apiConfig) updateCampusTimeZone(res http.ResponseWriter, req *http.Request) {
+	userID := req.Context().Value(ctxUserID).(uuid.UUID)
+
+	scope, err := cfg.adminCampus(req.Context(), userID)
+	if err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if scope.Valid {
+		respondWithError(res, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	campusID, err := uuid.Parse(req.PathValue("campusID"))
+
+	if err != nil {
+		respondWithError(res, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	reqStruct := struct {
+		TimeZone *string `json:"timeZone"`
+	}{}
+
+	if err := decodeJSON(req, &reqStruct); err != nil {
+		respondWithError(res, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if reqStruct.TimeZone == nil {
+		respondWithError(res, http.StatusBadRequest, "invalid JSON structure")
+		return
+	}
+
+	if _, err := loadTimeZone(*reqStruct.TimeZone); err != nil {
+		respondWithError(res, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	beforeDB, err := cfg.dbQueries.GetCampusFromID(req.Context(), campusID)
+
+	if err == sql.ErrNoRows {
+		respondWithError(res, http.StatusNotFound, "no campus exist for that campusID")
+		return
+	} else if err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	campusDB, err := cfg.dbQueries.UpdateCampusTimeZone(req.Context(), database.UpdateCampusTimeZoneParams{
+		ID:       campusID,
+		TimeZone: *reqStruct.TimeZone,
+	})
+
+	if err == sql.ErrNoRows {
+		respondWithError(res, http.StatusNotFound, "no campus exist for that campusID")
+		return
+	} else if err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	auditAfter(req.Context(), cfg.dbQueries, auditEntry{
+		Action:     "campus.update",
+		TargetType: "campus",
+		TargetID:   campusDB.ID,
+		CampusID:   uuid.NullUUID{UUID: campusDB.ID, Valid: true},
+		Before:     toCampusAudit(beforeDB),
+		After:      toCampusAudit(campusDB),
+	})
+
+	respondWithJSON(res, http.StatusOK, struct {
+		ID        uuid.UUID `json:"id"`
+		Name      string    `json:"name"`
+		TimeZone  string    `json:"timeZone"`
+		CreatedAt time.Time `json:"createdAt"`
+	}{
+		ID:        campusDB.ID,
+		Name:      campusDB.Name,
+		TimeZone:  campusDB.TimeZone,
+		CreatedAt: campusDB.CreatedAt,
+	})
+}
+
+func (cfg *apiConfig) assignCampusAdmin(res http.ResponseWriter, req *http.Request) {
+	userID := req.Context().Value(ctxUserID).(uuid.UUID)
+
+	scope, err := cfg.adminCampus(req.Context(), userID)
+	if err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if scope.Valid {
+		respondWithError(res, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	campusID, err := uuid.Parse(req.PathValue("campusID"))
+
+	if err != nil {
+		respondWithError(res, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	reqStruct := struct {
+		UserID *uuid.UUID `json:"userID"`
+	}{}
+
+	if err := decodeJSON(req, &reqStruct); err != nil {
+		respondWithError(res, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if reqStruct.UserID == nil {
+		respondWithError(res, http.StatusBadRequest, "invalid JSON structure")
+		return
+	}
+
+	userDB, err := cfg.dbQueries.GetUserFromID(req.Context(), *reqStruct.UserID)
+
+	if err == sql.ErrNoRows {
+		respondWithError(res, http.StatusBadRequest, "no user exist for that userID")
+		return
+	} else if err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	tx, err := cfg.db.BeginTx(req.Context(), nil)
+
+	if err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	defer tx.Rollback()
+
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	err = qtx.SetUserAdminCampus(req.Context(), database.SetUserAdminCampusParams{
+		AdminCampusID: uuid.NullUUID{UUID: campusID, Valid: true},
+		ID:            userDB.ID,
+	})
+
+	if err != nil {
+		hasPgErr, message := handlePgConstraints(err)
+		if hasPgErr {
+			respondWithError(res, http.StatusBadRequest, message)
+			return
+		}
+
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	//lot grants would come back if the user was made a lot manager again
+	if err := qtx.DeleteManagedLots(req.Context(), userDB.ID); err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	//the role is in the access token and the campus limits what it can do
+	if err := qtx.BumpTokenVersion(req.Context(), userDB.ID); err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if err := auditUser(req.Context(), qtx, "user.role", userDB); err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	cfg.tokenVersions.forget(userDB.ID)
+
+	respondWithJSON(res, http.StatusOK, struct {
+		Status string `json:"status"`
+	}{"The user is now an admin of this campus"})
+}
@@ -0,0 +1,496 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/Shayaan-Kashif/Database-Project/internal/database"
+	"github.com/google/uuid"
+)
+
+// citationFines is what each violation costs in cents. The fine is taken from
+// the wallet of the cited user, citations of an unregistered plate carry none.
+var citationFines = map[string]int64{
+	"overstay":  2500,
+	"no_permit": 5000,
+	"wrong_lot": 2500,
+}
+
+type citationJSON struct {
+	ID                uuid.UUID      `json:"id"`
+	OfficerID         uuid.NullUUID  `json:"officerID"`
+	UserID            uuid.NullUUID  `json:"userID"`
+	LicensePlate      sql.NullString `json:"licensePlate"`
+	ParkingLotID      uuid.UUID      `json:"parkingLotID"`
+	ParkingLogID      uuid.NullUUID  `json:"parkingLogID"`
+	Violation         string         `json:"violation"`
+	Notes             sql.NullString `json:"notes"`
+	Status            string         `json:"status"`
+	AppealReason      sql.NullString `json:"appealReason"`
+	AppealedAt        sql.NullTime   `json:"appealedAt"`
+	ResolvedAt        sql.NullTime   `json:"resolvedAt"`
+	FineTransactionID uuid.NullUUID  `json:"fineTransactionID"`
+	CreatedAt         time.Time      `json:"createdAt"`
+	UpdatedAt         time.Time      `json:"updatedAt"`
+	Photos            []string       `json:"photos,omitempty"`
+}
+
+func toCitationJSON(c database.Citation) citationJSON {
+	return citationJSON{
+		ID:                c.ID,
+		OfficerID:         c.OfficerID,
+		UserID:            c.UserID,
+		LicensePlate:      c.LicensePlate,
+		ParkingLotID:      c.ParkingLotID,
+		ParkingLogID:      c.ParkingLogID,
+		Violation:         c.Violation,
+		Notes:             c.Notes,
+		Status:            c.Status,
+		AppealReason:      c.AppealReason,
+		AppealedAt:        c.AppealedAt,
+		ResolvedAt:        c.ResolvedAt,
+		FineTransactionID: c.FineTransactionID,
+		CreatedAt:         c.CreatedAt,
+		UpdatedAt:         c.UpdatedAt,
+	}
+}
+
+// findCitedUser resolves the user a citation refers to, by ID or by license
+// plate. A plate that is not registered to anyone is not an error.
+func (cfg *apiConfig) findCitedUser(ctx context.Context, userID *uuid.UUID, licensePlate string) (uuid.NullUUID, error) {
+	if userID != nil {
+		userDB, err := cfg.dbQueries.GetUserFromID(ctx, *userID)
+		if err != nil {
+			return uuid.NullUUID{}, err
+		}
+		return uuid.NullUUID{UUID: userDB.ID, Valid: true}, nil
+	}
+
+	userDB, err := cfg.dbQueries.GetUserFromLicensePlate(ctx, sql.NullString{String: licensePlate, Valid: true})
+
+	if err == sql.ErrNoRows {
+		return uuid.NullUUID{}, nil
+	} else if err != nil {
+		return uuid.NullUUID{}, err
+	}
+
+	return uuid.NullUUID{UUID: userDB.ID, Valid: true}, nil
+}
+
+// activeParkingLog returns the entry log of the user's current stay at the lot,
+// if they are checked in there.
+func (cfg *apiConfig) activeParkingLog(ctx context.Context, userID, lotID uuid.UUID) (database.ParkingLog, bool, error) {
+	logDB, err := cfg.dbQueries.GetLatestLogFromUserAndLot(ctx, database.GetLatestLogFromUserAndLotParams{
+		UserID:       uuid.NullUUID{UUID: userID, Valid: true},
+		ParkingLotID: lotID,
+	})
+
+	if err == sql.ErrNoRows {
+		return database.ParkingLog{}, false, nil
+	} else if err != nil {
+		return database.ParkingLog{}, false, err
+	}
+
+	return logDB, logDB.EventType == "entry", nil
+}
+
+func (cfg *apiConfig) createCitation(res http.ResponseWriter, req *http.Request) {
+	officerID := req.Context().Value(ctxUserID).(uuid.UUID)
+
+	reqStruct := struct {
+		UserID       *uuid.UUID `json:"userID"`
+		LicensePlate string     `json:"licensePlate"`
+		ParkingLotID *uuid.UUID `json:"parkingLotID"`
+		Violation    *string    `json:"violation"`
+		Notes        string     `json:"notes"`
+		Photos       []string   `json:"photos"`
+	}{}
+
+	if err := decodeJSON(req, &reqStruct); err != nil {
+		respondWithError(res, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if reqStruct.ParkingLotID == nil || reqStruct.Violation == nil || (reqStruct.UserID == nil && reqStruct.LicensePlate == "") {
+		respondWithError(res, http.StatusBadRequest, "invalid JSON structure")
+		return
+	}
+
+	citedUser, err := cfg.findCitedUser(req.Context(), reqStruct.UserID, reqStruct.LicensePlate)
+
+	if err == sql.ErrNoRows {
+		respondWithError(res, http.StatusBadRequest, "no user exist for that userID")
+		return
+	} else if err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	parkingLogID := uuid.NullUUID{}
+
+	if citedUser.Valid {
+		logDB, checkedIn, err := cfg.activeParkingLog(req.Context(), citedUser.UUID, *reqStruct.ParkingLotID)
+		if err != nil {
+			respondWithError(res, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		if checkedIn {
+			parkingLogID = uuid.NullUUID{UUID: logDB.ID, Valid: true}
+		}
+	}
+
+	tx, err := cfg.db.BeginTx(req.Context(), nil)
+	if err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	defer tx.Rollback()
+
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	citationDB, err := qtx.CreateCitation(req.Context(), database.CreateCitationParams{
+		OfficerID: uuid.NullUUID{UUID: officerID, Valid: true},
+		UserID:    citedUser,
+		LicensePlate: sql.NullString{
+			String: reqStruct.LicensePlate,
+			Valid:  reqStruct.LicensePlate != "",
+		},
+		ParkingLotID: *reqStruct.ParkingLotID,
+		ParkingLogID: parkingLogID,
+		Violation:    *reqStruct.Violation,
+		Notes: sql.NullString{
+			String: reqStruct.Notes,
+			Valid:  reqStruct.Notes != "",
+		},
+	})
+
+	if err != nil {
+		hasPgErr, message := handlePgConstraints(err)
+		if hasPgErr {
+			respondWithError(res, http.StatusBadRequest, message)
+			return
+		}
+
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if citedUser.Valid {
+		fineTx, err := postWalletTransactionTx(req.Context(), qtx, citedUser.UUID, "fine", citationFines[citationDB.Violation], "citation for "+citationDB.Violation, citationDB.ID.String(), uuid.NullUUID{})
+		if err != nil {
+			respondWithError(res, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		citationDB, err = qtx.SetCitationFine(req.Context(), database.SetCitationFineParams{
+			FineTransactionID: uuid.NullUUID{UUID: fineTx.ID, Valid: true},
+			ID:                citationDB.ID,
+		})
+
+		if err != nil {
+			respondWithError(res, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+
+	for _, photo := range reqStruct.Photos {
+		err = qtx.CreateCitationPhoto(req.Context(), database.CreateCitationPhotoParams{
+			CitationID: citationDB.ID,
+			Url:        photo,
+		})
+
+		if err != nil {
+			respondWithError(res, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	response := toCitationJSON(citationDB)
+	response.Photos = reqStruct.Photos
+
+	respondWithJSON(res, http.StatusCreated, response)
+}
+
+func (cfg *apiConfig) getCitationsFromUserID(res http.ResponseWriter, req *http.Request) {
+	userID := req.Context().Value(ctxUserID).(uuid.UUID)
+
+	citationsDB, err := cfg.dbQueries.GetCitationsFromUserID(req.Context(), uuid.NullUUID{UUID: userID, Valid: true})
+
+	if err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	response := make([]citationJSON, 0, len(citationsDB))
+
+	for _, u := range citationsDB {
+		response = append(response, toCitationJSON(u))
+	}
+
+	respondWithJSON(res, http.StatusOK, response)
+}
+
+func (cfg *apiConfig) getAllCitations(res http.ResponseWriter, req *http.Request) {
+	citationsDB, err := cfg.dbQueries.GetCitations(req.Context())
+
+	if err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	response := make([]citationJSON, 0, len(citationsDB))
+
+	for _, u := range citationsDB {
+		response = append(response, toCitationJSON(u))
+	}
+
+	respondWithJSON(res, http.StatusOK, response)
+}
+
+func (cfg *apiConfig) getCitationFromID(res http.ResponseWriter, req *http.Request) {
+	userID := req.Context().Value(ctxUserID).(uuid.UUID)
+	role := req.Context().Value(ctxRole).(string)
+
+	citationID, err := uuid.Parse(req.PathValue("citationID"))
+
+	if err != nil {
+		respondWithError(res, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	citationDB, err := cfg.dbQueries.GetCitationFromID(req.Context(), citationID)
+
+	if err == sql.ErrNoRows {
+		respondWithError(res, http.StatusNotFound, "No citation with this ID was found")
+		return
+	} else if err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if !hasPermission(role, permCitationsReadAll) && citationDB.UserID.UUID != userID {
+		respondWithError(res, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	photosDB, err := cfg.dbQueries.GetCitationPhotos(req.Context(), citationID)
+
+	if err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	response := toCitationJSON(citationDB)
+	response.Photos = make([]string, 0, len(photosDB))
+
+	for _, u := range photosDB {
+		response.Photos = append(response.Photos, u.Url)
+	}
+
+	respondWithJSON(res, http.StatusOK, response)
+}
+
+func (cfg *apiConfig) appealCitation(res http.ResponseWriter, req *http.Request) {
+	userID := req.Context().Value(ctxUserID).(uuid.UUID)
+
+	citationID, err := uuid.Parse(req.PathValue("citationID"))
+
+	if err != nil {
+		respondWithError(res, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	reqStruct := struct {
+		Reason *string `json:"reason"`
+	}{}
+
+	if err := decodeJSON(req, &reqStruct); err != nil {
+		respondWithError(res, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if reqStruct.Reason == nil || *reqStruct.Reason == "" {
+		respondWithError(res, http.StatusBadRequest, "reason cannot be empty")
+		return
+	}
+
+	sqlResult, err := cfg.dbQueries.AppealCitation(req.Context(), database.AppealCitationParams{
+		AppealReason: sql.NullString{String: *reqStruct.Reason, Valid: true},
+		ID:           citationID,
+		UserID:       uuid.NullUUID{UUID: userID, Valid: true},
+	})
+
+	if err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	rowsAffected, _ := sqlResult.RowsAffected()
+	if rowsAffected == 0 {
+		respondWithError(res, http.StatusNotFound, "No open citation with this ID was found")
+		return
+	}
+
+	respondWithJSON(res, http.StatusOK, struct {
+		Status string `json:"status"`
+	}{"The citation has been appealed"})
+}
+
+// reverseCitationFine refunds what is left of the fine of a dismissed
+// citation. The fine of a deleted user has no wallet to go back to.
+func reverseCitationFine(ctx context.Context, qtx *database.Queries, citationDB database.Citation) error {
+	if !citationDB.FineTransactionID.Valid || !citationDB.UserID.Valid {
+		return nil
+	}
+
+	_, remaining, err := refundableCharge(ctx, qtx, citationDB.UserID.UUID, citationDB.FineTransactionID.UUID)
+	if err != nil || remaining <= 0 {
+		return err
+	}
+
+	_, err = postWalletTransactionTx(ctx, qtx, citationDB.UserID.UUID, "refund", remaining, "dismissed citation", citationDB.ID.String(), citationDB.FineTransactionID)
+	return err
+}
+
+func (cfg *apiConfig) resolveCitation(res http.ResponseWriter, req *http.Request) {
+	adminID := req.Context().Value(ctxUserID).(uuid.UUID)
+
+	citationID, err := uuid.Parse(req.PathValue("citationID"))
+
+	if err != nil {
+		respondWithError(res, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	reqStruct := struct {
+		Decision *string `json:"decision"`
+	}{}
+
+	if err := decodeJSON(req, &reqStruct); err != nil {
+		respondWithError(res, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if reqStruct.Decision == nil || (*reqStruct.Decision != "upheld" && *reqStruct.Decision != "dismissed") {
+		respondWithError(res, http.StatusBadRequest, "incorrect decision input")
+		return
+	}
+
+	tx, err := cfg.db.BeginTx(req.Context(), nil)
+	if err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	defer tx.Rollback()
+
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	citationDB, err := qtx.ResolveCitation(req.Context(), database.ResolveCitationParams{
+		Status:     *reqStruct.Decision,
+		ResolvedBy: uuid.NullUUID{UUID: adminID, Valid: true},
+		ID:         citationID,
+	})
+
+	if err == sql.ErrNoRows {
+		respondWithError(res, http.StatusNotFound, "No appealed citation with this ID was found")
+		return
+	} else if err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if citationDB.Status == "dismissed" {
+		if err := reverseCitationFine(req.Context(), qtx, citationDB); err != nil {
+			respondWithError(res, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	auditAfter(req.Context(), cfg.dbQueries, auditEntry{
+		Action:     "citation.resolve",
+		TargetType: "citation",
+		TargetID:   citationID,
+		Before: struct {
+			Status string `json:"status"`
+		}{"appealed"},
+		After: struct {
+			Status string `json:"status"`
+		}{*reqStruct.Decision},
+	})
+
+	respondWithJSON(res, http.StatusOK, struct {
+		Status string `json:"status"`
+	}{"The appeal has been resolved"})
+}
+
+func (cfg *apiConfig) checkParkingStatus(res http.ResponseWriter, req *http.Request) {
+	lotID, err := uuid.Parse(req.URL.Query().Get("lotID"))
+
+	if err != nil {
+		respondWithError(res, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var userID *uuid.UUID
+	licensePlate := req.URL.Query().Get("licensePlate")
+
+	if rawUserID := req.URL.Query().Get("userID"); rawUserID != "" {
+		parsed, err := uuid.Parse(rawUserID)
+		if err != nil {
+			respondWithError(res, http.StatusBadRequest, err.Error())
+			return
+		}
+		userID = &parsed
+	} else if licensePlate == "" {
+		respondWithError(res, http.StatusBadRequest, "userID or licensePlate is required")
+		return
+	}
+
+	citedUser, err := cfg.findCitedUser(req.Context(), userID, licensePlate)
+
+	if err == sql.ErrNoRows {
+		respondWithError(res, http.StatusBadRequest, "no user exist for that userID")
+		return
+	} else if err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	response := struct {
+		UserID       uuid.NullUUID `json:"userID"`
+		CheckedIn    bool          `json:"checkedIn"`
+		ParkingLogID uuid.NullUUID `json:"parkingLogID"`
+		Since        sql.NullTime  `json:"since"`
+	}{UserID: citedUser}
+
+	if citedUser.Valid {
+		logDB, checkedIn, err := cfg.activeParkingLog(req.Context(), citedUser.UUID, lotID)
+		if err != nil {
+			respondWithError(res, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		if checkedIn {
+			response.CheckedIn = true
+			response.ParkingLogID = uuid.NullUUID{UUID: logDB.ID, Valid: true}
+			response.Since = sql.NullTime{Time: logDB.Time, Valid: true}
+		}
+	}
+
+	respondWithJSON(res, http.StatusOK, response)
+}
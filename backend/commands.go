@@ -0,0 +1,899 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Shayaan-Kashif/Database-Project/internal/database"
+	"github.com/Shayaan-Kashif/Database-Project/internal/oidcmock"
+	"github.com/google/uuid"
+	"github.com/pressly/goose/v3"
+)
+
+const commandUsage = `usage:
+  server [serve] [--migrate]
+  server migrate up|down|status [--dir <path>]
+  server lots list [--campus <id>]
+  server lots create --name <name> --slots <n> --campus <id>
+  server lots update --id <id> [--name <name>] [--slots <n>]
+  server lots import --file <path> [--format csv|json] [--campus <id>] [--dry-run]
+  server lots export [--format csv|json] [--campus <id>] [--out <path>]
+  server logs export [--format csv|ndjson|parquet] [--from <date>] [--to <date>] [--lot <id>]
+                     [--type entry|exit] [--campus <id>] [--out <path>]
+  server logs partitions
+  server logs archive [--keep-months <n> | --month YYYY-MM] [--dir <path>]
+  server logs restore --month YYYY-MM [--dir <path>]
+  server users promote --email <email> [--campus <id>]
+  server users demote|disable|enable|verify|reset-totp --email <email>
+  server users delete-scheduled
+  server invitations create [--role admin|enforcement|lot_manager] [--campus <id>] [--email <email>] [--hours <n>]
+  server tokens purge-expired
+  server jwt generate-key [--alg EdDSA|RS256] --out <path>
+  server oidc mock [--addr <host:port>]
+  server occupancy recount
+  server simulate [--users <n>] [--days <n>] [--start YYYY-MM-DD] [--mode queries|handler]
+                  [--clock simulated|real] [--speed <x>] [--workers <n>] [--seed <n>] [--campus <id>]`
+
+// runCommand runs the subcommand in args against DB_URL. Without a subcommand
+// the http server is started.
+func (cfg *apiConfig) runCommand(args []string) error {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return cfg.serveCommand(args)
+	}
+
+	if args[0] == "serve" {
+		return cfg.serveCommand(args[1:])
+	}
+
+	if len(args) < 2 {
+		return errors.New(commandUsage)
+	}
+
+	switch args[0] + " " + args[1] {
+	case "migrate up", "migrate down", "migrate status":
+		return cfg.migrateCommand(args[1], args[2:])
+	case "lots list":
+		return cfg.listLotsCommand(args[2:])
+	case "lots create":
+		return cfg.createLotCommand(args[2:])
+	case "lots update":
+		return cfg.updateLotCommand(args[2:])
+	case "lots import":
+		return cfg.importLotsCommand(args[2:])
+	case "lots export":
+		return cfg.exportLotsCommand(args[2:])
+	case "logs export":
+		return cfg.exportLogsCommand(args[2:])
+	case "logs partitions":
+		return cfg.listLogPartitionsCommand()
+	case "logs archive":
+		return cfg.archiveLogsCommand(args[2:])
+	case "logs restore":
+		return cfg.restoreLogsCommand(args[2:])
+	case "users promote", "users demote", "users disable", "users enable", "users verify", "users reset-totp":
+		return cfg.userCommand(args[1], args[2:])
+	case "users delete-scheduled":
+		return cfg.deleteScheduledUsersCommand()
+	case "invitations create":
+		return cfg.createInvitationCommand(args[2:])
+	case "tokens purge-expired":
+		return cfg.purgeTokensCommand()
+	case "jwt generate-key":
+		return generateJWTKeyCommand(args[2:])
+	case "oidc mock":
+		return mockIdPCommand(args[2:])
+	case "occupancy recount":
+		return cfg.recountOccupancyCommand()
+	}
+
+	if args[0] == "simulate" {
+		return cfg.simulateCommand(args[1:])
+	}
+
+	return errors.New(commandUsage)
+}
+
+func parseCampusFlag(raw string) (uuid.NullUUID, error) {
+	if raw == "" {
+		return uuid.NullUUID{}, nil
+	}
+
+	campusID, err := uuid.Parse(raw)
+	if err != nil {
+		return uuid.NullUUID{}, err
+	}
+
+	return uuid.NullUUID{UUID: campusID, Valid: true}, nil
+}
+
+func (cfg *apiConfig) importLotsCommand(args []string) error {
+	flags := flag.NewFlagSet("lots import", flag.ContinueOnError)
+	file := flags.String("file", "", "csv or json file to import")
+	format := flags.String("format", "csv", "file format, csv or json")
+	campus := flags.String("campus", "", "campus used for rows without a campusID")
+	dryRun := flags.Bool("dry-run", false, "validate the file without saving anything")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if *file == "" {
+		return errors.New("--file is required")
+	}
+
+	campusID, err := parseCampusFlag(*campus)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(*file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	rows, err := parseLotImport(f, *format)
+	if err != nil {
+		return err
+	}
+
+	//the cli runs with database access so it is not limited to a campus
+	results, success, err := importLots(commandContext("lots import"), cfg.db, cfg.dbQueries, rows, campusID, uuid.NullUUID{}, *dryRun)
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(results); err != nil {
+		return err
+	}
+
+	if !success {
+		return errors.New("import failed, no lots were changed")
+	}
+
+	if *dryRun {
+		fmt.Fprintln(os.Stderr, "dry run, no lots were changed")
+	}
+
+	return nil
+}
+
+func (cfg *apiConfig) exportLotsCommand(args []string) error {
+	flags := flag.NewFlagSet("lots export", flag.ContinueOnError)
+	format := flags.String("format", "csv", "file format, csv or json")
+	campus := flags.String("campus", "", "only export lots of this campus")
+	out := flags.String("out", "", "file to write, defaults to stdout")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if *format != "csv" && *format != "json" {
+		return errors.New("incorrect format input")
+	}
+
+	campusID, err := parseCampusFlag(*campus)
+	if err != nil {
+		return err
+	}
+
+	lotsDB, err := cfg.dbQueries.GetParkingLots(context.Background(), campusID)
+	if err != nil {
+		return err
+	}
+
+	if *out == "" {
+		return writeLotExport(os.Stdout, *format, lotsDB)
+	}
+
+	f, err := os.Create(*out)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := writeLotExport(f, *format, lotsDB); err != nil {
+		return err
+	}
+
+	return f.Close()
+}
+
+// serveCommand refuses to start against an out of date schema unless
+// --migrate (or MIGRATE_ON_START=true) asks for pending migrations to be applied.
+func (cfg *apiConfig) serveCommand(args []string) error {
+	flags := flag.NewFlagSet("serve", flag.ContinueOnError)
+	migrate := flags.Bool("migrate", os.Getenv("MIGRATE_ON_START") == "true", "apply pending migrations before starting")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	//only the server signs tokens, the other commands run without the keys
+	jwtKeys, err := loadJWTKeys()
+	if err != nil {
+		return fmt.Errorf("failed to load the JWT keys: %w", err)
+	}
+	cfg.jwtKeys = jwtKeys
+
+	if err := cfg.checkSchema(*migrate); err != nil {
+		return err
+	}
+
+	if err := cfg.setupOIDC(context.Background()); err != nil {
+		return err
+	}
+
+	go cfg.maintainLogPartitions(context.Background())
+	go cfg.maintainAccountDeletions(context.Background())
+
+	return cfg.serve()
+}
+
+func (cfg *apiConfig) migrateCommand(direction string, args []string) error {
+	flags := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	dir := flags.String("dir", "", "read migrations from this directory instead of the ones built into the binary")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	migrationsDir, err := useMigrations(*dir)
+	if err != nil {
+		return err
+	}
+
+	switch direction {
+	case "up":
+		return goose.Up(cfg.db, migrationsDir)
+	case "down":
+		return goose.Down(cfg.db, migrationsDir)
+	}
+
+	return goose.Status(cfg.db, migrationsDir)
+}
+
+func printLots(lots []database.Parkinglot) error {
+	writer := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+
+	fmt.Fprintln(writer, "ID\tNAME\tSLOTS\tOCCUPIED\tCAMPUS")
+	for _, u := range lots {
+		fmt.Fprintf(writer, "%s\t%s\t%d\t%d\t%s\n", u.ID, u.Name, u.Slots, u.Occupiedslots, u.CampusID)
+	}
+
+	return writer.Flush()
+}
+
+func (cfg *apiConfig) listLotsCommand(args []string) error {
+	flags := flag.NewFlagSet("lots list", flag.ContinueOnError)
+	campus := flags.String("campus", "", "only list lots of this campus")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	campusID, err := parseCampusFlag(*campus)
+	if err != nil {
+		return err
+	}
+
+	lotsDB, err := cfg.dbQueries.GetParkingLots(context.Background(), campusID)
+	if err != nil {
+		return err
+	}
+
+	return printLots(lotsDB)
+}
+
+func (cfg *apiConfig) createLotCommand(args []string) error {
+	flags := flag.NewFlagSet("lots create", flag.ContinueOnError)
+	name := flags.String("name", "", "name of the lot")
+	slots := flags.Int("slots", -1, "number of slots")
+	campus := flags.String("campus", "", "campus the lot belongs to")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if *name == "" {
+		return errors.New("name cannot be empty")
+	}
+
+	if *slots < 0 {
+		return errors.New("--slots is required and cannot be negative")
+	}
+
+	campusID, err := uuid.Parse(*campus)
+	if err != nil {
+		return fmt.Errorf("--campus is required: %w", err)
+	}
+
+	ctx := commandContext("lots create")
+
+	tx, err := cfg.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	lotDB, err := qtx.CreateParkingLot(ctx, database.CreateParkingLotParams{
+		Name:     *name,
+		Slots:    int32(*slots),
+		CampusID: campusID,
+	})
+
+	if err != nil {
+		return err
+	}
+
+	if err := audit(ctx, qtx, lotAuditEntry("lot.create", nil, &lotDB)); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	return printLots([]database.Parkinglot{lotDB})
+}
+
+func (cfg *apiConfig) updateLotCommand(args []string) error {
+	flags := flag.NewFlagSet("lots update", flag.ContinueOnError)
+	id := flags.String("id", "", "id of the lot to update")
+	name := flags.String("name", "", "new name of the lot")
+	slots := flags.Int("slots", -1, "new number of slots")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	lotID, err := uuid.Parse(*id)
+	if err != nil {
+		return fmt.Errorf("--id is required: %w", err)
+	}
+
+	if *name == "" && *slots < 0 {
+		return errors.New("modification request invalid")
+	}
+
+	ctx := commandContext("lots update")
+
+	lotDB, err := cfg.dbQueries.GetParkingLotFromID(ctx, lotID)
+	if err == sql.ErrNoRows {
+		return errors.New("no lot exist for that parkinglotID")
+	} else if err != nil {
+		return err
+	}
+
+	before := lotDB
+
+	if *name != "" {
+		lotDB.Name = *name
+	}
+
+	if *slots >= 0 {
+		if int32(*slots) < lotDB.Occupiedslots {
+			return errors.New("slots cannot be smaller than occupied slots")
+		}
+		lotDB.Slots = int32(*slots)
+	}
+
+	tx, err := cfg.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	err = qtx.UpdateParkingLot(ctx, database.UpdateParkingLotParams{
+		Name:  lotDB.Name,
+		Slots: lotDB.Slots,
+		ID:    lotDB.ID,
+	})
+
+	if err != nil {
+		return err
+	}
+
+	if err := audit(ctx, qtx, lotAuditEntry("lot.update", &before, &lotDB)); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	return printLots([]database.Parkinglot{lotDB})
+}
+
+func (cfg *apiConfig) exportLogsCommand(args []string) error {
+	flags := flag.NewFlagSet("logs export", flag.ContinueOnError)
+	format := flags.String("format", "csv", "file format, csv, ndjson or parquet")
+	from := flags.String("from", "", "first day (YYYY-MM-DD) or time (RFC 3339) to export")
+	to := flags.String("to", "", "last day (YYYY-MM-DD, included) or time (RFC 3339, excluded) to export")
+	lot := flags.String("lot", "", "only export logs of this lot")
+	eventType := flags.String("type", "", "only export entry or exit logs")
+	campus := flags.String("campus", "", "only export logs of lots of this campus")
+	out := flags.String("out", "", "file to write, defaults to stdout")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	campusID, err := parseCampusFlag(*campus)
+	if err != nil {
+		return err
+	}
+
+	params, err := cfg.logExportParams(context.Background(), *from, *to, *lot, *eventType, campusID)
+	if err != nil {
+		return err
+	}
+
+	if *out == "" {
+		return cfg.writeLogExport(context.Background(), os.Stdout, *format, params)
+	}
+
+	f, err := os.Create(*out)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := cfg.writeLogExport(context.Background(), f, *format, params); err != nil {
+		return err
+	}
+
+	return f.Close()
+}
+
+func (cfg *apiConfig) listLogPartitionsCommand() error {
+	ctx := context.Background()
+
+	if err := cfg.ensureLogPartitions(ctx); err != nil {
+		return err
+	}
+
+	partitions, err := cfg.dbQueries.GetParkingLogPartitions(ctx)
+	if err != nil {
+		return err
+	}
+
+	restored, err := cfg.restoredLogMonths(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, partition := range partitions {
+		if month, ok := partitionMonth(partition); ok && restored[month] {
+			fmt.Printf("%s (restored, kept until archived with --month)\n", partition)
+			continue
+		}
+
+		fmt.Println(partition)
+	}
+
+	detached, err := cfg.dbQueries.GetDetachedParkingLogPartitions(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, partition := range detached {
+		fmt.Printf("%s (detached by an unfinished archive, archived by the next run)\n", partition)
+	}
+
+	archives, err := filepath.Glob(filepath.Join(logArchiveDir(), logPartitionPrefix+"*.csv.gz"))
+	if err != nil {
+		return err
+	}
+
+	for _, archive := range archives {
+		fmt.Printf("%s (archived)\n", archive)
+	}
+
+	return nil
+}
+
+func (cfg *apiConfig) archiveLogsCommand(args []string) error {
+	flags := flag.NewFlagSet("logs archive", flag.ContinueOnError)
+	keepMonths := flags.Int("keep-months", -1, "archive every month before the last n months, defaults to LOG_RETENTION_MONTHS")
+	month := flags.String("month", "", "archive only this month (YYYY-MM)")
+	dir := flags.String("dir", logArchiveDir(), "directory the archives are written to")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+
+	if *month != "" {
+		parsed, err := parseMonth(*month)
+		if err != nil {
+			return err
+		}
+
+		partitions, err := cfg.dbQueries.GetParkingLogPartitions(ctx)
+		if err != nil {
+			return err
+		}
+
+		detached, err := cfg.dbQueries.GetDetachedParkingLogPartitions(ctx)
+		if err != nil {
+			return err
+		}
+
+		partition := logPartitionPrefix + parsed.Format("2006_01")
+
+		if !slices.Contains(partitions, partition) && !slices.Contains(detached, partition) {
+			return errors.New("there is no partition for that month")
+		}
+
+		rows, err := cfg.archiveLogPartition(ctx, parsed, *dir)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("archived %d parking logs to %s\n", rows, logArchivePath(*dir, parsed))
+		return nil
+	}
+
+	if *keepMonths < 0 {
+		months, err := logRetentionMonths()
+		if err != nil {
+			return err
+		}
+		*keepMonths = months
+	}
+
+	if *keepMonths == 0 {
+		return errors.New("no retention policy, pass --keep-months or set LOG_RETENTION_MONTHS")
+	}
+
+	return cfg.applyLogRetention(ctx, *keepMonths, *dir)
+}
+
+func (cfg *apiConfig) restoreLogsCommand(args []string) error {
+	flags := flag.NewFlagSet("logs restore", flag.ContinueOnError)
+	month := flags.String("month", "", "month to restore (YYYY-MM)")
+	dir := flags.String("dir", logArchiveDir(), "directory the archives were written to")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	parsed, err := parseMonth(*month)
+	if err != nil {
+		return err
+	}
+
+	restored, skipped, err := cfg.restoreLogPartition(context.Background(), parsed, *dir)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("restored %d parking logs, skipped %d of deleted lots or already present\n", restored, skipped)
+	return nil
+}
+
+func (cfg *apiConfig) userCommand(action string, args []string) error {
+	flags := flag.NewFlagSet("users "+action, flag.ContinueOnError)
+	email := flags.String("email", "", "email of the user")
+	campus := flags.String("campus", "", "promote to an admin of only this campus")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	ctx := commandContext("users " + action)
+
+	userDB, err := cfg.dbQueries.GetUserFromEmail(ctx, *email)
+	if err == sql.ErrNoRows {
+		return errors.New("no user exist for that email")
+	} else if err != nil {
+		return err
+	}
+
+	tx, err := cfg.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	switch action {
+	case "promote":
+		campusID, err := parseCampusFlag(*campus)
+		if err != nil {
+			return err
+		}
+
+		if campusID.Valid {
+			err = qtx.SetUserAdminCampus(ctx, database.SetUserAdminCampusParams{
+				AdminCampusID: campusID,
+				ID:            userDB.ID,
+			})
+		} else {
+			_, err = qtx.UpdateUserRole(ctx, database.UpdateUserRoleParams{
+				Role: "admin",
+				ID:   userDB.ID,
+			})
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if err := qtx.DeleteManagedLots(ctx, userDB.ID); err != nil {
+			return err
+		}
+
+		if err := qtx.BumpTokenVersion(ctx, userDB.ID); err != nil {
+			return err
+		}
+	case "demote":
+		_, err = qtx.UpdateUserRole(ctx, database.UpdateUserRoleParams{
+			Role: "user",
+			ID:   userDB.ID,
+		})
+
+		if err != nil {
+			return err
+		}
+
+		if err := qtx.DeleteManagedLots(ctx, userDB.ID); err != nil {
+			return err
+		}
+
+		//running servers notice within tokenVersionTTL
+		if err := qtx.BumpTokenVersion(ctx, userDB.ID); err != nil {
+			return err
+		}
+	case "disable":
+		if _, err := qtx.DisableUser(ctx, userDB.ID); err != nil {
+			return err
+		}
+
+		//disabled users should not be able to refresh their access token either
+		if err := qtx.RevokeAllUserTokens(ctx, userDB.ID); err != nil {
+			return err
+		}
+
+		if err := qtx.BumpTokenVersion(ctx, userDB.ID); err != nil {
+			return err
+		}
+	case "enable":
+		if _, err := qtx.EnableUser(ctx, userDB.ID); err != nil {
+			return err
+		}
+	case "verify":
+		if err := qtx.MarkEmailVerified(ctx, userDB.ID); err != nil {
+			return err
+		}
+	case "reset-totp":
+		//for a lost authenticator without recovery codes, admins enroll again on their next login
+		if err := qtx.DisableTOTP(ctx, userDB.ID); err != nil {
+			return err
+		}
+
+		if err := qtx.DeleteUserRecoveryCodes(ctx, userDB.ID); err != nil {
+			return err
+		}
+
+		if err := qtx.RevokeAllUserTokens(ctx, userDB.ID); err != nil {
+			return err
+		}
+
+		if err := qtx.BumpTokenVersion(ctx, userDB.ID); err != nil {
+			return err
+		}
+	}
+
+	auditAction := map[string]string{"promote": "user.role", "demote": "user.role", "reset-totp": "user.reset_totp"}[action]
+	if auditAction == "" {
+		auditAction = "user." + action
+	}
+
+	if err := auditUser(ctx, qtx, auditAction, userDB); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	fmt.Printf("%s: %s done\n", userDB.Email, action)
+	return nil
+}
+
+// createInvitationCommand prints a new invitation, this is how the first admin
+// of a fresh database signs up.
+func (cfg *apiConfig) createInvitationCommand(args []string) error {
+	flags := flag.NewFlagSet("invitations create", flag.ContinueOnError)
+	role := flags.String("role", "admin", "role to grant, admin, enforcement or lot_manager")
+	campus := flags.String("campus", "", "invite an admin of only this campus")
+	email := flags.String("email", "", "only this email can redeem it, the link is also emailed to it")
+	hours := flags.Int("hours", int(defaultInvitationTTL.Hours()), "hours until the invitation expires")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if *role != "admin" && *role != "enforcement" && *role != "lot_manager" {
+		return errors.New("--role must be admin, enforcement or lot_manager")
+	}
+
+	ttl := time.Duration(*hours) * time.Hour
+	if ttl <= 0 || ttl > maxInvitationTTL {
+		return errors.New("--hours must be between 1 and 720")
+	}
+
+	campusID, err := parseCampusFlag(*campus)
+	if err != nil {
+		return err
+	}
+
+	if campusID.Valid && *role != "admin" {
+		return errors.New("--campus only works with --role admin")
+	}
+
+	token, invitationDB, err := cfg.createInvitation(commandContext("invitations create"), *role, campusID, *email, ttl, uuid.NullUUID{})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("invitation for %s, valid until %s\ntoken: %s\nlink:  %s\n", invitationDB.Role, invitationDB.ExpiresAt.Format(time.RFC3339), token, invitationLink(token))
+	return nil
+}
+
+// mockIdPCommand serves a fake campus identity provider to try single sign-on
+// without a real one.
+func mockIdPCommand(args []string) error {
+	flags := flag.NewFlagSet("oidc mock", flag.ContinueOnError)
+	addr := flags.String("addr", "localhost:9000", "address to listen on, the issuer is http://<addr>")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	idp, err := oidcmock.New("http://" + *addr)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("mock identity provider running, set OIDC_ISSUER=%s and any OIDC_CLIENT_ID\n", idp.Issuer)
+
+	return http.ListenAndServe(*addr, idp.Handler())
+}
+
+func (cfg *apiConfig) purgeTokensCommand() error {
+	purged, err := cfg.dbQueries.PurgeExpiredTokens(context.Background())
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("purged %d expired refresh tokens\n", purged)
+
+	purged, err = cfg.dbQueries.PurgeDeadSessions(context.Background())
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("purged %d sessions without refresh tokens\n", purged)
+
+	purged, err = cfg.dbQueries.PurgeExpiredVerificationTokens(context.Background())
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("purged %d expired email verification tokens\n", purged)
+
+	purged, err = cfg.dbQueries.PurgeExpiredPasswordResetTokens(context.Background())
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("purged %d expired password reset tokens\n", purged)
+
+	purged, err = cfg.dbQueries.PurgeStaleLoginThrottles(context.Background(), time.Now().Add(-loginFailureWindow))
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("purged %d forgotten failed login counters\n", purged)
+
+	purged, err = cfg.dbQueries.PurgeExpiredLoginChallenges(context.Background())
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("purged %d expired login challenges\n", purged)
+
+	purged, err = cfg.dbQueries.PurgeExpiredOIDCLoginStates(context.Background())
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("purged %d expired single sign-on logins\n", purged)
+	return nil
+}
+
+// deleteScheduledUsersCommand deletes the accounts whose grace period is over
+// now, the server does it every hour on its own.
+func (cfg *apiConfig) deleteScheduledUsersCommand() error {
+	deleted, err := cfg.deleteScheduledUsers(commandContext("users delete-scheduled"))
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("deleted %d accounts scheduled for deletion\n", deleted)
+	return nil
+}
+
+// recountOccupancyCommand fixes occupied slots that drifted from the number of
+// users currently parked in each lot.
+func (cfg *apiConfig) recountOccupancyCommand() error {
+	ctx := commandContext("occupancy recount")
+
+	tx, err := cfg.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	//read first for the audit log, the recount only returns the lots it fixed
+	beforeDB, err := qtx.GetParkingLots(ctx, uuid.NullUUID{})
+	if err != nil {
+		return err
+	}
+
+	before := map[uuid.UUID]database.Parkinglot{}
+	for _, lot := range beforeDB {
+		before[lot.ID] = lot
+	}
+
+	lotsDB, err := qtx.RecountOccupiedSlots(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, lot := range lotsDB {
+		lotBefore := before[lot.ID]
+		if err := audit(ctx, qtx, lotAuditEntry("lot.occupancy_recount", &lotBefore, &lot)); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	if len(lotsDB) == 0 {
+		fmt.Println("every lot already has the correct occupied slots")
+		return nil
+	}
+
+	return printLots(lotsDB)
+}
@@ -1,7 +1,18 @@
 package auth
 
 import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
 	"fmt"
+	"math/big"
 	"net/http"
 	"strings"
 	"time"
@@ -12,45 +23,317 @@ import (
 
 type CustomeClaims struct {
 	Role string `json:"role"`
+	// token version of the user when the token was made, see users.token_version
+	Version int32 `json:"ver"`
 	jwt.RegisteredClaims
 }
 
-func MakeJWT(userID uuid.UUID, role, tokenSecret string, expiresIn time.Duration) (string, error) {
+// JWTKeys signs and checks access tokens. Tokens are signed with the signing
+// key and accepted when their kid is one of the verification keys, so a new key
+// can be rolled out while tokens of the old one are still valid.
+type JWTKeys struct {
+	Issuer   string
+	Audience string
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, CustomeClaims{
-		Role: role,
+	method    jwt.SigningMethod
+	signing   crypto.Signer
+	signingID string
+	verify    map[string]crypto.PublicKey
+	// HS256 secret, only used while no signing key is configured
+	secret []byte
+}
+
+// NewHMACKeys signs with a shared secret like before signing keys existed.
+// Anyone verifying the tokens needs the secret, and changing it invalidates
+// every access token.
+func NewHMACKeys(secret, issuer, audience string) *JWTKeys {
+	return &JWTKeys{
+		Issuer:   issuer,
+		Audience: audience,
+		method:   jwt.SigningMethodHS256,
+		secret:   []byte(secret),
+	}
+}
+
+// NewJWTKeys signs with the Ed25519 (EdDSA) or RSA (RS256) private key in
+// signingPEM. verifyPEMs are keys of earlier rotations that are still
+// accepted, as public or private keys of the same kind.
+func NewJWTKeys(signingPEM []byte, verifyPEMs [][]byte, issuer, audience string) (*JWTKeys, error) {
+	signing, err := ParsePrivateKeyPEM(signingPEM)
+	if err != nil {
+		return nil, fmt.Errorf("signing key: %w", err)
+	}
+
+	keys := &JWTKeys{
+		Issuer:   issuer,
+		Audience: audience,
+		signing:  signing,
+		verify:   map[string]crypto.PublicKey{},
+	}
+
+	switch signing.(type) {
+	case ed25519.PrivateKey:
+		keys.method = jwt.SigningMethodEdDSA
+	case *rsa.PrivateKey:
+		keys.method = jwt.SigningMethodRS256
+	}
+
+	keys.signingID, err = KeyID(signing.Public())
+	if err != nil {
+		return nil, err
+	}
+	keys.verify[keys.signingID] = signing.Public()
+
+	for i, raw := range verifyPEMs {
+		public, err := parsePublicKeyPEM(raw)
+		if err != nil {
+			return nil, fmt.Errorf("verification key %d: %w", i+1, err)
+		}
+
+		if !sameKind(public, signing.Public()) {
+			return nil, fmt.Errorf("verification key %d is not a %s key", i+1, keys.method.Alg())
+		}
+
+		id, err := KeyID(public)
+		if err != nil {
+			return nil, err
+		}
+		keys.verify[id] = public
+	}
+
+	return keys, nil
+}
+
+func sameKind(a, b crypto.PublicKey) bool {
+	switch a.(type) {
+	case ed25519.PublicKey:
+		_, ok := b.(ed25519.PublicKey)
+		return ok
+	case *rsa.PublicKey:
+		_, ok := b.(*rsa.PublicKey)
+		return ok
+	}
+	return false
+}
+
+// ParsePrivateKeyPEM reads an Ed25519 or RSA private key in PKCS #8, or PKCS
+// #1 for RSA. RSA keys need at least 2048 bits.
+func ParsePrivateKeyPEM(raw []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(raw)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	var key any
+	var err error
+
+	if block.Type == "RSA PRIVATE KEY" {
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	} else {
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	switch key := key.(type) {
+	case ed25519.PrivateKey:
+		return key, nil
+	case *rsa.PrivateKey:
+		if key.N.BitLen() < 2048 {
+			return nil, errors.New("RSA keys need at least 2048 bits")
+		}
+		return key, nil
+	}
+
+	return nil, errors.New("only Ed25519 and RSA keys are supported")
+}
+
+func parsePublicKeyPEM(raw []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(raw)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	if block.Type != "PUBLIC KEY" {
+		private, err := ParsePrivateKeyPEM(raw)
+		if err != nil {
+			return nil, err
+		}
+		return private.Public(), nil
+	}
+
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	switch key.(type) {
+	case ed25519.PublicKey, *rsa.PublicKey:
+		return key, nil
+	}
+
+	return nil, errors.New("only Ed25519 and RSA keys are supported")
+}
+
+// KeyID is the kid of a public key, derived from the key so every server
+// with the same key agrees on it.
+func KeyID(public crypto.PublicKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(public)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(der)
+	return hex.EncodeToString(sum[:8]), nil
+}
+
+// GenerateSigningKeyPEM returns a new private key for alg (EdDSA or RS256) in
+// PKCS #8 PEM.
+func GenerateSigningKeyPEM(alg string) ([]byte, error) {
+	var key any
+	var err error
+
+	switch alg {
+	case "EdDSA":
+		_, key, err = ed25519.GenerateKey(rand.Reader)
+	case "RS256":
+		key, err = rsa.GenerateKey(rand.Reader, 2048)
+	default:
+		return nil, errors.New("alg must be EdDSA or RS256")
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+}
+
+func (k *JWTKeys) MakeJWT(userID uuid.UUID, role string, version int32, expiresIn time.Duration) (string, error) {
+
+	token := jwt.NewWithClaims(k.method, CustomeClaims{
+		Role:    role,
+		Version: version,
 		RegisteredClaims: jwt.RegisteredClaims{
-			Issuer:    "OntarioTechParkingGO",
+			Issuer:    k.Issuer,
+			Audience:  jwt.ClaimStrings{k.Audience},
 			IssuedAt:  jwt.NewNumericDate(time.Now().UTC()),
 			ExpiresAt: jwt.NewNumericDate(time.Now().UTC().Add(expiresIn)),
 			Subject:   userID.String(),
 		},
 	})
 
-	return token.SignedString([]byte(tokenSecret))
+	if k.signing == nil {
+		if len(k.secret) == 0 {
+			return "", errors.New("no JWT secret is set")
+		}
+		return token.SignedString(k.secret)
+	}
+
+	token.Header["kid"] = k.signingID
+	return token.SignedString(k.signing)
 
 }
 
-func ValidateJWT(tokenString, tokenSecret string) (uuid.UUID, string, error) {
+// ValidateJWT returns the user, role and token version of an access token.
+func (k *JWTKeys) ValidateJWT(tokenString string) (uuid.UUID, string, int32, error) {
+	parser := jwt.NewParser(
+		jwt.WithValidMethods([]string{k.method.Alg()}),
+		jwt.WithIssuer(k.Issuer),
+		jwt.WithAudience(k.Audience),
+		jwt.WithExpirationRequired(),
+	)
+
 	claim := &CustomeClaims{}
-	token, err := jwt.ParseWithClaims(tokenString, claim, func(t *jwt.Token) (interface{}, error) {
-		return []byte(tokenSecret), nil
+	token, err := parser.ParseWithClaims(tokenString, claim, func(t *jwt.Token) (interface{}, error) {
+		if k.signing == nil {
+			if len(k.secret) == 0 {
+				return nil, errors.New("no JWT secret is set")
+			}
+			return k.secret, nil
+		}
+
+		kid, _ := t.Header["kid"].(string)
+
+		public, ok := k.verify[kid]
+		if !ok {
+			return nil, fmt.Errorf("unknown key id %q", kid)
+		}
+
+		return public, nil
 	})
 
 	if err != nil {
-		return uuid.Nil, "", err
+		return uuid.Nil, "", 0, err
 	}
 
 	if token == nil || !token.Valid {
-		return uuid.Nil, "", fmt.Errorf("token not valid")
+		return uuid.Nil, "", 0, fmt.Errorf("token not valid")
 	}
 
 	userID, err := uuid.Parse(claim.Subject)
 	if err != nil {
-		return uuid.Nil, "", fmt.Errorf("invalid subject UUID")
+		return uuid.Nil, "", 0, fmt.Errorf("invalid subject UUID")
+	}
+
+	return userID, claim.Role, claim.Version, nil
+}
+
+// JWK is a public key as published in a JSON Web Key Set (RFC 7517).
+type JWK struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+}
+
+// JWKS lists every verification key, empty while tokens are signed with the
+// shared secret.
+func (k *JWTKeys) JWKS() []JWK {
+	keys := make([]JWK, 0, len(k.verify))
+
+	//the signing key first, clients that only take one take the right one
+	ids := []string{k.signingID}
+	for id := range k.verify {
+		if id != k.signingID {
+			ids = append(ids, id)
+		}
+	}
+
+	for _, id := range ids {
+		public, ok := k.verify[id]
+		if !ok {
+			continue
+		}
+
+		jwk := JWK{Use: "sig", Alg: k.method.Alg(), Kid: id}
+
+		switch public := public.(type) {
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(public)
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+		}
+
+		keys = append(keys, jwk)
 	}
 
-	return userID, claim.Role, nil
+	return keys
 }
 
 func GetBearerToken(header http.Header) (string, error) {
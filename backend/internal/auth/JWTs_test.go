@@ -0,0 +1,199 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+func newTestKeys(t *testing.T, alg string, verifyPEMs ...[]byte) (*JWTKeys, []byte) {
+	t.Helper()
+
+	signingPEM := mustKeyPEM(t, alg)
+
+	keys, err := NewJWTKeys(signingPEM, verifyPEMs, "issuer", "audience")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return keys, signingPEM
+}
+
+func TestValidateJWT(t *testing.T) {
+	userID := uuid.New()
+
+	for _, alg := range []string{"EdDSA", "RS256"} {
+		keys, _ := newTestKeys(t, alg)
+
+		token, err := keys.MakeJWT(userID, "admin", 3, time.Minute)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		gotID, role, version, err := keys.ValidateJWT(token)
+		if err != nil {
+			t.Fatalf("%s: ValidateJWT: %v", alg, err)
+		}
+		if gotID != userID || role != "admin" || version != 3 {
+			t.Errorf("%s: ValidateJWT = %s, %s, %d, want %s, admin, 3", alg, gotID, role, version, userID)
+		}
+	}
+}
+
+// TestValidateJWTRejects checks tokens of another issuer, audience or key are
+// refused, as are expired and unsigned tokens.
+func TestValidateJWTRejects(t *testing.T) {
+	keys, signingPEM := newTestKeys(t, "EdDSA")
+	otherKeys, _ := newTestKeys(t, "EdDSA")
+	userID := uuid.New()
+
+	sameKey := func(issuer, audience string) *JWTKeys {
+		t.Helper()
+
+		k, err := NewJWTKeys(signingPEM, nil, issuer, audience)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return k
+	}
+
+	sign := func(k *JWTKeys, expiresIn time.Duration) string {
+		t.Helper()
+
+		token, err := k.MakeJWT(userID, "student", 0, expiresIn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+
+	withKid := func(kid any) string {
+		t.Helper()
+
+		token := jwt.NewWithClaims(jwt.SigningMethodEdDSA, CustomeClaims{
+			Role: "student",
+			RegisteredClaims: jwt.RegisteredClaims{
+				Issuer:    keys.Issuer,
+				Audience:  jwt.ClaimStrings{keys.Audience},
+				ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+				Subject:   userID.String(),
+			},
+		})
+		if kid != nil {
+			token.Header["kid"] = kid
+		}
+
+		signed, err := token.SignedString(keys.signing)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return signed
+	}
+
+	unsigned, err := jwt.NewWithClaims(jwt.SigningMethodNone, CustomeClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    keys.Issuer,
+			Audience:  jwt.ClaimStrings{keys.Audience},
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+			Subject:   userID.String(),
+		},
+	}).SignedString(jwt.UnsafeAllowNoneSignatureType)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name  string
+		token string
+	}{
+		{"other issuer", sign(sameKey("other", keys.Audience), time.Minute)},
+		{"other audience", sign(sameKey(keys.Issuer, "other"), time.Minute)},
+		{"other key", sign(otherKeys, time.Minute)},
+		{"expired", sign(keys, -time.Minute)},
+		{"no kid", withKid(nil)},
+		{"unknown kid", withKid("0123456789abcdef")},
+		{"kid of another key", withKid(otherKeys.signingID)},
+		{"kid not a string", withKid(42)},
+		{"unsigned", unsigned},
+		{"HS256", sign(NewHMACKeys("secret", keys.Issuer, keys.Audience), time.Minute)},
+		{"garbage", "not.a.token"},
+	}
+
+	for _, c := range cases {
+		if _, _, _, err := keys.ValidateJWT(c.token); err == nil {
+			t.Errorf("%s: ValidateJWT accepted the token", c.name)
+		}
+	}
+}
+
+// TestValidateJWTRotation checks tokens of a rotated out key are accepted as
+// long as the key is a verification key, under its own kid.
+func TestValidateJWTRotation(t *testing.T) {
+	oldKeys, oldPEM := newTestKeys(t, "EdDSA")
+	newKeys, _ := newTestKeys(t, "EdDSA", oldPEM)
+
+	oldToken, err := oldKeys.MakeJWT(uuid.New(), "student", 0, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, _, err := newKeys.ValidateJWT(oldToken); err != nil {
+		t.Errorf("token of the old key refused: %v", err)
+	}
+
+	newToken, err := newKeys.MakeJWT(uuid.New(), "student", 0, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, _, err := oldKeys.ValidateJWT(newToken); err == nil {
+		t.Error("token of the new key accepted by the old key alone")
+	}
+
+	jwks := newKeys.JWKS()
+	if len(jwks) != 2 || jwks[0].Kid != newKeys.signingID || jwks[1].Kid != oldKeys.signingID {
+		t.Errorf("JWKS = %+v, want the new key then the old one", jwks)
+	}
+
+	if _, err := NewJWTKeys(oldPEM, [][]byte{mustKeyPEM(t, "RS256")}, "issuer", "audience"); err == nil {
+		t.Error("an RS256 verification key was accepted next to an EdDSA signing key")
+	}
+}
+
+func TestHMACKeys(t *testing.T) {
+	keys := NewHMACKeys("secret", "issuer", "audience")
+
+	token, err := keys.MakeJWT(uuid.New(), "student", 0, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, _, err := keys.ValidateJWT(token); err != nil {
+		t.Errorf("ValidateJWT: %v", err)
+	}
+
+	if _, _, _, err := NewHMACKeys("other", "issuer", "audience").ValidateJWT(token); err == nil {
+		t.Error("token accepted with another secret")
+	}
+
+	empty := NewHMACKeys("", "issuer", "audience")
+	if _, err := empty.MakeJWT(uuid.New(), "student", 0, time.Minute); err == nil {
+		t.Error("MakeJWT signed with an empty secret")
+	}
+
+	if _, _, _, err := empty.ValidateJWT(token); err == nil {
+		t.Error("ValidateJWT accepted a token with an empty secret")
+	}
+}
+
+func mustKeyPEM(t *testing.T, alg string) []byte {
+	t.Helper()
+
+	keyPEM, err := GenerateSigningKeyPEM(alg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return keyPEM
+}
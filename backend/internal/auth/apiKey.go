@@ -0,0 +1,49 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+)
+
+// APIKeyPrefix starts every API key, so they are easy to tell apart from JWTs
+// and to find in leaked code.
+const APIKeyPrefix = "pgo_"
+
+// apiKeyLookupBytes is the random part of the prefix. key_prefix is unique,
+// 8 bytes keep collisions out of reach, keys made before had 4.
+const (
+	apiKeyLookupBytes       = 8
+	legacyAPIKeyLookupBytes = 4
+)
+
+// MakeAPIKey returns a new key and its lookup prefix. The prefix is stored and
+// shown in plain, the rest is only ever stored hashed with HashToken.
+func MakeAPIKey() (key, prefix string, err error) {
+	lookup := make([]byte, apiKeyLookupBytes)
+	if _, err := rand.Read(lookup); err != nil {
+		return "", "", err
+	}
+
+	secret, err := MakeRefreshToken()
+	if err != nil {
+		return "", "", err
+	}
+
+	prefix = APIKeyPrefix + hex.EncodeToString(lookup)
+	return prefix + "_" + secret, prefix, nil
+}
+
+// APIKeyLookupPrefix returns the prefix of key, ok is false if key is not
+// shaped like an API key.
+func APIKeyLookupPrefix(key string) (prefix string, ok bool) {
+	for _, lookupBytes := range []int{apiKeyLookupBytes, legacyAPIKeyLookupBytes} {
+		prefixLen := len(APIKeyPrefix) + 2*lookupBytes
+
+		if strings.HasPrefix(key, APIKeyPrefix) && len(key) > prefixLen+1 && key[prefixLen] == '_' {
+			return key[:prefixLen], true
+		}
+	}
+
+	return "", false
+}
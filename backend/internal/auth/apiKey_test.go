@@ -0,0 +1,66 @@
+package auth
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func TestMakeAPIKey(t *testing.T) {
+	seen := map[string]bool{}
+
+	for i := 0; i < 1000; i++ {
+		key, prefix, err := MakeAPIKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		lookup := strings.TrimPrefix(prefix, APIKeyPrefix)
+		if lookup == prefix {
+			t.Fatalf("prefix %q does not start with %q", prefix, APIKeyPrefix)
+		}
+		if raw, err := hex.DecodeString(lookup); err != nil || len(raw) != apiKeyLookupBytes {
+			t.Fatalf("prefix %q does not have %d random bytes", prefix, apiKeyLookupBytes)
+		}
+		if !strings.HasPrefix(key, prefix+"_") {
+			t.Fatalf("key %q does not start with its prefix %q", key, prefix)
+		}
+
+		got, ok := APIKeyLookupPrefix(key)
+		if !ok || got != prefix {
+			t.Fatalf("APIKeyLookupPrefix(%q) = %q, %t, want %q", key, got, ok, prefix)
+		}
+
+		if seen[prefix] {
+			t.Fatalf("prefix %q was made twice", prefix)
+		}
+		seen[prefix] = true
+	}
+}
+
+func TestAPIKeyLookupPrefix(t *testing.T) {
+	secret := strings.Repeat("ab", 32)
+
+	cases := []struct {
+		name   string
+		key    string
+		prefix string
+		ok     bool
+	}{
+		{"key", "pgo_0123456789abcdef_" + secret, "pgo_0123456789abcdef", true},
+		{"legacy key", "pgo_01234567_" + secret, "pgo_01234567", true},
+		{"no secret", "pgo_0123456789abcdef_", "", false},
+		{"no separator", "pgo_0123456789abcdef" + secret, "", false},
+		{"short prefix", "pgo_012345_" + secret, "", false},
+		{"other prefix", "abc_0123456789abcdef_" + secret, "", false},
+		{"JWT", "eyJhbGciOiJIUzI1NiJ9.e30.sig", "", false},
+		{"empty", "", "", false},
+	}
+
+	for _, c := range cases {
+		prefix, ok := APIKeyLookupPrefix(c.key)
+		if prefix != c.prefix || ok != c.ok {
+			t.Errorf("%s: APIKeyLookupPrefix = %q, %t, want %q, %t", c.name, prefix, ok, c.prefix, c.ok)
+		}
+	}
+}
@@ -0,0 +1,14 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// HashToken hashes a random token that is emailed to a user. The token already
+// has 256 bits of entropy, so a fast hash is enough to keep database leaks
+// from exposing usable tokens.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,113 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// TOTP codes follow RFC 6238 with the defaults every authenticator app
+// supports: SHA1, 6 digits and a 30 second step.
+const (
+	totpDigits = 6
+	totpPeriod = 30
+	// codes of the step before and after are accepted too, for clock drift
+	totpSkew = 1
+)
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// MakeTOTPSecret returns a new random base32 secret for an authenticator app.
+func MakeTOTPSecret() (string, error) {
+	key := make([]byte, 20)
+
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+
+	return totpEncoding.EncodeToString(key), nil
+}
+
+// TOTPURI is the otpauth:// URI authenticator apps read from a QR code.
+func TOTPURI(secret, issuer, account string) string {
+	query := url.Values{}
+	query.Set("secret", secret)
+	query.Set("issuer", issuer)
+	query.Set("algorithm", "SHA1")
+	query.Set("digits", fmt.Sprint(totpDigits))
+	query.Set("period", fmt.Sprint(totpPeriod))
+
+	return (&url.URL{
+		Scheme:   "otpauth",
+		Host:     "totp",
+		Path:     "/" + issuer + ":" + account,
+		RawQuery: query.Encode(),
+	}).String()
+}
+
+func totpCode(key []byte, step int64) string {
+	counter := make([]byte, 8)
+	binary.BigEndian.PutUint64(counter, uint64(step))
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(counter)
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	return fmt.Sprintf("%06d", value%1000000)
+}
+
+// CheckTOTP reports whether code is valid for secret at now and returns the
+// step it belongs to, so callers can refuse a code that was already used.
+func CheckTOTP(secret, code string, now time.Time) (int64, bool) {
+	key, err := totpEncoding.DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return 0, false
+	}
+
+	code = strings.ReplaceAll(code, " ", "")
+	if len(code) != totpDigits {
+		return 0, false
+	}
+
+	current := now.Unix() / totpPeriod
+
+	for step := current - totpSkew; step <= current+totpSkew; step++ {
+		if subtle.ConstantTimeCompare([]byte(totpCode(key, step)), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+
+	return 0, false
+}
+
+// MakeRecoveryCode returns a one time code like "k3j9dx8q-2mfh7c4a" that can
+// replace a TOTP code when the authenticator is lost.
+func MakeRecoveryCode() (string, error) {
+	key := make([]byte, 10)
+
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+
+	code := strings.ToLower(totpEncoding.EncodeToString(key))
+
+	return code[:8] + "-" + code[8:], nil
+}
+
+// NormalizeRecoveryCode undoes the formatting a user may add or remove when
+// typing a recovery code, before it is hashed.
+func NormalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	code = strings.ReplaceAll(code, "-", "")
+	return strings.ReplaceAll(code, " ", "")
+}
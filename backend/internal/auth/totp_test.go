@@ -0,0 +1,116 @@
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+// rfc6238Secret is the SHA1 key of RFC 6238 appendix B.
+var rfc6238Secret = totpEncoding.EncodeToString([]byte("12345678901234567890"))
+
+// The appendix lists 8 digit codes, 6 digit codes are their last 6 digits.
+var rfc6238Vectors = []struct {
+	unix int64
+	code string
+}{
+	{59, "287082"},
+	{1111111109, "081804"},
+	{1111111111, "050471"},
+	{1234567890, "005924"},
+	{2000000000, "279037"},
+	{20000000000, "353130"},
+}
+
+func TestTOTPCodeRFC6238(t *testing.T) {
+	for _, v := range rfc6238Vectors {
+		if got := totpCode([]byte("12345678901234567890"), v.unix/totpPeriod); got != v.code {
+			t.Errorf("code at %d = %s, want %s", v.unix, got, v.code)
+		}
+	}
+}
+
+func TestCheckTOTP(t *testing.T) {
+	for _, v := range rfc6238Vectors {
+		now := time.Unix(v.unix, 0)
+
+		step, ok := CheckTOTP(rfc6238Secret, v.code, now)
+		if !ok {
+			t.Errorf("CheckTOTP rejected the code of %d", v.unix)
+			continue
+		}
+
+		if step != v.unix/totpPeriod {
+			t.Errorf("CheckTOTP returned step %d for %d, want %d", step, v.unix, v.unix/totpPeriod)
+		}
+	}
+}
+
+func TestCheckTOTPSkew(t *testing.T) {
+	// the code of 1111111109 belongs to step 37037036
+	at := func(step int64) time.Time {
+		return time.Unix(step*totpPeriod, 0)
+	}
+
+	tests := []struct {
+		name string
+		now  time.Time
+		want bool
+	}{
+		{"same step", at(37037036), true},
+		{"one step later", at(37037037), true},
+		{"one step earlier", at(37037035), true},
+		{"two steps later", at(37037038), false},
+		{"two steps earlier", at(37037034), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, ok := CheckTOTP(rfc6238Secret, "081804", tt.now); ok != tt.want {
+				t.Fatalf("CheckTOTP = %v, want %v", ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckTOTPInput(t *testing.T) {
+	now := time.Unix(1111111109, 0)
+
+	tests := []struct {
+		name   string
+		secret string
+		code   string
+		want   bool
+	}{
+		{"spaces in the code", rfc6238Secret, "081 804", true},
+		{"lowercase secret", "gezdgnbvgy3tqojqgezdgnbvgy3tqojq", "081804", true},
+		{"wrong code", rfc6238Secret, "081805", false},
+		{"too short", rfc6238Secret, "81804", false},
+		{"8 digits", rfc6238Secret, "07081804", false},
+		{"empty", rfc6238Secret, "", false},
+		{"invalid secret", "not base32!", "081804", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, ok := CheckTOTP(tt.secret, tt.code, now); ok != tt.want {
+				t.Fatalf("CheckTOTP(%q, %q) = %v, want %v", tt.secret, tt.code, ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecoveryCode(t *testing.T) {
+	code, err := MakeRecoveryCode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(code) != 17 || code[8] != '-' {
+		t.Fatalf("MakeRecoveryCode() = %q, want xxxxxxxx-xxxxxxxx", code)
+	}
+
+	typed := " " + code[:4] + " " + code[4:8] + code[9:] + " "
+	if NormalizeRecoveryCode(typed) != NormalizeRecoveryCode(code) {
+		t.Fatalf("NormalizeRecoveryCode(%q) does not match %q", typed, code)
+	}
+}
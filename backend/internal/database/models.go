@@ -6,16 +6,63 @@ package database
 
 import (
 	"database/sql"
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+type AdminInvitation struct {
+	ID            uuid.UUID
+	TokenHash     string
+	Role          string
+	AdminCampusID uuid.NullUUID
+	Email         sql.NullString
+	CreatedBy     uuid.NullUUID
+	ExpiresAt     time.Time
+	RedeemedBy    uuid.NullUUID
+	RedeemedAt    sql.NullTime
+	RevokedAt     sql.NullTime
+	CreatedAt     time.Time
+}
+
+type ApiKey struct {
+	ID         uuid.UUID
+	Name       string
+	KeyPrefix  string
+	KeyHash    string
+	Scopes     string
+	CreatedBy  uuid.UUID
+	ExpiresAt  sql.NullTime
+	LastUsedAt sql.NullTime
+	RevokedAt  sql.NullTime
+	CreatedAt  time.Time
+}
+
+type AuditLog struct {
+	ID         uuid.UUID
+	ActorID    uuid.NullUUID
+	ActorRole  sql.NullString
+	ApiKeyID   uuid.NullUUID
+	Action     string
+	TargetType string
+	TargetID   uuid.NullUUID
+	CampusID   uuid.NullUUID
+	Before     json.RawMessage
+	After      json.RawMessage
+	Ip         string
+	UserAgent  string
+	Method     string
+	Path       string
+	CreatedAt  time.Time
+}
+
 type AverageLotRating struct {
 	Lotid         uuid.UUID
 	Lotname       string
 	Averagerating string
 	Totalreviews  int64
+	CampusID      uuid.UUID
 }
 
 type AvgParkingTimePerUser struct {
@@ -24,28 +71,80 @@ type AvgParkingTimePerUser struct {
 	AvgMinutesParked string
 }
 
+type Campus struct {
+	ID        uuid.UUID
+	Name      string
+	CreatedAt time.Time
+	TimeZone  string
+}
+
+type Citation struct {
+	ID                uuid.UUID
+	OfficerID         uuid.NullUUID
+	UserID            uuid.NullUUID
+	LicensePlate      sql.NullString
+	ParkingLotID      uuid.UUID
+	ParkingLogID      uuid.NullUUID
+	Violation         string
+	Notes             sql.NullString
+	Status            string
+	AppealReason      sql.NullString
+	AppealedAt        sql.NullTime
+	ResolvedBy        uuid.NullUUID
+	ResolvedAt        sql.NullTime
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
+	FineTransactionID uuid.NullUUID
+}
+
+type CitationPhoto struct {
+	ID         uuid.UUID
+	CitationID uuid.UUID
+	Url        string
+	CreatedAt  time.Time
+}
+
 type CountOfLogsPerLot struct {
 	Lotid        uuid.UUID
 	Lotname      string
 	Totalentries int64
+	CampusID     uuid.UUID
 }
 
 type CountOfLogsPerUser struct {
 	Userid       uuid.NullUUID
 	Username     sql.NullString
 	Totalentries int64
+	CampusID     uuid.NullUUID
 }
 
 type CountOfReviewPerLot struct {
 	Lotid        uuid.UUID
 	Lotname      string
 	Totalreviews int64
+	CampusID     uuid.UUID
 }
 
 type CountOfReviewsPerUser struct {
 	Userid       uuid.UUID
 	Username     string
 	Totalreviews int64
+	CampusID     uuid.NullUUID
+}
+
+type DailyLotEntry struct {
+	Lotid        uuid.UUID
+	Day          time.Time
+	Totalentries int64
+	CampusID     uuid.UUID
+}
+
+type EmailVerificationToken struct {
+	TokenHash string
+	UserID    uuid.UUID
+	ExpiresAt time.Time
+	CreatedAt time.Time
+	Email     string
 }
 
 type FullParkingLot struct {
@@ -53,11 +152,67 @@ type FullParkingLot struct {
 	Name          string
 	Slots         int32
 	Occupiedslots int32
+	CampusID      uuid.UUID
+}
+
+type LedgerAccount struct {
+	ID        uuid.UUID
+	Name      string
+	UserID    uuid.NullUUID
+	Balance   int64
+	CreatedAt time.Time
+}
+
+type LedgerEntry struct {
+	ID            uuid.UUID
+	TransactionID uuid.UUID
+	AccountID     uuid.UUID
+	Amount        int64
+	Balance       int64
+	CreatedAt     time.Time
+}
+
+type LedgerTransaction struct {
+	ID          uuid.UUID
+	Kind        string
+	Description sql.NullString
+	Reference   sql.NullString
+	CreatedAt   time.Time
+	RefundOf    uuid.NullUUID
+}
+
+type LoginChallenge struct {
+	TokenHash string
+	UserID    uuid.UUID
+	ExpiresAt time.Time
+	CreatedAt time.Time
+}
+
+type LoginThrottle struct {
+	Kind          string
+	Value         string
+	Failures      int32
+	LockedUntil   sql.NullTime
+	LastFailureAt time.Time
+}
+
+type LotManager struct {
+	UserID       uuid.UUID
+	ParkingLotID uuid.UUID
+	CreatedAt    time.Time
+}
+
+type OidcLoginState struct {
+	StateHash    string
+	CodeVerifier string
+	Nonce        string
+	ExpiresAt    time.Time
+	CreatedAt    time.Time
 }
 
 type ParkingLog struct {
 	ID           uuid.UUID
-	UserID       uuid.UUID
+	UserID       uuid.NullUUID
 	ParkingLotID uuid.UUID
 	EventType    string
 	Time         time.Time
@@ -68,6 +223,15 @@ type Parkinglot struct {
 	Name          string
 	Slots         int32
 	Occupiedslots int32
+	CampusID      uuid.UUID
+}
+
+type PasswordResetToken struct {
+	TokenHash string
+	UserID    uuid.UUID
+	ExpiresAt time.Time
+	UsedAt    sql.NullTime
+	CreatedAt time.Time
 }
 
 type RefreshToken struct {
@@ -75,6 +239,13 @@ type RefreshToken struct {
 	UserID    uuid.UUID
 	ExpiresAt time.Time
 	RevokedAt sql.NullTime
+	FamilyID  uuid.UUID
+	RotatedAt sql.NullTime
+}
+
+type RestoredLogMonth struct {
+	Month      time.Time
+	RestoredAt time.Time
 }
 
 type Review struct {
@@ -87,6 +258,16 @@ type Review struct {
 	UpdatedAt    time.Time
 }
 
+type Session struct {
+	ID         uuid.UUID
+	UserID     uuid.UUID
+	UserAgent  string
+	Ip         string
+	CreatedAt  time.Time
+	LastUsedAt time.Time
+	ExpiresAt  time.Time
+}
+
 type Test struct {
 	ID        uuid.UUID
 	Name      string
@@ -95,20 +276,38 @@ type Test struct {
 }
 
 type TopRatedLot struct {
-	ID    uuid.UUID
-	Name  string
-	Round string
+	ID       uuid.UUID
+	Name     string
+	Round    string
+	CampusID uuid.UUID
+}
+
+type TotpRecoveryCode struct {
+	CodeHash  string
+	UserID    uuid.UUID
+	UsedAt    sql.NullTime
+	CreatedAt time.Time
 }
 
 type User struct {
-	ID             uuid.UUID
-	Name           string
-	Email          string
-	HashedPassword string
-	Role           string
-	ParkingLotID   uuid.NullUUID
-	CreatedAt      time.Time
-	UpdatedAt      time.Time
+	ID                  uuid.UUID
+	Name                string
+	Email               string
+	HashedPassword      string
+	Role                string
+	ParkingLotID        uuid.NullUUID
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
+	LicensePlate        sql.NullString
+	CampusID            uuid.NullUUID
+	AdminCampusID       uuid.NullUUID
+	DisabledAt          sql.NullTime
+	EmailVerifiedAt     sql.NullTime
+	TotpSecret          sql.NullString
+	TotpEnabledAt       sql.NullTime
+	TotpLastStep        sql.NullInt64
+	TokenVersion        int32
+	DeletionScheduledAt sql.NullTime
 }
 
 type UserHighestLowestRating struct {
@@ -124,6 +323,15 @@ type UserHighestLowestRating struct {
 	Reviewtype  string
 }
 
+type UserIdentity struct {
+	Issuer      string
+	Subject     string
+	UserID      uuid.UUID
+	Email       string
+	CreatedAt   time.Time
+	LastLoginAt time.Time
+}
+
 type UserReviewsWithLot struct {
 	Userid      uuid.UUID
 	Username    string
@@ -7,10 +7,31 @@ package database
 
 import (
 	"context"
+	"database/sql"
+	"time"
 
 	"github.com/google/uuid"
 )
 
+const attachParkingLogsPartition = `-- name: AttachParkingLogsPartition :exec
+SELECT attach_parking_logs_partition($1::timestamptz)
+`
+
+func (q *Queries) AttachParkingLogsPartition(ctx context.Context, month time.Time) error {
+	_, err := q.db.ExecContext(ctx, attachParkingLogsPartition, month)
+	return err
+}
+
+const clearLogMonthRestored = `-- name: ClearLogMonthRestored :exec
+DELETE FROM restored_log_months
+WHERE month = $1::timestamptz
+`
+
+func (q *Queries) ClearLogMonthRestored(ctx context.Context, month time.Time) error {
+	_, err := q.db.ExecContext(ctx, clearLogMonthRestored, month)
+	return err
+}
+
 const createLog = `-- name: CreateLog :one
 INSERT INTO parking_logs(id, user_id, parking_lot_id, event_type, time)
 VALUES (
@@ -23,7 +44,7 @@ VALUES (
 `
 
 type CreateLogParams struct {
-	UserID       uuid.UUID
+	UserID       uuid.NullUUID
 	ParkingLotID uuid.UUID
 	EventType    string
 }
@@ -41,12 +62,172 @@ func (q *Queries) CreateLog(ctx context.Context, arg CreateLogParams) (ParkingLo
 	return i, err
 }
 
+const createLogAt = `-- name: CreateLogAt :one
+INSERT INTO parking_logs(id, user_id, parking_lot_id, event_type, time)
+VALUES (
+    gen_random_uuid(),
+    $1,
+    $2,
+    $3,
+    $4
+) RETURNING id, user_id, parking_lot_id, event_type, time
+`
+
+type CreateLogAtParams struct {
+	UserID       uuid.NullUUID
+	ParkingLotID uuid.UUID
+	EventType    string
+	Time         time.Time
+}
+
+func (q *Queries) CreateLogAt(ctx context.Context, arg CreateLogAtParams) (ParkingLog, error) {
+	row := q.db.QueryRowContext(ctx, createLogAt,
+		arg.UserID,
+		arg.ParkingLotID,
+		arg.EventType,
+		arg.Time,
+	)
+	var i ParkingLog
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.ParkingLotID,
+		&i.EventType,
+		&i.Time,
+	)
+	return i, err
+}
+
+const createParkingLogsPartition = `-- name: CreateParkingLogsPartition :exec
+SELECT create_parking_logs_partition($1::timestamptz)
+`
+
+func (q *Queries) CreateParkingLogsPartition(ctx context.Context, month time.Time) error {
+	_, err := q.db.ExecContext(ctx, createParkingLogsPartition, month)
+	return err
+}
+
+const detachParkingLogsPartition = `-- name: DetachParkingLogsPartition :exec
+SELECT detach_parking_logs_partition($1::timestamptz)
+`
+
+func (q *Queries) DetachParkingLogsPartition(ctx context.Context, month time.Time) error {
+	_, err := q.db.ExecContext(ctx, detachParkingLogsPartition, month)
+	return err
+}
+
+const dropParkingLogsPartition = `-- name: DropParkingLogsPartition :exec
+SELECT drop_parking_logs_partition($1::timestamptz)
+`
+
+func (q *Queries) DropParkingLogsPartition(ctx context.Context, month time.Time) error {
+	_, err := q.db.ExecContext(ctx, dropParkingLogsPartition, month)
+	return err
+}
+
+const getDetachedParkingLogPartitions = `-- name: GetDetachedParkingLogPartitions :many
+SELECT relname::text AS name
+FROM pg_class
+WHERE relkind = 'r' AND relname ~ '^parking_logs_[0-9]{4}_[0-9]{2}$'
+AND NOT EXISTS (
+    SELECT 1 FROM pg_inherits
+    WHERE pg_inherits.inhrelid = pg_class.oid
+)
+ORDER BY relname
+`
+
+func (q *Queries) GetDetachedParkingLogPartitions(ctx context.Context) ([]string, error) {
+	rows, err := q.db.QueryContext(ctx, getDetachedParkingLogPartitions)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		items = append(items, name)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getDetachedParkingLogs = `-- name: GetDetachedParkingLogs :many
+SELECT id, user_id, parking_lot_id, event_type, time
+FROM detached_parking_logs($1::timestamptz)
+`
+
+func (q *Queries) GetDetachedParkingLogs(ctx context.Context, month time.Time) ([]ParkingLog, error) {
+	rows, err := q.db.QueryContext(ctx, getDetachedParkingLogs, month)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ParkingLog
+	for rows.Next() {
+		var i ParkingLog
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.ParkingLotID,
+			&i.EventType,
+			&i.Time,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getLatestLogFromUserAndLot = `-- name: GetLatestLogFromUserAndLot :one
+SELECT id, user_id, parking_lot_id, event_type, time
+FROM parking_logs
+WHERE user_id = $1 AND parking_lot_id = $2
+ORDER BY time DESC
+LIMIT 1
+`
+
+type GetLatestLogFromUserAndLotParams struct {
+	UserID       uuid.NullUUID
+	ParkingLotID uuid.UUID
+}
+
+func (q *Queries) GetLatestLogFromUserAndLot(ctx context.Context, arg GetLatestLogFromUserAndLotParams) (ParkingLog, error) {
+	row := q.db.QueryRowContext(ctx, getLatestLogFromUserAndLot, arg.UserID, arg.ParkingLotID)
+	var i ParkingLog
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.ParkingLotID,
+		&i.EventType,
+		&i.Time,
+	)
+	return i, err
+}
+
 const getLogs = `-- name: GetLogs :many
 SELECT id, user_id, parking_lot_id, event_type, time FROM parking_logs
+WHERE $1::uuid IS NULL OR parking_lot_id IN (
+    SELECT id FROM parkinglots WHERE campus_id = $1
+)
 `
 
-func (q *Queries) GetLogs(ctx context.Context) ([]ParkingLog, error) {
-	rows, err := q.db.QueryContext(ctx, getLogs)
+func (q *Queries) GetLogs(ctx context.Context, campusID uuid.NullUUID) ([]ParkingLog, error) {
+	rows, err := q.db.QueryContext(ctx, getLogs, campusID)
 	if err != nil {
 		return nil, err
 	}
@@ -74,6 +255,71 @@ func (q *Queries) GetLogs(ctx context.Context) ([]ParkingLog, error) {
 	return items, nil
 }
 
+const getLogsForExport = `-- name: GetLogsForExport :many
+SELECT parking_logs.id, parking_logs.user_id, parking_logs.parking_lot_id, parking_logs.event_type, parking_logs.time, parkinglots.name AS lot_name
+FROM parking_logs
+INNER JOIN parkinglots ON parking_logs.parking_lot_id = parkinglots.id
+WHERE ($1::timestamptz IS NULL OR parking_logs.time >= $1)
+AND ($2::timestamptz IS NULL OR parking_logs.time < $2)
+AND ($3::uuid IS NULL OR parking_logs.parking_lot_id = $3)
+AND ($4::text IS NULL OR parking_logs.event_type = $4)
+AND ($5::uuid IS NULL OR parkinglots.campus_id = $5)
+ORDER BY parking_logs.time ASC
+`
+
+type GetLogsForExportParams struct {
+	FromTime  sql.NullTime
+	ToTime    sql.NullTime
+	LotID     uuid.NullUUID
+	EventType sql.NullString
+	CampusID  uuid.NullUUID
+}
+
+type GetLogsForExportRow struct {
+	ID           uuid.UUID
+	UserID       uuid.NullUUID
+	ParkingLotID uuid.UUID
+	EventType    string
+	Time         time.Time
+	LotName      string
+}
+
+func (q *Queries) GetLogsForExport(ctx context.Context, arg GetLogsForExportParams) ([]GetLogsForExportRow, error) {
+	rows, err := q.db.QueryContext(ctx, getLogsForExport,
+		arg.FromTime,
+		arg.ToTime,
+		arg.LotID,
+		arg.EventType,
+		arg.CampusID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetLogsForExportRow
+	for rows.Next() {
+		var i GetLogsForExportRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.ParkingLotID,
+			&i.EventType,
+			&i.Time,
+			&i.LotName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getLogsFromLotID = `-- name: GetLogsFromLotID :many
 SELECT id, user_id, parking_lot_id, event_type, time
 FROM parking_logs
@@ -116,7 +362,7 @@ FROM parking_logs
 WHERE user_id = $1
 `
 
-func (q *Queries) GetLogsFromUserID(ctx context.Context, userID uuid.UUID) ([]ParkingLog, error) {
+func (q *Queries) GetLogsFromUserID(ctx context.Context, userID uuid.NullUUID) ([]ParkingLog, error) {
 	rows, err := q.db.QueryContext(ctx, getLogsFromUserID, userID)
 	if err != nil {
 		return nil, err
@@ -144,3 +390,104 @@ func (q *Queries) GetLogsFromUserID(ctx context.Context, userID uuid.UUID) ([]Pa
 	}
 	return items, nil
 }
+
+const getParkingLogPartitions = `-- name: GetParkingLogPartitions :many
+SELECT child.relname::text AS name
+FROM pg_inherits
+INNER JOIN pg_class parent ON pg_inherits.inhparent = parent.oid
+INNER JOIN pg_class child ON pg_inherits.inhrelid = child.oid
+WHERE parent.relname = 'parking_logs'
+ORDER BY child.relname
+`
+
+func (q *Queries) GetParkingLogPartitions(ctx context.Context) ([]string, error) {
+	rows, err := q.db.QueryContext(ctx, getParkingLogPartitions)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		items = append(items, name)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getRestoredLogMonths = `-- name: GetRestoredLogMonths :many
+SELECT month
+FROM restored_log_months
+ORDER BY month
+`
+
+func (q *Queries) GetRestoredLogMonths(ctx context.Context) ([]time.Time, error) {
+	rows, err := q.db.QueryContext(ctx, getRestoredLogMonths)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []time.Time
+	for rows.Next() {
+		var month time.Time
+		if err := rows.Scan(&month); err != nil {
+			return nil, err
+		}
+		items = append(items, month)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const markLogMonthRestored = `-- name: MarkLogMonthRestored :exec
+INSERT INTO restored_log_months(month, restored_at)
+VALUES ($1::timestamptz, NOW())
+ON CONFLICT (month) DO UPDATE SET restored_at = NOW()
+`
+
+func (q *Queries) MarkLogMonthRestored(ctx context.Context, month time.Time) error {
+	_, err := q.db.ExecContext(ctx, markLogMonthRestored, month)
+	return err
+}
+
+const restoreLog = `-- name: RestoreLog :execrows
+INSERT INTO parking_logs(id, user_id, parking_lot_id, event_type, time)
+SELECT $1::uuid, (SELECT users.id FROM users WHERE users.id = $2::uuid), $3::uuid, $4::text, $5::timestamptz
+WHERE EXISTS (SELECT 1 FROM parkinglots WHERE parkinglots.id = $3)
+ON CONFLICT DO NOTHING
+`
+
+type RestoreLogParams struct {
+	ID           uuid.UUID
+	UserID       uuid.NullUUID
+	ParkingLotID uuid.UUID
+	EventType    string
+	Time         time.Time
+}
+
+func (q *Queries) RestoreLog(ctx context.Context, arg RestoreLogParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, restoreLog,
+		arg.ID,
+		arg.UserID,
+		arg.ParkingLotID,
+		arg.EventType,
+		arg.Time,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
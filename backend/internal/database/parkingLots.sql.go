@@ -12,30 +12,53 @@ import (
 	"github.com/google/uuid"
 )
 
+const countParkingLotDependents = `-- name: CountParkingLotDependents :one
+SELECT
+    (SELECT COUNT(*) FROM parking_logs WHERE parking_logs.parking_lot_id = $1) AS logs,
+    (SELECT COUNT(*) FROM reviews WHERE reviews.parking_lot_id = $1) AS reviews,
+    (SELECT COUNT(*) FROM citations WHERE citations.parking_lot_id = $1) AS citations
+`
+
+type CountParkingLotDependentsRow struct {
+	Logs      int64
+	Reviews   int64
+	Citations int64
+}
+
+func (q *Queries) CountParkingLotDependents(ctx context.Context, id uuid.UUID) (CountParkingLotDependentsRow, error) {
+	row := q.db.QueryRowContext(ctx, countParkingLotDependents, id)
+	var i CountParkingLotDependentsRow
+	err := row.Scan(&i.Logs, &i.Reviews, &i.Citations)
+	return i, err
+}
+
 const createParkingLot = `-- name: CreateParkingLot :one
-INSERT INTO parkinglots(id, name, slots, occupiedslots)
+INSERT INTO parkinglots(id, name, slots, occupiedslots, campus_id)
 VALUES (
     gen_random_uuid(),
     $1,
     $2,
-    0
+    0,
+    $3
 )
-RETURNING id, name, slots, occupiedslots
+RETURNING id, name, slots, occupiedslots, campus_id
 `
 
 type CreateParkingLotParams struct {
-	Name  string
-	Slots int32
+	Name     string
+	Slots    int32
+	CampusID uuid.UUID
 }
 
 func (q *Queries) CreateParkingLot(ctx context.Context, arg CreateParkingLotParams) (Parkinglot, error) {
-	row := q.db.QueryRowContext(ctx, createParkingLot, arg.Name, arg.Slots)
+	row := q.db.QueryRowContext(ctx, createParkingLot, arg.Name, arg.Slots, arg.CampusID)
 	var i Parkinglot
 	err := row.Scan(
 		&i.ID,
 		&i.Name,
 		&i.Slots,
 		&i.Occupiedslots,
+		&i.CampusID,
 	)
 	return i, err
 }
@@ -49,8 +72,32 @@ func (q *Queries) DeleteParkingLot(ctx context.Context, id uuid.UUID) (sql.Resul
 	return q.db.ExecContext(ctx, deleteParkingLot, id)
 }
 
+const getParkingLotFromCampusAndName = `-- name: GetParkingLotFromCampusAndName :one
+SELECT id, name, slots, occupiedslots, campus_id
+FROM parkinglots
+WHERE campus_id = $1 AND name = $2
+`
+
+type GetParkingLotFromCampusAndNameParams struct {
+	CampusID uuid.UUID
+	Name     string
+}
+
+func (q *Queries) GetParkingLotFromCampusAndName(ctx context.Context, arg GetParkingLotFromCampusAndNameParams) (Parkinglot, error) {
+	row := q.db.QueryRowContext(ctx, getParkingLotFromCampusAndName, arg.CampusID, arg.Name)
+	var i Parkinglot
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Slots,
+		&i.Occupiedslots,
+		&i.CampusID,
+	)
+	return i, err
+}
+
 const getParkingLotFromID = `-- name: GetParkingLotFromID :one
-SELECT id, name, slots, occupiedslots
+SELECT id, name, slots, occupiedslots, campus_id
 FROM parkinglots
 WHERE id = $1
 `
@@ -63,17 +110,61 @@ func (q *Queries) GetParkingLotFromID(ctx context.Context, id uuid.UUID) (Parkin
 		&i.Name,
 		&i.Slots,
 		&i.Occupiedslots,
+		&i.CampusID,
 	)
 	return i, err
 }
 
 const getParkingLots = `-- name: GetParkingLots :many
-SELECT id, name, slots, occupiedslots
+SELECT id, name, slots, occupiedslots, campus_id
 FROM parkinglots
+WHERE $1::uuid IS NULL OR campus_id = $1
+`
+
+func (q *Queries) GetParkingLots(ctx context.Context, campusID uuid.NullUUID) ([]Parkinglot, error) {
+	rows, err := q.db.QueryContext(ctx, getParkingLots, campusID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Parkinglot
+	for rows.Next() {
+		var i Parkinglot
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Slots,
+			&i.Occupiedslots,
+			&i.CampusID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const recountOccupiedSlots = `-- name: RecountOccupiedSlots :many
+UPDATE parkinglots
+SET occupiedslots = counts.parked
+FROM (
+    SELECT parkinglots.id, COUNT(users.id) AS parked
+    FROM parkinglots
+    LEFT JOIN users ON users.parking_lot_id = parkinglots.id
+    GROUP BY parkinglots.id
+) AS counts
+WHERE parkinglots.id = counts.id AND parkinglots.occupiedslots <> counts.parked
+RETURNING parkinglots.id, parkinglots.name, parkinglots.slots, parkinglots.occupiedslots, parkinglots.campus_id
 `
 
-func (q *Queries) GetParkingLots(ctx context.Context) ([]Parkinglot, error) {
-	rows, err := q.db.QueryContext(ctx, getParkingLots)
+func (q *Queries) RecountOccupiedSlots(ctx context.Context) ([]Parkinglot, error) {
+	rows, err := q.db.QueryContext(ctx, recountOccupiedSlots)
 	if err != nil {
 		return nil, err
 	}
@@ -86,6 +177,7 @@ func (q *Queries) GetParkingLots(ctx context.Context) ([]Parkinglot, error) {
 			&i.Name,
 			&i.Slots,
 			&i.Occupiedslots,
+			&i.CampusID,
 		); err != nil {
 			return nil, err
 		}
@@ -14,45 +14,60 @@ import (
 )
 
 const createRefreshToken = `-- name: CreateRefreshToken :one
-INSERT INTO refresh_tokens(token, user_id, expires_at, revoked_at)
+INSERT INTO refresh_tokens(token, user_id, expires_at, revoked_at, family_id, rotated_at)
 VALUES(
     $1,
     $2,
-    $3,
+    LEAST($3, (SELECT expires_at FROM sessions WHERE id = $4)),
+    NULL,
+    $4,
     NULL
-) RETURNING token, user_id, expires_at, revoked_at
+) RETURNING token, user_id, expires_at, revoked_at, family_id, rotated_at
 `
 
 type CreateRefreshTokenParams struct {
 	Token     string
 	UserID    uuid.UUID
 	ExpiresAt time.Time
+	FamilyID  uuid.UUID
 }
 
 func (q *Queries) CreateRefreshToken(ctx context.Context, arg CreateRefreshTokenParams) (RefreshToken, error) {
-	row := q.db.QueryRowContext(ctx, createRefreshToken, arg.Token, arg.UserID, arg.ExpiresAt)
+	row := q.db.QueryRowContext(ctx, createRefreshToken,
+		arg.Token,
+		arg.UserID,
+		arg.ExpiresAt,
+		arg.FamilyID,
+	)
 	var i RefreshToken
 	err := row.Scan(
 		&i.Token,
 		&i.UserID,
 		&i.ExpiresAt,
 		&i.RevokedAt,
+		&i.FamilyID,
+		&i.RotatedAt,
 	)
 	return i, err
 }
 
 const getRefreshToken = `-- name: GetRefreshToken :one
-SELECT refresh_tokens.token, refresh_tokens.user_id, refresh_tokens.expires_at, refresh_tokens.revoked_at, users.role FROM refresh_tokens
+SELECT refresh_tokens.token, refresh_tokens.user_id, refresh_tokens.expires_at, refresh_tokens.revoked_at, refresh_tokens.family_id, refresh_tokens.rotated_at, users.role, users.disabled_at, users.totp_enabled_at, users.token_version FROM refresh_tokens
 INNER JOIN users ON refresh_tokens.user_id = users.id 
 WHERE refresh_tokens.token = $1
 `
 
 type GetRefreshTokenRow struct {
-	Token     string
-	UserID    uuid.UUID
-	ExpiresAt time.Time
-	RevokedAt sql.NullTime
-	Role      string
+	Token         string
+	UserID        uuid.UUID
+	ExpiresAt     time.Time
+	RevokedAt     sql.NullTime
+	FamilyID      uuid.UUID
+	RotatedAt     sql.NullTime
+	Role          string
+	DisabledAt    sql.NullTime
+	TotpEnabledAt sql.NullTime
+	TokenVersion  int32
 }
 
 func (q *Queries) GetRefreshToken(ctx context.Context, token string) (GetRefreshTokenRow, error) {
@@ -63,11 +78,56 @@ func (q *Queries) GetRefreshToken(ctx context.Context, token string) (GetRefresh
 		&i.UserID,
 		&i.ExpiresAt,
 		&i.RevokedAt,
+		&i.FamilyID,
+		&i.RotatedAt,
 		&i.Role,
+		&i.DisabledAt,
+		&i.TotpEnabledAt,
+		&i.TokenVersion,
 	)
 	return i, err
 }
 
+const purgeExpiredTokens = `-- name: PurgeExpiredTokens :execrows
+DELETE FROM refresh_tokens
+WHERE expires_at < NOW()
+`
+
+func (q *Queries) PurgeExpiredTokens(ctx context.Context) (int64, error) {
+	result, err := q.db.ExecContext(ctx, purgeExpiredTokens)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const revokeAllUserTokens = `-- name: RevokeAllUserTokens :exec
+UPDATE refresh_tokens
+SET revoked_at = NOW()
+WHERE user_id = $1 AND revoked_at IS NULL
+`
+
+func (q *Queries) RevokeAllUserTokens(ctx context.Context, userID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, revokeAllUserTokens, userID)
+	return err
+}
+
+const revokeOtherUserTokens = `-- name: RevokeOtherUserTokens :exec
+UPDATE refresh_tokens
+SET revoked_at = NOW()
+WHERE user_id = $1 AND family_id <> $2 AND revoked_at IS NULL
+`
+
+type RevokeOtherUserTokensParams struct {
+	UserID   uuid.UUID
+	FamilyID uuid.UUID
+}
+
+func (q *Queries) RevokeOtherUserTokens(ctx context.Context, arg RevokeOtherUserTokensParams) error {
+	_, err := q.db.ExecContext(ctx, revokeOtherUserTokens, arg.UserID, arg.FamilyID)
+	return err
+}
+
 const revokeToken = `-- name: RevokeToken :exec
 UPDATE refresh_tokens
 SET revoked_at = NOW()
@@ -78,3 +138,28 @@ func (q *Queries) RevokeToken(ctx context.Context, token string) error {
 	_, err := q.db.ExecContext(ctx, revokeToken, token)
 	return err
 }
+
+const revokeTokenFamily = `-- name: RevokeTokenFamily :exec
+UPDATE refresh_tokens
+SET revoked_at = NOW()
+WHERE family_id = $1 AND revoked_at IS NULL
+`
+
+func (q *Queries) RevokeTokenFamily(ctx context.Context, familyID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, revokeTokenFamily, familyID)
+	return err
+}
+
+const rotateRefreshToken = `-- name: RotateRefreshToken :execrows
+UPDATE refresh_tokens
+SET rotated_at = NOW()
+WHERE token = $1 AND rotated_at IS NULL AND revoked_at IS NULL
+`
+
+func (q *Queries) RotateRefreshToken(ctx context.Context, token string) (int64, error) {
+	result, err := q.db.ExecContext(ctx, rotateRefreshToken, token)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
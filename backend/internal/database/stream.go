@@ -0,0 +1,130 @@
+package database
+
+import (
+	"context"
+	"time"
+)
+
+// StreamLogsForExport runs GetLogsForExport but hands every row to fn as it is
+// read from postgres instead of collecting them, so exports of the whole
+// parking_logs table do not have to fit in memory.
+func (q *Queries) StreamLogsForExport(ctx context.Context, arg GetLogsForExportParams, fn func(GetLogsForExportRow) error) error {
+	rows, err := q.db.QueryContext(ctx, getLogsForExport,
+		arg.FromTime,
+		arg.ToTime,
+		arg.LotID,
+		arg.EventType,
+		arg.CampusID,
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var i GetLogsForExportRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.ParkingLotID,
+			&i.EventType,
+			&i.Time,
+			&i.LotName,
+		); err != nil {
+			return err
+		}
+
+		if err := fn(i); err != nil {
+			return err
+		}
+	}
+
+	if err := rows.Close(); err != nil {
+		return err
+	}
+
+	return rows.Err()
+}
+
+// StreamAuditEntriesForExport is StreamLogsForExport for
+// GetAuditEntriesForExport.
+func (q *Queries) StreamAuditEntriesForExport(ctx context.Context, arg GetAuditEntriesForExportParams, fn func(AuditLog) error) error {
+	rows, err := q.db.QueryContext(ctx, getAuditEntriesForExport,
+		arg.ActorID,
+		arg.Action,
+		arg.TargetType,
+		arg.TargetID,
+		arg.CampusID,
+		arg.FromTime,
+		arg.ToTime,
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var i AuditLog
+		if err := rows.Scan(
+			&i.ID,
+			&i.ActorID,
+			&i.ActorRole,
+			&i.ApiKeyID,
+			&i.Action,
+			&i.TargetType,
+			&i.TargetID,
+			&i.CampusID,
+			&i.Before,
+			&i.After,
+			&i.Ip,
+			&i.UserAgent,
+			&i.Method,
+			&i.Path,
+			&i.CreatedAt,
+		); err != nil {
+			return err
+		}
+
+		if err := fn(i); err != nil {
+			return err
+		}
+	}
+
+	if err := rows.Close(); err != nil {
+		return err
+	}
+
+	return rows.Err()
+}
+
+// StreamDetachedParkingLogs is StreamLogsForExport for GetDetachedParkingLogs.
+func (q *Queries) StreamDetachedParkingLogs(ctx context.Context, month time.Time, fn func(ParkingLog) error) error {
+	rows, err := q.db.QueryContext(ctx, getDetachedParkingLogs, month)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var i ParkingLog
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.ParkingLotID,
+			&i.EventType,
+			&i.Time,
+		); err != nil {
+			return err
+		}
+
+		if err := fn(i); err != nil {
+			return err
+		}
+	}
+
+	if err := rows.Close(); err != nil {
+		return err
+	}
+
+	return rows.Err()
+}
@@ -12,6 +12,32 @@ import (
 	"github.com/google/uuid"
 )
 
+const bumpTokenVersion = `-- name: BumpTokenVersion :exec
+UPDATE users
+SET token_version = token_version + 1
+WHERE id = $1
+`
+
+func (q *Queries) BumpTokenVersion(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, bumpTokenVersion, id)
+	return err
+}
+
+const cancelUserDeletion = `-- name: CancelUserDeletion :execrows
+UPDATE users
+SET deletion_scheduled_at = NULL,
+updated_at = NOW()
+WHERE id = $1 AND deletion_scheduled_at IS NOT NULL
+`
+
+func (q *Queries) CancelUserDeletion(ctx context.Context, id uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, cancelUserDeletion, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const createUser = `-- name: CreateUser :one
 INSERT INTO users(id, name, email, hashed_password, role, parking_lot_id, created_at, updated_at)
 VALUES (
@@ -66,12 +92,35 @@ func (q *Queries) DeleteUser(ctx context.Context, id uuid.UUID) (sql.Result, err
 	return q.db.ExecContext(ctx, deleteUser, id)
 }
 
+const disableUser = `-- name: DisableUser :execresult
+UPDATE users
+SET disabled_at = NOW(),
+updated_at = NOW()
+WHERE id = $1
+`
+
+func (q *Queries) DisableUser(ctx context.Context, id uuid.UUID) (sql.Result, error) {
+	return q.db.ExecContext(ctx, disableUser, id)
+}
+
+const enableUser = `-- name: EnableUser :execresult
+UPDATE users
+SET disabled_at = NULL,
+updated_at = NOW()
+WHERE id = $1
+`
+
+func (q *Queries) EnableUser(ctx context.Context, id uuid.UUID) (sql.Result, error) {
+	return q.db.ExecContext(ctx, enableUser, id)
+}
+
 const getAllUsers = `-- name: GetAllUsers :many
-SELECT id, name, email, hashed_password, role, parking_lot_id, created_at, updated_at FROM users
+SELECT id, name, email, hashed_password, role, parking_lot_id, created_at, updated_at, license_plate, campus_id, admin_campus_id, disabled_at, email_verified_at, totp_secret, totp_enabled_at, totp_last_step, token_version, deletion_scheduled_at FROM users
+WHERE $1::uuid IS NULL OR campus_id = $1
 `
 
-func (q *Queries) GetAllUsers(ctx context.Context) ([]User, error) {
-	rows, err := q.db.QueryContext(ctx, getAllUsers)
+func (q *Queries) GetAllUsers(ctx context.Context, campusID uuid.NullUUID) ([]User, error) {
+	rows, err := q.db.QueryContext(ctx, getAllUsers, campusID)
 	if err != nil {
 		return nil, err
 	}
@@ -88,6 +137,16 @@ func (q *Queries) GetAllUsers(ctx context.Context) ([]User, error) {
 			&i.ParkingLotID,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.LicensePlate,
+			&i.CampusID,
+			&i.AdminCampusID,
+			&i.DisabledAt,
+			&i.EmailVerifiedAt,
+			&i.TotpSecret,
+			&i.TotpEnabledAt,
+			&i.TotpLastStep,
+			&i.TokenVersion,
+			&i.DeletionScheduledAt,
 		); err != nil {
 			return nil, err
 		}
@@ -102,8 +161,25 @@ func (q *Queries) GetAllUsers(ctx context.Context) ([]User, error) {
 	return items, nil
 }
 
+const getTokenVersion = `-- name: GetTokenVersion :one
+SELECT token_version, disabled_at FROM users
+WHERE id = $1
+`
+
+type GetTokenVersionRow struct {
+	TokenVersion int32
+	DisabledAt   sql.NullTime
+}
+
+func (q *Queries) GetTokenVersion(ctx context.Context, id uuid.UUID) (GetTokenVersionRow, error) {
+	row := q.db.QueryRowContext(ctx, getTokenVersion, id)
+	var i GetTokenVersionRow
+	err := row.Scan(&i.TokenVersion, &i.DisabledAt)
+	return i, err
+}
+
 const getUserFromEmail = `-- name: GetUserFromEmail :one
-SELECT id, name, email, hashed_password, role, parking_lot_id, created_at, updated_at FROM users
+SELECT id, name, email, hashed_password, role, parking_lot_id, created_at, updated_at, license_plate, campus_id, admin_campus_id, disabled_at, email_verified_at, totp_secret, totp_enabled_at, totp_last_step, token_version, deletion_scheduled_at FROM users
 WHERE email = $1
 `
 
@@ -119,12 +195,22 @@ func (q *Queries) GetUserFromEmail(ctx context.Context, email string) (User, err
 		&i.ParkingLotID,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.LicensePlate,
+		&i.CampusID,
+		&i.AdminCampusID,
+		&i.DisabledAt,
+		&i.EmailVerifiedAt,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastStep,
+		&i.TokenVersion,
+		&i.DeletionScheduledAt,
 	)
 	return i, err
 }
 
 const getUserFromID = `-- name: GetUserFromID :one
-SELECT id, name, email, hashed_password, role, parking_lot_id, created_at, updated_at FROM users
+SELECT id, name, email, hashed_password, role, parking_lot_id, created_at, updated_at, license_plate, campus_id, admin_campus_id, disabled_at, email_verified_at, totp_secret, totp_enabled_at, totp_last_step, token_version, deletion_scheduled_at FROM users
 WHERE id  = $1
 `
 
@@ -140,23 +226,250 @@ func (q *Queries) GetUserFromID(ctx context.Context, id uuid.UUID) (User, error)
 		&i.ParkingLotID,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.LicensePlate,
+		&i.CampusID,
+		&i.AdminCampusID,
+		&i.DisabledAt,
+		&i.EmailVerifiedAt,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastStep,
+		&i.TokenVersion,
+		&i.DeletionScheduledAt,
 	)
 	return i, err
 }
 
+const getUserFromIDForUpdate = `-- name: GetUserFromIDForUpdate :one
+SELECT id, name, email, hashed_password, role, parking_lot_id, created_at, updated_at, license_plate, campus_id, admin_campus_id, disabled_at, email_verified_at, totp_secret, totp_enabled_at, totp_last_step, token_version, deletion_scheduled_at FROM users
+WHERE id = $1
+FOR UPDATE
+`
+
+func (q *Queries) GetUserFromIDForUpdate(ctx context.Context, id uuid.UUID) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserFromIDForUpdate, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Email,
+		&i.HashedPassword,
+		&i.Role,
+		&i.ParkingLotID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.LicensePlate,
+		&i.CampusID,
+		&i.AdminCampusID,
+		&i.DisabledAt,
+		&i.EmailVerifiedAt,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastStep,
+		&i.TokenVersion,
+		&i.DeletionScheduledAt,
+	)
+	return i, err
+}
+
+const getUserFromLicensePlate = `-- name: GetUserFromLicensePlate :one
+SELECT id, name, email, hashed_password, role, parking_lot_id, created_at, updated_at, license_plate, campus_id, admin_campus_id, disabled_at, email_verified_at, totp_secret, totp_enabled_at, totp_last_step, token_version, deletion_scheduled_at FROM users
+WHERE license_plate = $1
+`
+
+func (q *Queries) GetUserFromLicensePlate(ctx context.Context, licensePlate sql.NullString) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserFromLicensePlate, licensePlate)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Email,
+		&i.HashedPassword,
+		&i.Role,
+		&i.ParkingLotID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.LicensePlate,
+		&i.CampusID,
+		&i.AdminCampusID,
+		&i.DisabledAt,
+		&i.EmailVerifiedAt,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastStep,
+		&i.TokenVersion,
+		&i.DeletionScheduledAt,
+	)
+	return i, err
+}
+
+const getUsersDueForDeletion = `-- name: GetUsersDueForDeletion :many
+SELECT id FROM users
+WHERE deletion_scheduled_at <= NOW()
+ORDER BY deletion_scheduled_at
+`
+
+func (q *Queries) GetUsersDueForDeletion(ctx context.Context) ([]uuid.UUID, error) {
+	rows, err := q.db.QueryContext(ctx, getUsersDueForDeletion)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const lockUserDueForDeletion = `-- name: LockUserDueForDeletion :one
+SELECT id, name, email, hashed_password, role, parking_lot_id, created_at, updated_at, license_plate, campus_id, admin_campus_id, disabled_at, email_verified_at, totp_secret, totp_enabled_at, totp_last_step, token_version, deletion_scheduled_at FROM users
+WHERE id = $1 AND deletion_scheduled_at <= NOW()
+FOR UPDATE
+`
+
+func (q *Queries) LockUserDueForDeletion(ctx context.Context, id uuid.UUID) (User, error) {
+	row := q.db.QueryRowContext(ctx, lockUserDueForDeletion, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Email,
+		&i.HashedPassword,
+		&i.Role,
+		&i.ParkingLotID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.LicensePlate,
+		&i.CampusID,
+		&i.AdminCampusID,
+		&i.DisabledAt,
+		&i.EmailVerifiedAt,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastStep,
+		&i.TokenVersion,
+		&i.DeletionScheduledAt,
+	)
+	return i, err
+}
+
+const markEmailVerified = `-- name: MarkEmailVerified :exec
+UPDATE users
+SET email_verified_at = NOW(),
+updated_at = NOW()
+WHERE id = $1 AND email_verified_at IS NULL
+`
+
+func (q *Queries) MarkEmailVerified(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, markEmailVerified, id)
+	return err
+}
+
+const markEmailVerifiedFromToken = `-- name: MarkEmailVerifiedFromToken :one
+UPDATE users
+SET email_verified_at = COALESCE(users.email_verified_at, NOW()),
+updated_at = NOW()
+FROM email_verification_tokens
+WHERE email_verification_tokens.token_hash = $1
+AND email_verification_tokens.expires_at > NOW()
+AND users.id = email_verification_tokens.user_id
+AND users.email = email_verification_tokens.email
+RETURNING users.id
+`
+
+func (q *Queries) MarkEmailVerifiedFromToken(ctx context.Context, tokenHash string) (uuid.UUID, error) {
+	row := q.db.QueryRowContext(ctx, markEmailVerifiedFromToken, tokenHash)
+	var id uuid.UUID
+	err := row.Scan(&id)
+	return id, err
+}
+
+const scheduleUserDeletion = `-- name: ScheduleUserDeletion :one
+UPDATE users
+SET deletion_scheduled_at = NOW() + make_interval(days => $1::int),
+updated_at = NOW()
+WHERE id = $2 AND deletion_scheduled_at IS NULL
+RETURNING deletion_scheduled_at
+`
+
+type ScheduleUserDeletionParams struct {
+	GraceDays int32
+	ID        uuid.UUID
+}
+
+func (q *Queries) ScheduleUserDeletion(ctx context.Context, arg ScheduleUserDeletionParams) (sql.NullTime, error) {
+	row := q.db.QueryRowContext(ctx, scheduleUserDeletion, arg.GraceDays, arg.ID)
+	var deletion_scheduled_at sql.NullTime
+	err := row.Scan(&deletion_scheduled_at)
+	return deletion_scheduled_at, err
+}
+
+const setUserAdminCampus = `-- name: SetUserAdminCampus :exec
+UPDATE users
+SET role = 'admin',
+admin_campus_id = $1,
+updated_at = NOW()
+WHERE id = $2
+`
+
+type SetUserAdminCampusParams struct {
+	AdminCampusID uuid.NullUUID
+	ID            uuid.UUID
+}
+
+func (q *Queries) SetUserAdminCampus(ctx context.Context, arg SetUserAdminCampusParams) error {
+	_, err := q.db.ExecContext(ctx, setUserAdminCampus, arg.AdminCampusID, arg.ID)
+	return err
+}
+
+const setUserRoleAndCampus = `-- name: SetUserRoleAndCampus :exec
+UPDATE users
+SET role = $1,
+admin_campus_id = $2,
+updated_at = NOW()
+WHERE id = $3
+`
+
+type SetUserRoleAndCampusParams struct {
+	Role          string
+	AdminCampusID uuid.NullUUID
+	ID            uuid.UUID
+}
+
+func (q *Queries) SetUserRoleAndCampus(ctx context.Context, arg SetUserRoleAndCampusParams) error {
+	_, err := q.db.ExecContext(ctx, setUserRoleAndCampus, arg.Role, arg.AdminCampusID, arg.ID)
+	return err
+}
+
 const updateUser = `-- name: UpdateUser :exec
 UPDATE users
 SET name = $1,
 email = $2,
+email_verified_at = CASE WHEN email = $2 THEN email_verified_at ELSE NULL END,
 hashed_password = $3,
+license_plate = $4,
+campus_id = $5,
 updated_at = NOW()
-WHERE id = $4
+WHERE id = $6
 `
 
 type UpdateUserParams struct {
 	Name           string
 	Email          string
 	HashedPassword string
+	LicensePlate   sql.NullString
+	CampusID       uuid.NullUUID
 	ID             uuid.UUID
 }
 
@@ -165,6 +478,8 @@ func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) error {
 		arg.Name,
 		arg.Email,
 		arg.HashedPassword,
+		arg.LicensePlate,
+		arg.CampusID,
 		arg.ID,
 	)
 	return err
@@ -185,3 +500,37 @@ func (q *Queries) UpdateUserParkingLot(ctx context.Context, arg UpdateUserParkin
 	_, err := q.db.ExecContext(ctx, updateUserParkingLot, arg.ParkingLotID, arg.ID)
 	return err
 }
+
+const updateUserPassword = `-- name: UpdateUserPassword :exec
+UPDATE users
+SET hashed_password = $1,
+updated_at = NOW()
+WHERE id = $2
+`
+
+type UpdateUserPasswordParams struct {
+	HashedPassword string
+	ID             uuid.UUID
+}
+
+func (q *Queries) UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) error {
+	_, err := q.db.ExecContext(ctx, updateUserPassword, arg.HashedPassword, arg.ID)
+	return err
+}
+
+const updateUserRole = `-- name: UpdateUserRole :execresult
+UPDATE users
+SET role = $1,
+admin_campus_id = NULL,
+updated_at = NOW()
+WHERE id = $2
+`
+
+type UpdateUserRoleParams struct {
+	Role string
+	ID   uuid.UUID
+}
+
+func (q *Queries) UpdateUserRole(ctx context.Context, arg UpdateUserRoleParams) (sql.Result, error) {
+	return q.db.ExecContext(ctx, updateUserRole, arg.Role, arg.ID)
+}
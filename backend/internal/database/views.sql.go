@@ -12,7 +12,7 @@ import (
 )
 
 const getAverageLotRatingFromID = `-- name: GetAverageLotRatingFromID :one
-SELECT lotid, lotname, averagerating, totalreviews
+SELECT lotid, lotname, averagerating, totalreviews, campus_id
 FROM average_lot_ratings
 WHERE lotid = $1
 `
@@ -25,6 +25,7 @@ func (q *Queries) GetAverageLotRatingFromID(ctx context.Context, lotid uuid.UUID
 		&i.Lotname,
 		&i.Averagerating,
 		&i.Totalreviews,
+		&i.CampusID,
 	)
 	return i, err
 }
@@ -43,12 +44,13 @@ func (q *Queries) GetAverageParkingTimeFromUserID(ctx context.Context, userID uu
 }
 
 const getCountOfLogsPerLot = `-- name: GetCountOfLogsPerLot :many
-SELECT lotid, lotname, totalentries
+SELECT lotid, lotname, totalentries, campus_id
 FROM count_of_logs_per_lot
+WHERE $1::uuid IS NULL OR campus_id = $1
 `
 
-func (q *Queries) GetCountOfLogsPerLot(ctx context.Context) ([]CountOfLogsPerLot, error) {
-	rows, err := q.db.QueryContext(ctx, getCountOfLogsPerLot)
+func (q *Queries) GetCountOfLogsPerLot(ctx context.Context, campusID uuid.NullUUID) ([]CountOfLogsPerLot, error) {
+	rows, err := q.db.QueryContext(ctx, getCountOfLogsPerLot, campusID)
 	if err != nil {
 		return nil, err
 	}
@@ -56,7 +58,12 @@ func (q *Queries) GetCountOfLogsPerLot(ctx context.Context) ([]CountOfLogsPerLot
 	var items []CountOfLogsPerLot
 	for rows.Next() {
 		var i CountOfLogsPerLot
-		if err := rows.Scan(&i.Lotid, &i.Lotname, &i.Totalentries); err != nil {
+		if err := rows.Scan(
+			&i.Lotid,
+			&i.Lotname,
+			&i.Totalentries,
+			&i.CampusID,
+		); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -71,12 +78,13 @@ func (q *Queries) GetCountOfLogsPerLot(ctx context.Context) ([]CountOfLogsPerLot
 }
 
 const getCountOfLogsPerUser = `-- name: GetCountOfLogsPerUser :many
-SELECT userid, username, totalentries
+SELECT userid, username, totalentries, campus_id
 FROM count_of_logs_per_user
+WHERE $1::uuid IS NULL OR campus_id = $1
 `
 
-func (q *Queries) GetCountOfLogsPerUser(ctx context.Context) ([]CountOfLogsPerUser, error) {
-	rows, err := q.db.QueryContext(ctx, getCountOfLogsPerUser)
+func (q *Queries) GetCountOfLogsPerUser(ctx context.Context, campusID uuid.NullUUID) ([]CountOfLogsPerUser, error) {
+	rows, err := q.db.QueryContext(ctx, getCountOfLogsPerUser, campusID)
 	if err != nil {
 		return nil, err
 	}
@@ -84,7 +92,12 @@ func (q *Queries) GetCountOfLogsPerUser(ctx context.Context) ([]CountOfLogsPerUs
 	var items []CountOfLogsPerUser
 	for rows.Next() {
 		var i CountOfLogsPerUser
-		if err := rows.Scan(&i.Userid, &i.Username, &i.Totalentries); err != nil {
+		if err := rows.Scan(
+			&i.Userid,
+			&i.Username,
+			&i.Totalentries,
+			&i.CampusID,
+		); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -99,12 +112,13 @@ func (q *Queries) GetCountOfLogsPerUser(ctx context.Context) ([]CountOfLogsPerUs
 }
 
 const getCountOfReviewsPerLot = `-- name: GetCountOfReviewsPerLot :many
-SELECT lotid, lotname, totalreviews
+SELECT lotid, lotname, totalreviews, campus_id
 FROM count_of_review_per_lot
+WHERE $1::uuid IS NULL OR campus_id = $1
 `
 
-func (q *Queries) GetCountOfReviewsPerLot(ctx context.Context) ([]CountOfReviewPerLot, error) {
-	rows, err := q.db.QueryContext(ctx, getCountOfReviewsPerLot)
+func (q *Queries) GetCountOfReviewsPerLot(ctx context.Context, campusID uuid.NullUUID) ([]CountOfReviewPerLot, error) {
+	rows, err := q.db.QueryContext(ctx, getCountOfReviewsPerLot, campusID)
 	if err != nil {
 		return nil, err
 	}
@@ -112,7 +126,12 @@ func (q *Queries) GetCountOfReviewsPerLot(ctx context.Context) ([]CountOfReviewP
 	var items []CountOfReviewPerLot
 	for rows.Next() {
 		var i CountOfReviewPerLot
-		if err := rows.Scan(&i.Lotid, &i.Lotname, &i.Totalreviews); err != nil {
+		if err := rows.Scan(
+			&i.Lotid,
+			&i.Lotname,
+			&i.Totalreviews,
+			&i.CampusID,
+		); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -127,12 +146,13 @@ func (q *Queries) GetCountOfReviewsPerLot(ctx context.Context) ([]CountOfReviewP
 }
 
 const getCountOfReviewsPerUser = `-- name: GetCountOfReviewsPerUser :many
-SELECT userid, username, totalreviews
+SELECT userid, username, totalreviews, campus_id
 FROM count_of_reviews_per_user
+WHERE $1::uuid IS NULL OR campus_id = $1
 `
 
-func (q *Queries) GetCountOfReviewsPerUser(ctx context.Context) ([]CountOfReviewsPerUser, error) {
-	rows, err := q.db.QueryContext(ctx, getCountOfReviewsPerUser)
+func (q *Queries) GetCountOfReviewsPerUser(ctx context.Context, campusID uuid.NullUUID) ([]CountOfReviewsPerUser, error) {
+	rows, err := q.db.QueryContext(ctx, getCountOfReviewsPerUser, campusID)
 	if err != nil {
 		return nil, err
 	}
@@ -140,7 +160,47 @@ func (q *Queries) GetCountOfReviewsPerUser(ctx context.Context) ([]CountOfReview
 	var items []CountOfReviewsPerUser
 	for rows.Next() {
 		var i CountOfReviewsPerUser
-		if err := rows.Scan(&i.Userid, &i.Username, &i.Totalreviews); err != nil {
+		if err := rows.Scan(
+			&i.Userid,
+			&i.Username,
+			&i.Totalreviews,
+			&i.CampusID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getDailyEntriesFromLotID = `-- name: GetDailyEntriesFromLotID :many
+SELECT lotid, day, totalentries, campus_id
+FROM daily_lot_entries
+WHERE lotid = $1
+ORDER BY day ASC
+`
+
+func (q *Queries) GetDailyEntriesFromLotID(ctx context.Context, lotid uuid.UUID) ([]DailyLotEntry, error) {
+	rows, err := q.db.QueryContext(ctx, getDailyEntriesFromLotID, lotid)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []DailyLotEntry
+	for rows.Next() {
+		var i DailyLotEntry
+		if err := rows.Scan(
+			&i.Lotid,
+			&i.Day,
+			&i.Totalentries,
+			&i.CampusID,
+		); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -155,12 +215,13 @@ func (q *Queries) GetCountOfReviewsPerUser(ctx context.Context) ([]CountOfReview
 }
 
 const getFullLots = `-- name: GetFullLots :many
-SELECT id, name, slots, occupiedslots
+SELECT id, name, slots, occupiedslots, campus_id
 FROM full_parking_lots
+WHERE $1::uuid IS NULL OR campus_id = $1
 `
 
-func (q *Queries) GetFullLots(ctx context.Context) ([]FullParkingLot, error) {
-	rows, err := q.db.QueryContext(ctx, getFullLots)
+func (q *Queries) GetFullLots(ctx context.Context, campusID uuid.NullUUID) ([]FullParkingLot, error) {
+	rows, err := q.db.QueryContext(ctx, getFullLots, campusID)
 	if err != nil {
 		return nil, err
 	}
@@ -173,6 +234,7 @@ func (q *Queries) GetFullLots(ctx context.Context) ([]FullParkingLot, error) {
 			&i.Name,
 			&i.Slots,
 			&i.Occupiedslots,
+			&i.CampusID,
 		); err != nil {
 			return nil, err
 		}
@@ -266,8 +328,48 @@ func (q *Queries) GetReviewsFromLotID(ctx context.Context, lotid uuid.UUID) ([]U
 	return items, nil
 }
 
+const getReviewsFromUserID = `-- name: GetReviewsFromUserID :many
+SELECT userid, username, lotid, lotname, title, description, score, created_at, updated_at
+FROM user_reviews_with_lot
+WHERE userid = $1
+ORDER BY created_at
+`
+
+func (q *Queries) GetReviewsFromUserID(ctx context.Context, userid uuid.UUID) ([]UserReviewsWithLot, error) {
+	rows, err := q.db.QueryContext(ctx, getReviewsFromUserID, userid)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []UserReviewsWithLot
+	for rows.Next() {
+		var i UserReviewsWithLot
+		if err := rows.Scan(
+			&i.Userid,
+			&i.Username,
+			&i.Lotid,
+			&i.Lotname,
+			&i.Title,
+			&i.Description,
+			&i.Score,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getTopRatedLots = `-- name: GetTopRatedLots :many
-SELECT id, name, round
+SELECT id, name, round, campus_id
 FROM top_rated_lot
 `
 
@@ -280,7 +382,51 @@ func (q *Queries) GetTopRatedLots(ctx context.Context) ([]TopRatedLot, error) {
 	var items []TopRatedLot
 	for rows.Next() {
 		var i TopRatedLot
-		if err := rows.Scan(&i.ID, &i.Name, &i.Round); err != nil {
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Round,
+			&i.CampusID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getTopRatedLotsFromCampusID = `-- name: GetTopRatedLotsFromCampusID :many
+SELECT lotid, lotname, averagerating
+FROM average_lot_ratings
+WHERE campus_id = $1 AND averagerating = (
+    SELECT MAX(averagerating)
+    FROM average_lot_ratings
+    WHERE campus_id = $1
+)
+`
+
+type GetTopRatedLotsFromCampusIDRow struct {
+	Lotid         uuid.UUID
+	Lotname       string
+	Averagerating string
+}
+
+func (q *Queries) GetTopRatedLotsFromCampusID(ctx context.Context, campusID uuid.UUID) ([]GetTopRatedLotsFromCampusIDRow, error) {
+	rows, err := q.db.QueryContext(ctx, getTopRatedLotsFromCampusID, campusID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetTopRatedLotsFromCampusIDRow
+	for rows.Next() {
+		var i GetTopRatedLotsFromCampusIDRow
+		if err := rows.Scan(&i.Lotid, &i.Lotname, &i.Averagerating); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
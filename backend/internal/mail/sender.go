@@ -0,0 +1,100 @@
+package mail
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Sender delivers a plain text email.
+type Sender interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// checkHeaders rejects line breaks that would let a value inject headers.
+func checkHeaders(msg Message) error {
+	if strings.ContainsAny(msg.To, "\r\n") || strings.ContainsAny(msg.Subject, "\r\n") {
+		return fmt.Errorf("mail headers cannot contain line breaks")
+	}
+	return nil
+}
+
+// format renders msg as an RFC 5322 message.
+func format(from string, msg Message) []byte {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", msg.Subject)
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+
+	return []byte(b.String())
+}
+
+// SMTPSender sends through an SMTP server, Username can be left empty for
+// servers without authentication such as MailHog.
+type SMTPSender struct {
+	Addr     string
+	From     string
+	Username string
+	Password string
+}
+
+func (s SMTPSender) Send(ctx context.Context, msg Message) error {
+	if err := checkHeaders(msg); err != nil {
+		return err
+	}
+
+	var auth smtp.Auth
+	if s.Username != "" {
+		host := s.Addr
+		if i := strings.LastIndex(host, ":"); i >= 0 {
+			host = host[:i]
+		}
+		auth = smtp.PlainAuth("", s.Username, s.Password, host)
+	}
+
+	return smtp.SendMail(s.Addr, auth, s.From, []string{msg.To}, format(s.From, msg))
+}
+
+// OutboxSender writes every email to a .eml file in Dir instead of sending
+// it, for development and offline testing.
+type OutboxSender struct {
+	Dir  string
+	From string
+}
+
+func (s OutboxSender) Send(ctx context.Context, msg Message) error {
+	if err := checkHeaders(msg); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(s.Dir, 0o755); err != nil {
+		return err
+	}
+
+	key := make([]byte, 4)
+	if _, err := rand.Read(key); err != nil {
+		return err
+	}
+
+	name := time.Now().UTC().Format("20060102T150405.000000000") + "-" + hex.EncodeToString(key) + ".eml"
+
+	return os.WriteFile(filepath.Join(s.Dir, name), format(s.From, msg), 0o644)
+}
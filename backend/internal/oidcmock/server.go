@@ -0,0 +1,280 @@
+// Package oidcmock is a minimal OpenID Connect provider for trying single
+// sign-on locally. Every login is approved as whoever the user types in, so it
+// must never be reachable from outside the machine.
+package oidcmock
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"html/template"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	codeTTL    = time.Minute
+	idTokenTTL = time.Hour
+	keyID      = "mock"
+)
+
+// authCode is an approved login waiting to be exchanged at the token endpoint.
+type authCode struct {
+	clientID      string
+	redirectURI   string
+	challenge     string
+	nonce         string
+	email         string
+	name          string
+	emailVerified bool
+	expiresAt     time.Time
+}
+
+// Server serves the provider at Issuer, which has to be the URL it is reached
+// at.
+type Server struct {
+	Issuer string
+
+	key   *rsa.PrivateKey
+	mu    sync.Mutex
+	codes map[string]authCode
+}
+
+// New returns a provider with a new signing key, tokens from an earlier run do
+// not verify anymore.
+func New(issuer string) (*Server, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Server{
+		Issuer: strings.TrimSuffix(issuer, "/"),
+		key:    key,
+		codes:  map[string]authCode{},
+	}, nil
+}
+
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", s.discovery)
+	mux.HandleFunc("GET /authorize", s.authorizeForm)
+	mux.HandleFunc("POST /authorize", s.authorize)
+	mux.HandleFunc("POST /token", s.token)
+	mux.HandleFunc("GET /jwks", s.jwks)
+	return mux
+}
+
+func writeJSON(res http.ResponseWriter, status int, payload any) {
+	res.Header().Set("Content-Type", "application/json")
+	res.Header().Set("Cache-Control", "no-store")
+	res.WriteHeader(status)
+	json.NewEncoder(res).Encode(payload)
+}
+
+// tokenError answers the token endpoint with an OAuth2 error code.
+func tokenError(res http.ResponseWriter, code, description string) {
+	writeJSON(res, http.StatusBadRequest, map[string]string{
+		"error":             code,
+		"error_description": description,
+	})
+}
+
+func randomString() string {
+	key := make([]byte, 16)
+	rand.Read(key)
+	return hex.EncodeToString(key)
+}
+
+func (s *Server) discovery(res http.ResponseWriter, req *http.Request) {
+	writeJSON(res, http.StatusOK, map[string]any{
+		"issuer":                                s.Issuer,
+		"authorization_endpoint":                s.Issuer + "/authorize",
+		"token_endpoint":                        s.Issuer + "/token",
+		"jwks_uri":                              s.Issuer + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+		"scopes_supported":                      []string{"openid", "email", "profile"},
+	})
+}
+
+var authorizePage = template.Must(template.New("authorize").Parse(`<!DOCTYPE html>
+<html>
+<head><title>Mock campus login</title></head>
+<body style="font-family: sans-serif; max-width: 24rem; margin: 4rem auto;">
+<h1>Mock campus login</h1>
+<p>Any email is accepted, this provider is only for local testing.</p>
+<form method="POST" action="/authorize">
+{{range $name, $value := .Hidden}}<input type="hidden" name="{{$name}}" value="{{$value}}">
+{{end}}
+<p><label>Email<br><input name="email" type="email" required></label></p>
+<p><label>Name<br><input name="name"></label></p>
+<p><label><input name="email_verified" type="checkbox" value="true" checked> Email verified</label></p>
+<p><button type="submit">Login</button></p>
+</form>
+</body>
+</html>
+`))
+
+func (s *Server) authorizeForm(res http.ResponseWriter, req *http.Request) {
+	query := req.URL.Query()
+
+	if query.Get("response_type") != "code" {
+		http.Error(res, "only response_type=code is supported", http.StatusBadRequest)
+		return
+	}
+
+	//the mock insists on PKCE so a client that forgets it fails here already
+	if query.Get("code_challenge") == "" || query.Get("code_challenge_method") != "S256" {
+		http.Error(res, "a S256 code_challenge is required", http.StatusBadRequest)
+		return
+	}
+
+	if query.Get("client_id") == "" || query.Get("redirect_uri") == "" {
+		http.Error(res, "client_id and redirect_uri are required", http.StatusBadRequest)
+		return
+	}
+
+	hidden := map[string]string{}
+	for _, name := range []string{"client_id", "redirect_uri", "state", "nonce", "code_challenge"} {
+		hidden[name] = query.Get(name)
+	}
+
+	res.Header().Set("Content-Type", "text/html; charset=utf-8")
+	authorizePage.Execute(res, struct{ Hidden map[string]string }{hidden})
+}
+
+func (s *Server) authorize(res http.ResponseWriter, req *http.Request) {
+	if err := req.ParseForm(); err != nil {
+		http.Error(res, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	redirectURI, err := url.Parse(req.PostForm.Get("redirect_uri"))
+	if err != nil || req.PostForm.Get("email") == "" {
+		http.Error(res, "invalid login", http.StatusBadRequest)
+		return
+	}
+
+	name := req.PostForm.Get("name")
+	if name == "" {
+		name = req.PostForm.Get("email")
+	}
+
+	code := randomString()
+
+	s.mu.Lock()
+	s.codes[code] = authCode{
+		clientID:      req.PostForm.Get("client_id"),
+		redirectURI:   redirectURI.String(),
+		challenge:     req.PostForm.Get("code_challenge"),
+		nonce:         req.PostForm.Get("nonce"),
+		email:         req.PostForm.Get("email"),
+		name:          name,
+		emailVerified: req.PostForm.Get("email_verified") == "true",
+		expiresAt:     time.Now().Add(codeTTL),
+	}
+	s.mu.Unlock()
+
+	query := redirectURI.Query()
+	query.Set("code", code)
+	query.Set("state", req.PostForm.Get("state"))
+	redirectURI.RawQuery = query.Encode()
+
+	http.Redirect(res, req, redirectURI.String(), http.StatusFound)
+}
+
+func (s *Server) token(res http.ResponseWriter, req *http.Request) {
+	if err := req.ParseForm(); err != nil {
+		tokenError(res, "invalid_request", err.Error())
+		return
+	}
+
+	if req.PostForm.Get("grant_type") != "authorization_code" {
+		tokenError(res, "unsupported_grant_type", "only authorization_code is supported")
+		return
+	}
+
+	//codes are single use, it is gone whether the exchange works or not
+	s.mu.Lock()
+	code, ok := s.codes[req.PostForm.Get("code")]
+	delete(s.codes, req.PostForm.Get("code"))
+	s.mu.Unlock()
+
+	if !ok || time.Now().After(code.expiresAt) {
+		tokenError(res, "invalid_grant", "unknown or expired code")
+		return
+	}
+
+	clientID, _, hasBasicAuth := req.BasicAuth()
+	if !hasBasicAuth {
+		clientID = req.PostForm.Get("client_id")
+	}
+
+	if clientID != code.clientID || req.PostForm.Get("redirect_uri") != code.redirectURI {
+		tokenError(res, "invalid_grant", "client_id or redirect_uri do not match the login")
+		return
+	}
+
+	sum := sha256.Sum256([]byte(req.PostForm.Get("code_verifier")))
+	if base64.RawURLEncoding.EncodeToString(sum[:]) != code.challenge {
+		tokenError(res, "invalid_grant", "code_verifier does not match the code_challenge")
+		return
+	}
+
+	//the same email is always the same subject, like a real campus account
+	subject := sha256.Sum256([]byte(strings.ToLower(code.email)))
+	now := time.Now()
+
+	idToken := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":            s.Issuer,
+		"sub":            hex.EncodeToString(subject[:16]),
+		"aud":            code.clientID,
+		"iat":            now.Unix(),
+		"exp":            now.Add(idTokenTTL).Unix(),
+		"nonce":          code.nonce,
+		"email":          code.email,
+		"email_verified": code.emailVerified,
+		"name":           code.name,
+	})
+	idToken.Header["kid"] = keyID
+
+	signed, err := idToken.SignedString(s.key)
+	if err != nil {
+		tokenError(res, "server_error", err.Error())
+		return
+	}
+
+	writeJSON(res, http.StatusOK, map[string]any{
+		"access_token": randomString(),
+		"token_type":   "Bearer",
+		"expires_in":   int(idTokenTTL.Seconds()),
+		"id_token":     signed,
+	})
+}
+
+func (s *Server) jwks(res http.ResponseWriter, req *http.Request) {
+	publicKey := s.key.PublicKey
+
+	writeJSON(res, http.StatusOK, map[string]any{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"use": "sig",
+			"alg": "RS256",
+			"kid": keyID,
+			"n":   base64.RawURLEncoding.EncodeToString(publicKey.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(publicKey.E)).Bytes()),
+		}},
+	})
+}
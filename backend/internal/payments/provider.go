@@ -0,0 +1,33 @@
+package payments
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// Provider charges a user's external payment method and returns the
+// provider's reference for the charge.
+type Provider interface {
+	Charge(ctx context.Context, userID uuid.UUID, amount int64) (string, error)
+}
+
+// StubProvider simulates a payment provider that approves every charge.
+type StubProvider struct{}
+
+func (StubProvider) Charge(ctx context.Context, userID uuid.UUID, amount int64) (string, error) {
+	if amount <= 0 {
+		return "", fmt.Errorf("charge amount must be positive")
+	}
+
+	key := make([]byte, 12)
+
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+
+	return "stub_" + hex.EncodeToString(key), nil
+}
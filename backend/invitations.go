@@ -0,0 +1,319 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/Shayaan-Kashif/Database-Project/internal/auth"
+	"github.com/Shayaan-Kashif/Database-Project/internal/database"
+	"github.com/Shayaan-Kashif/Database-Project/internal/mail"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultInvitationTTL = 72 * time.Hour
+	maxInvitationTTL     = 30 * 24 * time.Hour
+)
+
+var errInvalidInvitation = errors.New("invalid or expired invitation")
+
+// invitationJSON is an invitation as the admin endpoints return it, the token
+// itself is only ever shown once when it is created.
+type invitationJSON struct {
+	ID            uuid.UUID     `json:"id"`
+	Role          string        `json:"role"`
+	AdminCampusID uuid.NullUUID `json:"adminCampusID"`
+	Email         *string       `json:"email"`
+	CreatedBy     uuid.NullUUID `json:"createdBy"`
+	ExpiresAt     time.Time     `json:"expiresAt"`
+	RedeemedBy    uuid.NullUUID `json:"redeemedBy"`
+	RedeemedAt    *time.Time    `json:"redeemedAt"`
+	RevokedAt     *time.Time    `json:"revokedAt"`
+	CreatedAt     time.Time     `json:"createdAt"`
+}
+
+func toInvitationJSON(i database.AdminInvitation) invitationJSON {
+	out := invitationJSON{
+		ID:            i.ID,
+		Role:          i.Role,
+		AdminCampusID: i.AdminCampusID,
+		CreatedBy:     i.CreatedBy,
+		ExpiresAt:     i.ExpiresAt,
+		RedeemedBy:    i.RedeemedBy,
+		CreatedAt:     i.CreatedAt,
+	}
+
+	if i.Email.Valid {
+		out.Email = &i.Email.String
+	}
+	if i.RedeemedAt.Valid {
+		out.RedeemedAt = &i.RedeemedAt.Time
+	}
+	if i.RevokedAt.Valid {
+		out.RevokedAt = &i.RevokedAt.Time
+	}
+
+	return out
+}
+
+// invitationAuditEntry records action on an invitation, without the email it
+// is for.
+func invitationAuditEntry(action string, before, after *database.AdminInvitation) auditEntry {
+	entry := auditEntry{Action: action, TargetType: "invitation"}
+
+	for _, i := range []*database.AdminInvitation{before, after} {
+		if i != nil {
+			entry.TargetID = i.ID
+			entry.CampusID = i.AdminCampusID
+		}
+	}
+
+	if before != nil {
+		b := toInvitationJSON(*before)
+		b.Email = nil
+		entry.Before = b
+	}
+	if after != nil {
+		a := toInvitationJSON(*after)
+		a.Email = nil
+		entry.After = a
+	}
+
+	return entry
+}
+
+// invitationLink is the signup page with the token filled in.
+func invitationLink(token string) string {
+	return appURL() + "/signup?invitation=" + url.QueryEscape(token)
+}
+
+// createInvitation stores a new invitation and returns its token. When email is
+// set only that email can redeem it and the link is mailed to it. createdBy is
+// invalid for invitations made from the command line.
+func (cfg *apiConfig) createInvitation(ctx context.Context, role string, campusID uuid.NullUUID, email string, ttl time.Duration, createdBy uuid.NullUUID) (string, database.AdminInvitation, error) {
+	token, err := auth.MakeRefreshToken()
+	if err != nil {
+		return "", database.AdminInvitation{}, err
+	}
+
+	invitationDB, err := cfg.dbQueries.CreateAdminInvitation(ctx, database.CreateAdminInvitationParams{
+		TokenHash:     auth.HashToken(token),
+		Role:          role,
+		AdminCampusID: campusID,
+		Email:         sql.NullString{String: email, Valid: email != ""},
+		CreatedBy:     createdBy,
+		ExpiresAt:     time.Now().Add(ttl),
+	})
+
+	if err != nil {
+		return "", database.AdminInvitation{}, err
+	}
+
+	auditAfter(ctx, cfg.dbQueries, invitationAuditEntry("invitation.create", nil, &invitationDB))
+
+	if email == "" {
+		return token, invitationDB, nil
+	}
+
+	//the invitation exists either way, the link is also in the response
+	err = cfg.mail.Send(ctx, mail.Message{
+		To:      email,
+		Subject: "You are invited to ParkingGO",
+		Body: fmt.Sprintf(
+			"You have been invited to join ParkingGO as %s.\n\nCreate your account with this link before %s:\n%s\n",
+			role, invitationDB.ExpiresAt.Format(time.RFC1123), invitationLink(token),
+		),
+	})
+
+	if err != nil {
+		log.Printf("Error sending the invitation email: %s", err)
+	}
+
+	return token, invitationDB, nil
+}
+
+// redeemInvitation uses up the invitation of token for email and returns it.
+// It has to run in the transaction that creates the user, see signUp.
+func redeemInvitation(ctx context.Context, qtx *database.Queries, token, email string) (database.AdminInvitation, error) {
+	invitationDB, err := qtx.RedeemAdminInvitation(ctx, database.RedeemAdminInvitationParams{
+		TokenHash:     auth.HashToken(token),
+		RedeemerEmail: email,
+	})
+
+	if err == sql.ErrNoRows {
+		return database.AdminInvitation{}, errInvalidInvitation
+	}
+
+	return invitationDB, err
+}
+
+func (cfg *apiConfig) createAdminInvitation(res http.ResponseWriter, req *http.Request) {
+	reqStruct := struct {
+		Role           *string    `json:"role"`
+		CampusID       *uuid.UUID `json:"campusID"`
+		Email          string     `json:"email"`
+		ExpiresInHours *int       `json:"expiresInHours"`
+	}{}
+
+	if err := decodeJSON(req, &reqStruct); err != nil {
+		respondWithError(res, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if reqStruct.Role == nil {
+		respondWithError(res, http.StatusBadRequest, "invalid JSON structure")
+		return
+	}
+
+	if *reqStruct.Role != "admin" && *reqStruct.Role != "enforcement" && *reqStruct.Role != "lot_manager" {
+		respondWithError(res, http.StatusBadRequest, "role must be admin, enforcement or lot_manager")
+		return
+	}
+
+	ttl := defaultInvitationTTL
+	if reqStruct.ExpiresInHours != nil {
+		ttl = time.Duration(*reqStruct.ExpiresInHours) * time.Hour
+
+		if ttl <= 0 || ttl > maxInvitationTTL {
+			respondWithError(res, http.StatusBadRequest, "expiresInHours must be between 1 and 720")
+			return
+		}
+	}
+
+	userID := req.Context().Value(ctxUserID).(uuid.UUID)
+
+	scope, err := cfg.adminCampus(req.Context(), userID)
+	if err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	campusID := uuid.NullUUID{}
+	if reqStruct.CampusID != nil {
+		campusID = uuid.NullUUID{UUID: *reqStruct.CampusID, Valid: true}
+	}
+
+	//campus admins can only invite admins of their own campus and lot managers,
+	//who only get lots of the campus from them
+	if scope.Valid {
+		if *reqStruct.Role == "enforcement" || (campusID.Valid && campusID != scope) {
+			respondWithError(res, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+
+		if *reqStruct.Role == "admin" {
+			campusID = scope
+		}
+	}
+
+	if campusID.Valid && *reqStruct.Role != "admin" {
+		respondWithError(res, http.StatusBadRequest, "only admin invitations can have a campusID")
+		return
+	}
+
+	token, invitationDB, err := cfg.createInvitation(req.Context(), *reqStruct.Role, campusID, reqStruct.Email, ttl, uuid.NullUUID{UUID: userID, Valid: true})
+
+	if err != nil {
+		hasPgErr, message := handlePgConstraints(err)
+
+		if hasPgErr {
+			respondWithError(res, http.StatusBadRequest, message)
+			return
+		}
+
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondWithJSON(res, http.StatusCreated, struct {
+		invitationJSON
+		Token string `json:"token"`
+		Link  string `json:"link"`
+	}{
+		invitationJSON: toInvitationJSON(invitationDB),
+		Token:          token,
+		Link:           invitationLink(token),
+	})
+}
+
+func (cfg *apiConfig) getAdminInvitations(res http.ResponseWriter, req *http.Request) {
+	campusID, err := cfg.adminCampusFilter(req)
+
+	if err != nil {
+		respondWithError(res, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	invitationsDB, err := cfg.dbQueries.GetAdminInvitations(req.Context(), campusID)
+
+	if err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	response := make([]invitationJSON, 0, len(invitationsDB))
+	for _, i := range invitationsDB {
+		response = append(response, toInvitationJSON(i))
+	}
+
+	respondWithJSON(res, http.StatusOK, response)
+}
+
+func (cfg *apiConfig) revokeAdminInvitation(res http.ResponseWriter, req *http.Request) {
+	invitationID, err := uuid.Parse(req.PathValue("invitationID"))
+
+	if err != nil {
+		respondWithError(res, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	invitationDB, err := cfg.dbQueries.GetAdminInvitationFromID(req.Context(), invitationID)
+
+	if err == sql.ErrNoRows {
+		respondWithError(res, http.StatusNotFound, "no invitation exist for that invitationID")
+		return
+	} else if err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	userID := req.Context().Value(ctxUserID).(uuid.UUID)
+
+	scope, err := cfg.adminCampus(req.Context(), userID)
+	if err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if scope.Valid && invitationDB.AdminCampusID != scope {
+		respondWithError(res, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	revoked, err := cfg.dbQueries.RevokeAdminInvitation(req.Context(), invitationID)
+
+	if err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if revoked == 0 {
+		respondWithError(res, http.StatusConflict, "the invitation has already been redeemed or revoked")
+		return
+	}
+
+	after := invitationDB
+	after.RevokedAt = sql.NullTime{Time: time.Now(), Valid: true}
+
+	auditAfter(req.Context(), cfg.dbQueries, invitationAuditEntry("invitation.revoke", &invitationDB, &after))
+
+	respondWithJSON(res, http.StatusOK, struct {
+		Status string `json:"status"`
+	}{"The invitation has been revoked"})
+}
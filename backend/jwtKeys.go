@@ -0,0 +1,116 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/Shayaan-Kashif/Database-Project/internal/auth"
+)
+
+const (
+	defaultJWTIssuer   = "OntarioTechParkingGO"
+	defaultJWTAudience = "parkinggo-api"
+)
+
+// loadJWTKeys signs access tokens with the private key in JWT_SIGNING_KEY_FILE.
+// JWT_VERIFICATION_KEY_FILES (comma separated) are the keys of earlier
+// rotations that are still accepted. Without a signing key the tokens are
+// signed HS256 with JWTSecret like before, one of the two has to be set.
+func loadJWTKeys() (*auth.JWTKeys, error) {
+	issuer := os.Getenv("JWT_ISSUER")
+	if issuer == "" {
+		issuer = defaultJWTIssuer
+	}
+
+	audience := os.Getenv("JWT_AUDIENCE")
+	if audience == "" {
+		audience = defaultJWTAudience
+	}
+
+	signingFile := os.Getenv("JWT_SIGNING_KEY_FILE")
+	if signingFile == "" {
+		secret := os.Getenv("JWTSecret")
+		if secret == "" {
+			return nil, errors.New("neither JWT_SIGNING_KEY_FILE nor JWTSecret is set")
+		}
+		return auth.NewHMACKeys(secret, issuer, audience), nil
+	}
+
+	signingPEM, err := os.ReadFile(signingFile)
+	if err != nil {
+		return nil, err
+	}
+
+	verifyPEMs := [][]byte{}
+	for _, file := range strings.Split(os.Getenv("JWT_VERIFICATION_KEY_FILES"), ",") {
+		file = strings.TrimSpace(file)
+		if file == "" {
+			continue
+		}
+
+		raw, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		verifyPEMs = append(verifyPEMs, raw)
+	}
+
+	return auth.NewJWTKeys(signingPEM, verifyPEMs, issuer, audience)
+}
+
+func (cfg *apiConfig) getJWKS(res http.ResponseWriter, req *http.Request) {
+	//verifiers cache it, a rotated key is announced before it signs anything
+	res.Header().Set("Cache-Control", "public, max-age=300")
+
+	respondWithJSON(res, http.StatusOK, struct {
+		Keys []auth.JWK `json:"keys"`
+	}{cfg.jwtKeys.JWKS()})
+}
+
+// generateJWTKeyCommand writes a new signing key for JWT_SIGNING_KEY_FILE.
+func generateJWTKeyCommand(args []string) error {
+	flags := flag.NewFlagSet("jwt generate-key", flag.ContinueOnError)
+	alg := flags.String("alg", "EdDSA", "EdDSA or RS256")
+	out := flags.String("out", "", "file to write the private key to")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if *out == "" {
+		return errors.New("--out is required")
+	}
+
+	keyPEM, err := auth.GenerateSigningKeyPEM(*alg)
+	if err != nil {
+		return err
+	}
+
+	//O_EXCL so an active key is never overwritten by accident
+	file, err := os.OpenFile(*out, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if _, err := file.Write(keyPEM); err != nil {
+		return err
+	}
+
+	key, err := auth.ParsePrivateKeyPEM(keyPEM)
+	if err != nil {
+		return err
+	}
+
+	kid, err := auth.KeyID(key.Public())
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("wrote %s key %s to %s\n", *alg, kid, *out)
+	return file.Close()
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"testing"
+)
+
+// TestLoadJWTKeysNeedsAKey checks the server does not sign tokens with an
+// empty secret when no signing key is configured.
+func TestLoadJWTKeysNeedsAKey(t *testing.T) {
+	t.Setenv("JWT_SIGNING_KEY_FILE", "")
+	t.Setenv("JWTSecret", "")
+
+	if _, err := loadJWTKeys(); err == nil {
+		t.Error("loadJWTKeys returned keys without a signing key or secret")
+	}
+
+	t.Setenv("JWTSecret", "secret")
+
+	keys, err := loadJWTKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keys.Issuer != defaultJWTIssuer || keys.Audience != defaultJWTAudience {
+		t.Errorf("issuer and audience = %q, %q, want the defaults", keys.Issuer, keys.Audience)
+	}
+}
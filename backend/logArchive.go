@@ -0,0 +1,438 @@
+package main
+
+import (
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/Shayaan-Kashif/Database-Project/internal/database"
+	"github.com/google/uuid"
+)
+
+const logPartitionPrefix = "parking_logs_"
+
+// logArchiveHeader is the first row of every archive. Archives made before
+// 015_timestampTZ end with a "time" column instead of "time_utc": it holds the
+// wall clock time of the TIMESTAMP column followed by a Z that does not mean
+// UTC, restoreLogPartition reads it in the time zone of the lot's campus.
+var logArchiveHeader = []string{"id", "user_id", "parking_lot_id", "event_type", "time_utc"}
+
+// logArchiveDir is where archived parking_logs partitions are written,
+// LOG_ARCHIVE_DIR or ./archive by default.
+func logArchiveDir() string {
+	if dir := os.Getenv("LOG_ARCHIVE_DIR"); dir != "" {
+		return dir
+	}
+	return "archive"
+}
+
+// logRetentionMonths is how many months of parking logs stay in postgres,
+// including the current one. 0 keeps every month.
+func logRetentionMonths() (int, error) {
+	raw := os.Getenv("LOG_RETENTION_MONTHS")
+	if raw == "" {
+		return 0, nil
+	}
+
+	months, err := strconv.Atoi(raw)
+	if err != nil || months < 0 {
+		return 0, errors.New("LOG_RETENTION_MONTHS must be a whole number of months")
+	}
+
+	return months, nil
+}
+
+func monthStart(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+func parseMonth(raw string) (time.Time, error) {
+	month, err := time.Parse("2006-01", raw)
+	if err != nil {
+		return time.Time{}, errors.New("months must be YYYY-MM")
+	}
+	return month, nil
+}
+
+// partitionMonth returns the month of a monthly partition name, default and
+// other partitions are reported as not monthly.
+func partitionMonth(name string) (time.Time, bool) {
+	month, err := time.Parse(logPartitionPrefix+"2006_01", name)
+	return month, err == nil
+}
+
+func logArchivePath(dir string, month time.Time) string {
+	return filepath.Join(dir, logPartitionPrefix+month.Format("2006_01")+".csv.gz")
+}
+
+// ensureLogPartitions creates the partitions of this month and the next two
+// so new logs do not land in the default partition.
+func (cfg *apiConfig) ensureLogPartitions(ctx context.Context) error {
+	month := monthStart(time.Now())
+
+	for i := 0; i < 3; i++ {
+		if err := cfg.dbQueries.CreateParkingLogsPartition(ctx, month.AddDate(0, i, 0)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// archiveLogPartition detaches the partition of month, writes every log of it
+// to a gzipped csv file in dir and then drops it. Logs written to the month
+// after the detach go to the default partition instead of being dropped
+// unarchived, and the file is complete before anything is dropped, so a
+// failure never loses logs. The partition is attached again when the file
+// cannot be written, one left detached by a failure after that is archived by
+// the next run. A restored month is left to the retention policy again once it
+// is archived.
+func (cfg *apiConfig) archiveLogPartition(ctx context.Context, month time.Time, dir string) (int, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return 0, err
+	}
+
+	path := logArchivePath(dir, month)
+
+	detached, err := cfg.dbQueries.GetDetachedParkingLogPartitions(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	if _, err := os.Stat(path); err == nil {
+		if !slices.Contains(detached, logPartitionPrefix+month.Format("2006_01")) {
+			return 0, fmt.Errorf("%s already exists, restore it before archiving the month again", path)
+		}
+
+		//the file was written by an archive that failed before the drop
+		return 0, cfg.dropArchivedLogPartition(ctx, month)
+	}
+
+	if err := cfg.dbQueries.DetachParkingLogsPartition(ctx, month); err != nil {
+		return 0, err
+	}
+
+	rows, err := cfg.writeLogArchive(ctx, month, dir, path)
+	if err != nil {
+		//the logs of the month must not stay out of every query until the next
+		//run, even when the archive failed because ctx was canceled
+		if attachErr := cfg.dbQueries.AttachParkingLogsPartition(context.WithoutCancel(ctx), month); attachErr != nil {
+			return 0, errors.Join(err, attachErr)
+		}
+		return 0, err
+	}
+
+	if err := cfg.dropArchivedLogPartition(ctx, month); err != nil {
+		return 0, err
+	}
+
+	return rows, nil
+}
+
+// writeLogArchive writes the detached partition of month to path. The file
+// only appears once it is complete.
+func (cfg *apiConfig) writeLogArchive(ctx context.Context, month time.Time, dir, path string) (int, error) {
+	tmp, err := os.CreateTemp(dir, ".archive-*")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	zw := gzip.NewWriter(tmp)
+	writer := csv.NewWriter(zw)
+
+	if err := writer.Write(logArchiveHeader); err != nil {
+		return 0, err
+	}
+
+	rows := 0
+
+	err = cfg.dbQueries.StreamDetachedParkingLogs(ctx, month, func(u database.ParkingLog) error {
+		rows++
+		return writer.Write([]string{
+			u.ID.String(),
+			nullUUIDString(u.UserID),
+			u.ParkingLotID.String(),
+			u.EventType,
+			u.Time.UTC().Format(time.RFC3339Nano),
+		})
+	})
+
+	if err != nil {
+		return 0, err
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return 0, err
+	}
+
+	if err := zw.Close(); err != nil {
+		return 0, err
+	}
+
+	if err := tmp.Sync(); err != nil {
+		return 0, err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return 0, err
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return 0, err
+	}
+
+	return rows, nil
+}
+
+// dropArchivedLogPartition drops the partition of an archived month.
+func (cfg *apiConfig) dropArchivedLogPartition(ctx context.Context, month time.Time) error {
+	if err := cfg.dbQueries.DropParkingLogsPartition(ctx, month); err != nil {
+		return err
+	}
+
+	return cfg.dbQueries.ClearLogMonthRestored(ctx, month)
+}
+
+// restoreLogPartition loads an archived month back into its partition. Logs of
+// lots deleted since the archive are skipped, logs of deleted users come back
+// anonymized. The month is marked as restored so the retention policy does not
+// archive it again the next day.
+func (cfg *apiConfig) restoreLogPartition(ctx context.Context, month time.Time, dir string) (int64, int64, error) {
+	path := logArchivePath(dir, month)
+
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	reader := csv.NewReader(zr)
+
+	header, err := reader.Read()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if len(header) != len(logArchiveHeader) {
+		return 0, 0, fmt.Errorf("%s is not a parking log archive", path)
+	}
+
+	wallClock := header[4] == "time"
+	lotZones := map[uuid.UUID]*time.Location{}
+
+	tx, err := cfg.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	defer tx.Rollback()
+
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	detached, err := qtx.GetDetachedParkingLogPartitions(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	//left by an archive that failed before the drop, the file has its logs
+	if slices.Contains(detached, logPartitionPrefix+month.Format("2006_01")) {
+		if err := qtx.DropParkingLogsPartition(ctx, month); err != nil {
+			return 0, 0, err
+		}
+	}
+
+	if err := qtx.CreateParkingLogsPartition(ctx, month); err != nil {
+		return 0, 0, err
+	}
+
+	restored, skipped := int64(0), int64(0)
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, 0, err
+		}
+
+		params := database.RestoreLogParams{EventType: record[3]}
+
+		if params.ID, err = uuid.Parse(record[0]); err != nil {
+			return 0, 0, err
+		}
+		//anonymized logs have no userID
+		if record[1] != "" {
+			userID, err := uuid.Parse(record[1])
+			if err != nil {
+				return 0, 0, err
+			}
+			params.UserID = uuid.NullUUID{UUID: userID, Valid: true}
+		}
+		if params.ParkingLotID, err = uuid.Parse(record[2]); err != nil {
+			return 0, 0, err
+		}
+		if params.Time, err = time.Parse(time.RFC3339Nano, record[4]); err != nil {
+			return 0, 0, err
+		}
+
+		if wallClock {
+			loc, err := cfg.lotTimeZone(ctx, lotZones, params.ParkingLotID)
+			if err != nil {
+				return 0, 0, err
+			}
+
+			t := params.Time
+			params.Time = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), loc)
+		}
+
+		inserted, err := qtx.RestoreLog(ctx, params)
+		if err != nil {
+			return 0, 0, err
+		}
+
+		restored += inserted
+		skipped += 1 - inserted
+	}
+
+	if err := qtx.MarkLogMonthRestored(ctx, month); err != nil {
+		return 0, 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, 0, err
+	}
+
+	//the logs are back in postgres, the file is kept as a backup under a new name
+	if err := os.Rename(path, path+".restored"); err != nil {
+		return 0, 0, err
+	}
+
+	return restored, skipped, nil
+}
+
+// lotTimeZone returns the time zone of the lot's campus, or the default time
+// zone for lots deleted since, caching it in zones.
+func (cfg *apiConfig) lotTimeZone(ctx context.Context, zones map[uuid.UUID]*time.Location, lotID uuid.UUID) (*time.Location, error) {
+	if loc, ok := zones[lotID]; ok {
+		return loc, nil
+	}
+
+	campusID := uuid.NullUUID{}
+
+	lotDB, err := cfg.dbQueries.GetParkingLotFromID(ctx, lotID)
+	if err == nil {
+		campusID = uuid.NullUUID{UUID: lotDB.CampusID, Valid: true}
+	} else if err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	loc, err := cfg.campusLocation(ctx, campusID)
+	if err != nil {
+		return nil, err
+	}
+
+	zones[lotID] = loc
+	return loc, nil
+}
+
+// applyLogRetention archives every monthly partition older than the last
+// keepMonths months, except the restored ones, and finishes the archives that
+// failed after detaching their partition.
+func (cfg *apiConfig) applyLogRetention(ctx context.Context, keepMonths int, dir string) error {
+	if keepMonths <= 0 {
+		return nil
+	}
+
+	cutoff := monthStart(time.Now()).AddDate(0, 1-keepMonths, 0)
+
+	partitions, err := cfg.dbQueries.GetParkingLogPartitions(ctx)
+	if err != nil {
+		return err
+	}
+
+	restored, err := cfg.restoredLogMonths(ctx)
+	if err != nil {
+		return err
+	}
+
+	detached, err := cfg.dbQueries.GetDetachedParkingLogPartitions(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, partition := range append(partitions, detached...) {
+		month, ok := partitionMonth(partition)
+		if !ok {
+			continue
+		}
+
+		if !slices.Contains(detached, partition) && (!month.Before(cutoff) || restored[month]) {
+			continue
+		}
+
+		rows, err := cfg.archiveLogPartition(ctx, month, dir)
+		if err != nil {
+			return err
+		}
+
+		log.Printf("archived %d parking logs of %s to %s", rows, month.Format("2006-01"), logArchivePath(dir, month))
+	}
+
+	return nil
+}
+
+// restoredLogMonths returns the months restored since they were last archived.
+func (cfg *apiConfig) restoredLogMonths(ctx context.Context) (map[time.Time]bool, error) {
+	months, err := cfg.dbQueries.GetRestoredLogMonths(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	restored := make(map[time.Time]bool, len(months))
+	for _, month := range months {
+		restored[month.UTC()] = true
+	}
+
+	return restored, nil
+}
+
+// maintainLogPartitions creates upcoming partitions and applies the retention
+// policy once a day while the server runs.
+func (cfg *apiConfig) maintainLogPartitions(ctx context.Context) {
+	for {
+		if err := cfg.ensureLogPartitions(ctx); err != nil {
+			log.Printf("Error creating parking log partitions: %s", err)
+		}
+
+		if keepMonths, err := logRetentionMonths(); err != nil {
+			log.Printf("Error reading the log retention policy: %s", err)
+		} else if err := cfg.applyLogRetention(ctx, keepMonths, logArchiveDir()); err != nil {
+			log.Printf("Error archiving parking logs: %s", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(24 * time.Hour):
+		}
+	}
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/Shayaan-Kashif/Database-Project/internal/database"
+	"github.com/google/uuid"
+	"github.com/parquet-go/parquet-go"
+)
+
+var logExportContentTypes = map[string]string{
+	"csv":     "text/csv",
+	"ndjson":  "application/x-ndjson",
+	"parquet": "application/vnd.apache.parquet",
+}
+
+// parquetRowGroupSize is how many rows the parquet export buffers before
+// writing them out as a row group.
+const parquetRowGroupSize = 64 * 1024
+
+// parquetLog is a row of the parquet export, user_id is null for the logs of
+// deleted users.
+type parquetLog struct {
+	ID           string    `parquet:"id"`
+	UserID       string    `parquet:"user_id,optional"`
+	ParkingLotID string    `parquet:"parking_lot_id"`
+	LotName      string    `parquet:"lot_name"`
+	EventType    string    `parquet:"event_type"`
+	Time         time.Time `parquet:"time,timestamp(millisecond)"`
+}
+
+func newParquetLogWriter(w io.Writer, rowGroupSize int64) *parquet.GenericWriter[parquetLog] {
+	return parquet.NewGenericWriter[parquetLog](w,
+		parquet.Compression(&parquet.Gzip),
+		parquet.MaxRowsPerRowGroup(rowGroupSize),
+	)
+}
+
+// parseExportTime accepts RFC 3339 timestamps or plain dates. Plain dates start
+// at midnight in loc, and as the end of a range include that whole day.
+func parseExportTime(raw string, end bool, loc *time.Location) (sql.NullTime, error) {
+	if raw == "" {
+		return sql.NullTime{}, nil
+	}
+
+	if parsed, err := time.Parse(time.RFC3339, raw); err == nil {
+		return sql.NullTime{Time: parsed, Valid: true}, nil
+	}
+
+	parsed, err := time.ParseInLocation("2006-01-02", raw, loc)
+	if err != nil {
+		return sql.NullTime{}, errors.New("dates must be YYYY-MM-DD or RFC 3339")
+	}
+
+	if end {
+		parsed = parsed.AddDate(0, 0, 1)
+	}
+
+	return sql.NullTime{Time: parsed, Valid: true}, nil
+}
+
+// logExportParams builds the export filters shared by the endpoint and the
+// cli. Plain dates are read in the time zone of the campus.
+func (cfg *apiConfig) logExportParams(ctx context.Context, from, to, lotID, eventType string, campusID uuid.NullUUID) (database.GetLogsForExportParams, error) {
+	params := database.GetLogsForExportParams{CampusID: campusID}
+
+	loc, err := cfg.campusLocation(ctx, campusID)
+	if err != nil {
+		return params, err
+	}
+
+	if params.FromTime, err = parseExportTime(from, false, loc); err != nil {
+		return params, err
+	}
+
+	if params.ToTime, err = parseExportTime(to, true, loc); err != nil {
+		return params, err
+	}
+
+	if lotID != "" {
+		parsed, err := uuid.Parse(lotID)
+		if err != nil {
+			return params, err
+		}
+		params.LotID = uuid.NullUUID{UUID: parsed, Valid: true}
+	}
+
+	if eventType != "" {
+		if eventType != "entry" && eventType != "exit" {
+			return params, errors.New("incorrect type input")
+		}
+		params.EventType = sql.NullString{String: eventType, Valid: true}
+	}
+
+	return params, nil
+}
+
+// writeLogExport streams the matching parking logs to w one row at a time.
+// Parquet rows are flushed in row groups to keep memory bounded.
+func (cfg *apiConfig) writeLogExport(ctx context.Context, w io.Writer, format string, params database.GetLogsForExportParams) error {
+	switch format {
+	case "csv":
+		csvWriter := csv.NewWriter(w)
+
+		if err := csvWriter.Write([]string{"id", "userID", "parkingLotID", "lotName", "eventType", "time"}); err != nil {
+			return err
+		}
+
+		err := cfg.dbQueries.StreamLogsForExport(ctx, params, func(u database.GetLogsForExportRow) error {
+			return csvWriter.Write([]string{
+				u.ID.String(),
+				nullUUIDString(u.UserID),
+				u.ParkingLotID.String(),
+				u.LotName,
+				u.EventType,
+				u.Time.Format(time.RFC3339),
+			})
+		})
+
+		if err != nil {
+			return err
+		}
+
+		csvWriter.Flush()
+		return csvWriter.Error()
+
+	case "ndjson":
+		encoder := json.NewEncoder(w)
+
+		return cfg.dbQueries.StreamLogsForExport(ctx, params, func(u database.GetLogsForExportRow) error {
+			return encoder.Encode(struct {
+				ID           uuid.UUID     `json:"id"`
+				UserID       uuid.NullUUID `json:"userID"`
+				ParkingLotID uuid.UUID     `json:"parkingLotID"`
+				LotName      string        `json:"lotName"`
+				EventType    string        `json:"eventType"`
+				Time         time.Time     `json:"time"`
+			}{
+				ID:           u.ID,
+				UserID:       u.UserID,
+				ParkingLotID: u.ParkingLotID,
+				LotName:      u.LotName,
+				EventType:    u.EventType,
+				Time:         u.Time,
+			})
+		})
+
+	case "parquet":
+		parquetWriter := newParquetLogWriter(w, parquetRowGroupSize)
+
+		err := cfg.dbQueries.StreamLogsForExport(ctx, params, func(u database.GetLogsForExportRow) error {
+			_, err := parquetWriter.Write([]parquetLog{{
+				ID:           u.ID.String(),
+				UserID:       nullUUIDString(u.UserID),
+				ParkingLotID: u.ParkingLotID.String(),
+				LotName:      u.LotName,
+				EventType:    u.EventType,
+				Time:         u.Time,
+			}})
+			return err
+		})
+
+		if err != nil {
+			return err
+		}
+
+		return parquetWriter.Close()
+	}
+
+	return errors.New("incorrect format input")
+}
+
+func (cfg *apiConfig) exportParkingLogs(res http.ResponseWriter, req *http.Request) {
+	format := req.URL.Query().Get("format")
+	if format == "" {
+		format = "csv"
+	}
+
+	contentType, ok := logExportContentTypes[format]
+	if !ok {
+		respondWithError(res, http.StatusBadRequest, "incorrect format input")
+		return
+	}
+
+	campusID, err := cfg.adminCampusFilter(req)
+
+	if err != nil {
+		respondWithError(res, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	query := req.URL.Query()
+
+	params, err := cfg.logExportParams(req.Context(), query.Get("from"), query.Get("to"), query.Get("lotID"), query.Get("type"), campusID)
+
+	if err != nil {
+		respondWithError(res, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	res.Header().Set("Content-Type", contentType)
+	res.Header().Set("Content-Disposition", "attachment; filename=parkingLogs."+format)
+	res.WriteHeader(http.StatusOK)
+
+	//the status is already sent, a failure can only cut the download short
+	if err := cfg.writeLogExport(req.Context(), res, format, params); err != nil {
+		log.Printf("Error exporting parking logs: %s", err)
+	}
+}
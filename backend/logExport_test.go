@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/parquet-go/parquet-go/format"
+)
+
+func TestParquetLogRoundTrip(t *testing.T) {
+	start := time.Date(2025, 3, 9, 6, 59, 59, 123_000_000, time.UTC)
+
+	tests := []struct {
+		name      string
+		rows      int
+		groupSize int64
+		groups    int
+	}{
+		{"empty", 0, 4, 0},
+		{"single row group", 3, 4, 1},
+		{"exact row group", 4, 4, 1},
+		{"several row groups", 10, 4, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := make([]parquetLog, tt.rows)
+			for i := range want {
+				want[i] = parquetLog{
+					ID:           fmt.Sprintf("log-%d", i),
+					ParkingLotID: "lot",
+					LotName:      "Lot 5",
+					EventType:    "entry",
+					Time:         start.Add(time.Duration(i) * time.Hour),
+				}
+				//every other log belongs to a deleted user
+				if i%2 == 0 {
+					want[i].UserID = fmt.Sprintf("user-%d", i)
+				}
+			}
+
+			var buf bytes.Buffer
+			w := newParquetLogWriter(&buf, tt.groupSize)
+
+			//written one row at a time like the export does
+			for _, row := range want {
+				if _, err := w.Write([]parquetLog{row}); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if err := w.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			file, err := parquet.OpenFile(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := file.NumRows(); got != int64(tt.rows) {
+				t.Errorf("NumRows = %d, want %d", got, tt.rows)
+			}
+
+			if got := len(file.RowGroups()); got != tt.groups {
+				t.Errorf("got %d row groups, want %d", got, tt.groups)
+			}
+
+			for _, rg := range file.Metadata().RowGroups {
+				var compressed int64
+				for _, c := range rg.Columns {
+					if c.MetaData.Codec != format.Gzip {
+						t.Errorf("column %v uses %v, want gzip", c.MetaData.PathInSchema, c.MetaData.Codec)
+					}
+					compressed += c.MetaData.TotalCompressedSize
+				}
+				if rg.TotalCompressedSize != 0 && rg.TotalCompressedSize != compressed {
+					t.Errorf("TotalCompressedSize = %d, columns add up to %d", rg.TotalCompressedSize, compressed)
+				}
+			}
+
+			timeColumn, ok := file.Schema().Lookup("time")
+			if !ok {
+				t.Fatal("no time column")
+			}
+
+			logical := timeColumn.Node.Type().LogicalType()
+			if logical == nil || logical.Timestamp == nil || logical.Timestamp.Unit.Millis == nil || !logical.Timestamp.IsAdjustedToUTC {
+				t.Errorf("time column is %v, want TIMESTAMP(MILLIS, UTC)", logical)
+			}
+
+			userColumn, _ := file.Schema().Lookup("user_id")
+			if !userColumn.Node.Optional() {
+				t.Error("user_id is not optional")
+			}
+
+			reader := parquet.NewGenericReader[parquetLog](file)
+			defer reader.Close()
+
+			got := make([]parquetLog, tt.rows+1)
+			n, err := reader.Read(got)
+			if err != nil && err != io.EOF {
+				t.Fatal(err)
+			}
+
+			if n != tt.rows {
+				t.Fatalf("read %d rows, want %d", n, tt.rows)
+			}
+
+			for i := range want {
+				if got[i].ID != want[i].ID || got[i].UserID != want[i].UserID || got[i].LotName != want[i].LotName || got[i].EventType != want[i].EventType {
+					t.Errorf("row %d = %+v, want %+v", i, got[i], want[i])
+				}
+				if !got[i].Time.Equal(want[i].Time) {
+					t.Errorf("row %d time = %s, want %s", i, got[i].Time, want[i].Time)
+				}
+			}
+		})
+	}
+}
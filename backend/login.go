@@ -1,23 +1,27 @@
 package main
 
 import (
+	"context"
 	"database/sql"
-	"errors"
+	"log"
 	"net/http"
 	"time"
 
 	"github.com/Shayaan-Kashif/Database-Project/internal/auth"
 	"github.com/Shayaan-Kashif/Database-Project/internal/database"
 	"github.com/google/uuid"
-	"github.com/jackc/pgx/v5/pgconn"
 )
 
 func (cfg *apiConfig) signUp(res http.ResponseWriter, req *http.Request) {
+	if !cfg.passwordLoginAllowed(res) {
+		return
+	}
+
 	reqStruct := struct {
-		Email     *string `json:"email"`
-		Password  *string `json:"password"`
-		Name      *string `json:"name"`
-		AdminCode string  `json:"adminCode"`
+		Email      *string `json:"email"`
+		Password   *string `json:"password"`
+		Name       *string `json:"name"`
+		Invitation string  `json:"invitation"`
 	}{}
 
 	if err := decodeJSON(req, &reqStruct); err != nil {
@@ -30,23 +34,42 @@ func (cfg *apiConfig) signUp(res http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	role := "user"
+	hashed_password, err := auth.Hashpassword(*reqStruct.Password)
 
-	if reqStruct.AdminCode == cfg.adminCode {
-		role = "admin"
-	} else if reqStruct.AdminCode != "" {
-		respondWithError(res, http.StatusUnauthorized, "Unauthorized")
+	if err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	hashed_password, err := auth.Hashpassword(*reqStruct.Password)
+	tx, err := cfg.db.BeginTx(req.Context(), nil)
 
 	if err != nil {
 		respondWithError(res, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	userDBEntry, err := cfg.dbQueries.CreateUser(req.Context(), database.CreateUserParams{
+	defer tx.Rollback()
+
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	role := "user"
+	invitationDB := database.AdminInvitation{}
+
+	if reqStruct.Invitation != "" {
+		invitationDB, err = redeemInvitation(req.Context(), qtx, reqStruct.Invitation, *reqStruct.Email)
+
+		if err == errInvalidInvitation {
+			respondWithError(res, http.StatusUnauthorized, err.Error())
+			return
+		} else if err != nil {
+			respondWithError(res, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		role = invitationDB.Role
+	}
+
+	userDBEntry, err := qtx.CreateUser(req.Context(), database.CreateUserParams{
 		Name:           *reqStruct.Name,
 		Email:          *reqStruct.Email,
 		HashedPassword: hashed_password,
@@ -58,6 +81,54 @@ func (cfg *apiConfig) signUp(res http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	if reqStruct.Invitation != "" {
+		//the invitation is what grants the role and campus
+		err = qtx.SetUserRoleAndCampus(req.Context(), database.SetUserRoleAndCampusParams{
+			Role:          invitationDB.Role,
+			AdminCampusID: invitationDB.AdminCampusID,
+			ID:            userDBEntry.ID,
+		})
+
+		if err != nil {
+			respondWithError(res, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		err = qtx.SetAdminInvitationRedeemer(req.Context(), database.SetAdminInvitationRedeemerParams{
+			RedeemedBy: uuid.NullUUID{UUID: userDBEntry.ID, Valid: true},
+			ID:         invitationDB.ID,
+		})
+
+		if err != nil {
+			respondWithError(res, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		redeemed, err := qtx.GetAdminInvitationFromID(req.Context(), invitationDB.ID)
+
+		if err != nil {
+			respondWithError(res, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		entry := invitationAuditEntry("invitation.redeem", &invitationDB, &redeemed)
+
+		if err := audit(actorContext(req.Context(), userDBEntry.ID, role), qtx, entry); err != nil {
+			respondWithError(res, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	//the account exists either way, a failed email can be sent again with /api/users/verify/resend
+	if err := cfg.sendVerificationEmail(req.Context(), userDBEntry.ID, userDBEntry.Email); err != nil {
+		log.Printf("Error sending the verification email: %s", err)
+	}
+
 	responceStruct := struct {
 		Name  string `json:"name"`
 		Email string `json:"email"`
@@ -72,7 +143,69 @@ func (cfg *apiConfig) signUp(res http.ResponseWriter, req *http.Request) {
 
 }
 
+// sessionLifetime is how long a login lasts however often it is refreshed, the
+// user has to login again after it.
+const sessionLifetime = 30 * 24 * time.Hour
+
+// refreshReuseGrace is how long a rotated refresh token keeps working, so the
+// concurrent refreshes of several tabs or proxied requests sending the same
+// cookie are not mistaken for a stolen token.
+const refreshReuseGrace = 10 * time.Second
+
+// issueRefreshToken stores a new refresh token of the family, valid for an hour
+// but never past the end of its session. It runs inside the caller's
+// transaction, where a failed insert aborts the transaction, so a duplicate of
+// the 256 random bits is not retried.
+func issueRefreshToken(ctx context.Context, queries *database.Queries, userID, familyID uuid.UUID) (string, error) {
+	refreshToken, err := auth.MakeRefreshToken()
+
+	if err != nil {
+		return "", err
+	}
+
+	_, err = queries.CreateRefreshToken(ctx, database.CreateRefreshTokenParams{
+		Token:     refreshToken,
+		UserID:    userID,
+		ExpiresAt: time.Now().Add(time.Hour),
+		FamilyID:  familyID,
+	})
+
+	if err != nil {
+		return "", err
+	}
+
+	return refreshToken, nil
+}
+
+func setRefreshCookie(res http.ResponseWriter, refreshToken string) {
+	http.SetCookie(res, &http.Cookie{
+		Name:     "refresh_token",
+		Value:    refreshToken,
+		HttpOnly: true,
+		Secure:   false, //only for dev side, true for production
+		SameSite: http.SameSiteLaxMode,
+		Path:     "/api",
+		MaxAge:   3600,
+	})
+}
+
+func clearRefreshCookie(res http.ResponseWriter) {
+	http.SetCookie(res, &http.Cookie{
+		Name:     "refresh_token",
+		Value:    "",
+		HttpOnly: true,
+		Secure:   false, //only for dev side, true for production
+		SameSite: http.SameSiteLaxMode,
+		Path:     "/api",
+		MaxAge:   -1,
+	})
+}
+
 func (cfg *apiConfig) login(res http.ResponseWriter, req *http.Request) {
+	if !cfg.passwordLoginAllowed(res) {
+		return
+	}
+
 	reqStruct := struct {
 		Email    *string `json:"email"`
 		Password *string `json:"password"`
@@ -88,87 +221,151 @@ func (cfg *apiConfig) login(res http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	userDB, err := cfg.dbQueries.GetUserFromEmail(req.Context(), *reqStruct.Email)
-
-	if err == sql.ErrNoRows { //user does not exist with that email
-		respondWithError(res, http.StatusUnauthorized, "Unauthorized")
-		return
-	} else if err != nil { //other server error
-		respondWithError(res, http.StatusInternalServerError, err.Error())
-		return
-	}
+	ip := clientIP(req)
 
-	correctPassword, err := auth.CheckPasswordHash(*reqStruct.Password, userDB.HashedPassword)
+	wait, err := cfg.loginRetryAfter(req.Context(), *reqStruct.Email, ip)
 
 	if err != nil {
 		respondWithError(res, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	if !correctPassword {
-		respondWithError(res, http.StatusUnauthorized, "Unauthorized")
+	if wait > 0 {
+		respondLockedOut(res, wait)
 		return
 	}
 
-	//code below assumes the client is authenticated
+	userDB, err := cfg.dbQueries.GetUserFromEmail(req.Context(), *reqStruct.Email)
 
-	JWT, err := auth.MakeJWT(userDB.ID, userDB.Role, cfg.JWTSecret, (15 * time.Minute))
-	if err != nil {
+	if err != nil && err != sql.ErrNoRows { //other server error
 		respondWithError(res, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	refreshToken := ""
+	correctPassword := false
 
-	for tries := 1; tries <= 3; tries++ {
-		refreshToken, err = auth.MakeRefreshToken()
+	if err == nil {
+		correctPassword, err = auth.CheckPasswordHash(*reqStruct.Password, userDB.HashedPassword)
 
 		if err != nil {
 			respondWithError(res, http.StatusInternalServerError, err.Error())
 			return
 		}
+	}
 
-		_, err = cfg.dbQueries.CreateRefreshToken(req.Context(), database.CreateRefreshTokenParams{
-			Token:     refreshToken,
-			UserID:    userDB.ID,
-			ExpiresAt: time.Now().Add(time.Hour),
-		})
-
-		if err == nil {
-			break
-		}
-
-		var pqErr *pgconn.PgError
-		if !(errors.As(err, &pqErr) && pqErr.Code == "23505") { //database connection error
+	if !correctPassword { //wrong password or no user with that email
+		if err := cfg.recordLoginFailure(req.Context(), *reqStruct.Email, ip); err != nil {
 			respondWithError(res, http.StatusInternalServerError, err.Error())
 			return
 		}
 
+		respondWithError(res, http.StatusUnauthorized, "Unauthorized")
+		return
 	}
 
-	if err != nil {
-		respondWithError(res, http.StatusInternalServerError, err.Error()) //if unique refreshtoken cannnot be made
+	if userDB.DisabledAt.Valid {
+		respondWithError(res, http.StatusForbidden, "account disabled")
 		return
 	}
 
-	http.SetCookie(res, &http.Cookie{
-		Name:     "refresh_token",
-		Value:    refreshToken,
-		HttpOnly: true,
-		Secure:   false, //only for dev side, true for production
-		SameSite: http.SameSiteLaxMode,
-		Path:     "/api",
-		MaxAge:   3600,
+	if needsSecondFactor(userDB) {
+		cfg.startLoginChallenge(res, req, userDB)
+		return
+	}
+
+	cfg.startSession(res, req, userDB, nil)
+}
+
+// createSession logs in an authenticated user: it creates the session with its
+// first refresh token and returns that and a new access token.
+func (cfg *apiConfig) createSession(req *http.Request, userDB database.User) (string, string, error) {
+	//cleared only once the whole login succeeded, so a known password does not
+	//reset failed second factor codes. the ip keeps counting failures on other
+	//accounts
+	_, err := cfg.dbQueries.ClearLoginThrottle(req.Context(), database.ClearLoginThrottleParams{
+		Kind:  "account",
+		Value: accountThrottleKey(userDB.Email),
 	})
 
+	if err != nil {
+		return "", "", err
+	}
+
+	JWT, err := cfg.jwtKeys.MakeJWT(userDB.ID, userDB.Role, userDB.TokenVersion, 15*time.Minute)
+	if err != nil {
+		return "", "", err
+	}
+
+	familyID := uuid.New()
+
+	tx, err := cfg.db.BeginTx(req.Context(), nil)
+
+	if err != nil {
+		return "", "", err
+	}
+
+	defer tx.Rollback()
+
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	err = qtx.CreateSession(req.Context(), database.CreateSessionParams{
+		ID:        familyID,
+		UserID:    userDB.ID,
+		UserAgent: req.UserAgent(),
+		Ip:        clientIP(req),
+		ExpiresAt: time.Now().Add(sessionLifetime),
+	})
+
+	if err != nil {
+		return "", "", err
+	}
+
+	refreshToken, err := issueRefreshToken(req.Context(), qtx, userDB.ID, familyID)
+
+	if err != nil {
+		return "", "", err
+	}
+
+	err = audit(actorContext(req.Context(), userDB.ID, userDB.Role), qtx, auditEntry{
+		Action:     "session.login",
+		TargetType: "session",
+		TargetID:   familyID,
+		CampusID:   userDB.CampusID,
+	})
+
+	if err != nil {
+		return "", "", err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", "", err
+	}
+
+	return JWT, refreshToken, nil
+}
+
+// startSession creates the session and answers with the access token.
+// recoveryCodes are included when the login also finished the TOTP enrollment.
+func (cfg *apiConfig) startSession(res http.ResponseWriter, req *http.Request, userDB database.User, recoveryCodes []string) {
+	JWT, refreshToken, err := cfg.createSession(req, userDB)
+
+	if err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	setRefreshCookie(res, refreshToken)
+
 	responseStruct := struct {
-		AccessToken string `json:"access_token"`
-		Name        string `json:"name"`
-		Role        string `json:"role"`
+		AccessToken   string   `json:"access_token"`
+		Name          string   `json:"name"`
+		Role          string   `json:"role"`
+		RecoveryCodes []string `json:"recoveryCodes,omitempty"`
 	}{
-		AccessToken: JWT,
-		Name:        userDB.Name,
-		Role:        userDB.Role,
+		AccessToken:   JWT,
+		Name:          userDB.Name,
+		Role:          userDB.Role,
+		RecoveryCodes: recoveryCodes,
 	}
 
 	respondWithJSON(res, http.StatusOK, responseStruct)
@@ -182,20 +379,22 @@ func (cfg *apiConfig) logout(res http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	if err := cfg.dbQueries.RevokeToken(req.Context(), refreshToken); err != nil {
+	dbToken, err := cfg.dbQueries.GetRefreshToken(req.Context(), refreshToken)
+
+	if err != nil && err != sql.ErrNoRows {
 		respondWithError(res, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	http.SetCookie(res, &http.Cookie{
-		Name:     "refresh_token",
-		Value:    "",
-		HttpOnly: true,
-		Secure:   false, //only for dev side, true for production
-		SameSite: http.SameSiteLaxMode,
-		Path:     "/api",
-		MaxAge:   -1,
-	})
+	//the whole family is the session, its older rotated tokens go with it
+	if err == nil {
+		if err := cfg.dbQueries.RevokeTokenFamily(req.Context(), dbToken.FamilyID); err != nil {
+			respondWithError(res, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+
+	clearRefreshCookie(res)
 
 	respondWithJSON(res, http.StatusOK, struct {
 		Status string `json:"status"`
@@ -220,11 +419,30 @@ func (cfg *apiConfig) refresh(res http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	//past the grace period a rotated token is only ever presented again if it
+	//was copied, the whole family is revoked so neither the thief nor the user
+	//can keep using it
+	if dbToken.RotatedAt.Valid && (dbToken.RevokedAt.Valid || time.Since(dbToken.RotatedAt.Time) > refreshReuseGrace) {
+		cfg.revokeReusedFamily(actorContext(req.Context(), dbToken.UserID, dbToken.Role), res, dbToken.FamilyID)
+		return
+	}
+
 	if dbToken.RevokedAt.Valid {
 		respondWithError(res, http.StatusUnauthorized, "refresh token revoked/expired")
 		return
 	}
 
+	if dbToken.DisabledAt.Valid {
+		respondWithError(res, http.StatusForbidden, "account disabled")
+		return
+	}
+
+	//a user promoted to admin after logging in has to login again to enroll
+	if dbToken.Role == "admin" && !dbToken.TotpEnabledAt.Valid {
+		respondWithError(res, http.StatusUnauthorized, "two-factor authentication required, login again")
+		return
+	}
+
 	if time.Now().After(dbToken.ExpiresAt) {
 		if !dbToken.RevokedAt.Valid {
 			if err := cfg.dbQueries.RevokeToken(req.Context(), refreshToken); err != nil {
@@ -237,13 +455,76 @@ func (cfg *apiConfig) refresh(res http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	JWT, err := auth.MakeJWT(dbToken.UserID, dbToken.Role, cfg.JWTSecret, 15*time.Minute)
+	tx, err := cfg.db.BeginTx(req.Context(), nil)
+
+	if err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	defer tx.Rollback()
+
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	//a token rotated within the grace period gets another token of the family,
+	//alongside the one its first refresh got
+	if !dbToken.RotatedAt.Valid {
+		rotated, err := qtx.RotateRefreshToken(req.Context(), refreshToken)
+
+		if err != nil {
+			respondWithError(res, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		//a concurrent refresh rotated it since it was read, which the grace
+		//period allows, unless it was revoked instead
+		if rotated == 0 {
+			current, err := qtx.GetRefreshToken(req.Context(), refreshToken)
+
+			if err != nil {
+				respondWithError(res, http.StatusInternalServerError, err.Error())
+				return
+			}
+
+			if current.RevokedAt.Valid {
+				respondWithError(res, http.StatusUnauthorized, "refresh token revoked/expired")
+				return
+			}
+		}
+	}
+
+	newRefreshToken, err := issueRefreshToken(req.Context(), qtx, dbToken.UserID, dbToken.FamilyID)
+
+	if err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	err = qtx.TouchSession(req.Context(), database.TouchSessionParams{
+		ID:        dbToken.FamilyID,
+		UserAgent: req.UserAgent(),
+		Ip:        clientIP(req),
+	})
 
 	if err != nil {
 		respondWithError(res, http.StatusInternalServerError, err.Error())
 		return
 	}
 
+	if err := tx.Commit(); err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	JWT, err := cfg.jwtKeys.MakeJWT(dbToken.UserID, dbToken.Role, dbToken.TokenVersion, 15*time.Minute)
+
+	if err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	setRefreshCookie(res, newRefreshToken)
+
 	respondWithJSON(res, http.StatusOK, struct {
 		AccessToken string `json:"access_token"`
 		Role        string `json:"role"`
@@ -251,6 +532,86 @@ func (cfg *apiConfig) refresh(res http.ResponseWriter, req *http.Request) {
 
 }
 
+// revokeReusedFamily answers a refresh with a token that was already rotated,
+// ctx has the owner of the token as the actor.
+func (cfg *apiConfig) revokeReusedFamily(ctx context.Context, res http.ResponseWriter, familyID uuid.UUID) {
+	tx, err := cfg.db.BeginTx(ctx, nil)
+
+	if err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	defer tx.Rollback()
+
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	if err := qtx.RevokeTokenFamily(ctx, familyID); err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	err = audit(ctx, qtx, auditEntry{
+		Action:     "session.reuse_revoke",
+		TargetType: "session",
+		TargetID:   familyID,
+	})
+
+	if err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	clearRefreshCookie(res)
+	respondWithError(res, http.StatusUnauthorized, "refresh token reused, login again")
+}
+
+// userProfileJSON is the signed in user as /api/user and the data export
+// return it.
+type userProfileJSON struct {
+	ID                  uuid.UUID      `json:"id"`
+	Name                string         `json:"name"`
+	Email               string         `json:"email"`
+	Role                string         `json:"role"`
+	ParkingLotID        uuid.NullUUID  `json:"parkingLotID"`
+	LicensePlate        sql.NullString `json:"licensePlate"`
+	CampusID            uuid.NullUUID  `json:"campusID"`
+	EmailVerified       bool           `json:"emailVerified"`
+	TwoFactor           bool           `json:"twoFactorEnabled"`
+	Permissions         []permission   `json:"permissions"`
+	DeletionScheduledAt *time.Time     `json:"deletionScheduledAt"`
+	CreatedAt           time.Time      `json:"createdAt"`
+	UpdatedAt           time.Time      `json:"updatedAt"`
+}
+
+func toUserProfileJSON(u database.User) userProfileJSON {
+	out := userProfileJSON{
+		ID:            u.ID,
+		Name:          u.Name,
+		Email:         u.Email,
+		Role:          u.Role,
+		ParkingLotID:  u.ParkingLotID,
+		LicensePlate:  u.LicensePlate,
+		CampusID:      u.CampusID,
+		EmailVerified: u.EmailVerifiedAt.Valid,
+		TwoFactor:     u.TotpEnabledAt.Valid,
+		Permissions:   rolePermissions[u.Role],
+		CreatedAt:     u.CreatedAt,
+		UpdatedAt:     u.UpdatedAt,
+	}
+
+	if u.DeletionScheduledAt.Valid {
+		out.DeletionScheduledAt = &u.DeletionScheduledAt.Time
+	}
+
+	return out
+}
+
 func (cfg *apiConfig) getUserFromID(res http.ResponseWriter, req *http.Request) {
 	userID := req.Context().Value(ctxUserID).(uuid.UUID)
 
@@ -261,36 +622,18 @@ func (cfg *apiConfig) getUserFromID(res http.ResponseWriter, req *http.Request)
 		return
 	}
 
-	response := struct {
-		ID           uuid.UUID     `json:"id"`
-		Name         string        `json:"name"`
-		Email        string        `json:"email"`
-		Role         string        `json:"role"`
-		ParkingLotID uuid.NullUUID `json:"parkingLotID"`
-		CreatedAt    time.Time     `json:"createdAt"`
-		UpdatedAt    time.Time     `json:"updatedAt"`
-	}{
-		ID:           userDB.ID,
-		Name:         userDB.Name,
-		Email:        userDB.Email,
-		Role:         userDB.Role,
-		ParkingLotID: userDB.ParkingLotID,
-		CreatedAt:    userDB.CreatedAt,
-		UpdatedAt:    userDB.UpdatedAt,
-	}
-
-	respondWithJSON(res, http.StatusOK, response)
+	respondWithJSON(res, http.StatusOK, toUserProfileJSON(userDB))
 }
 
 func (cfg *apiConfig) getAllUsers(res http.ResponseWriter, req *http.Request) {
-	role := req.Context().Value(ctxRole).(string)
+	campusID, err := cfg.adminCampusFilter(req)
 
-	if role != "admin" {
-		respondWithError(res, http.StatusUnauthorized, "Unauthorized")
+	if err != nil {
+		respondWithError(res, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	usersDB, err := cfg.dbQueries.GetAllUsers(req.Context())
+	usersDB, err := cfg.dbQueries.GetAllUsers(req.Context(), campusID)
 
 	if err != nil {
 		respondWithError(res, http.StatusInternalServerError, err.Error())
@@ -303,6 +646,7 @@ func (cfg *apiConfig) getAllUsers(res http.ResponseWriter, req *http.Request) {
 		Email        string        `json:"email"`
 		Role         string        `json:"role"`
 		ParkingLotID uuid.NullUUID `json:"parkingLotID"`
+		CampusID     uuid.NullUUID `json:"campusID"`
 		CreatedAt    time.Time     `json:"createdAt"`
 		UpdatedAt    time.Time     `json:"updatedAt"`
 	}, 0, len(usersDB))
@@ -314,6 +658,7 @@ func (cfg *apiConfig) getAllUsers(res http.ResponseWriter, req *http.Request) {
 			Email        string        `json:"email"`
 			Role         string        `json:"role"`
 			ParkingLotID uuid.NullUUID `json:"parkingLotID"`
+			CampusID     uuid.NullUUID `json:"campusID"`
 			CreatedAt    time.Time     `json:"createdAt"`
 			UpdatedAt    time.Time     `json:"updatedAt"`
 		}{
@@ -322,6 +667,7 @@ func (cfg *apiConfig) getAllUsers(res http.ResponseWriter, req *http.Request) {
 			Email:        u.Email,
 			Role:         u.Role,
 			ParkingLotID: u.ParkingLotID,
+			CampusID:     u.CampusID,
 			CreatedAt:    u.CreatedAt,
 			UpdatedAt:    u.CreatedAt,
 		})
@@ -330,36 +676,15 @@ func (cfg *apiConfig) getAllUsers(res http.ResponseWriter, req *http.Request) {
 	respondWithJSON(res, http.StatusOK, response)
 }
 
-func (cfg *apiConfig) deleteUser(res http.ResponseWriter, req *http.Request) {
-	userID := req.Context().Value(ctxUserID).(uuid.UUID)
-
-	sqlResult, err := cfg.dbQueries.DeleteUser(req.Context(), userID)
-
-	if err != nil {
-		respondWithError(res, http.StatusInternalServerError, err.Error())
-		return
-	}
-
-	rowsAffected, _ := sqlResult.RowsAffected()
-	if rowsAffected == 0 {
-		respondWithError(res, http.StatusNotFound, "No user with this ID was found")
-		return
-	}
-
-	responseStruct := struct {
-		Status string `json:"status"`
-	}{"The user has been deleted"}
-
-	respondWithJSON(res, http.StatusOK, responseStruct)
-}
-
 func (cfg *apiConfig) updateUser(res http.ResponseWriter, req *http.Request) {
 	userID := req.Context().Value(ctxUserID).(uuid.UUID)
 
 	reqStruct := struct {
-		Name     *string `json:"name"`
-		Email    *string `json:"email"`
-		Password *string `json:"password"`
+		Name         *string    `json:"name"`
+		Email        *string    `json:"email"`
+		Password     *string    `json:"password"`
+		LicensePlate *string    `json:"licensePlate"`
+		CampusID     *uuid.UUID `json:"campusID"`
 	}{}
 
 	if err := decodeJSON(req, &reqStruct); err != nil {
@@ -367,7 +692,7 @@ func (cfg *apiConfig) updateUser(res http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	if reqStruct.Name == nil && reqStruct.Email == nil && reqStruct.Password == nil {
+	if reqStruct.Name == nil && reqStruct.Email == nil && reqStruct.Password == nil && reqStruct.LicensePlate == nil && reqStruct.CampusID == nil {
 		respondWithError(res, http.StatusBadRequest, "modification request invalid")
 		return
 	}
@@ -392,11 +717,14 @@ func (cfg *apiConfig) updateUser(res http.ResponseWriter, req *http.Request) {
 		currentToModifiedUser.Name = *reqStruct.Name
 	}
 
+	emailChanged := false
+
 	if reqStruct.Email != nil {
 		if *reqStruct.Email == "" {
 			respondWithError(res, http.StatusBadRequest, "email cannot be empty")
 			return
 		}
+		emailChanged = *reqStruct.Email != currentToModifiedUser.Email
 		currentToModifiedUser.Email = *reqStruct.Email
 	}
 
@@ -415,10 +743,33 @@ func (cfg *apiConfig) updateUser(res http.ResponseWriter, req *http.Request) {
 		currentToModifiedUser.HashedPassword = hashedPassword
 	}
 
-	err = cfg.dbQueries.UpdateUser(req.Context(), database.UpdateUserParams{
+	if reqStruct.LicensePlate != nil {
+		currentToModifiedUser.LicensePlate.String = *reqStruct.LicensePlate
+		currentToModifiedUser.LicensePlate.Valid = *reqStruct.LicensePlate != ""
+	}
+
+	if reqStruct.CampusID != nil {
+		currentToModifiedUser.CampusID.UUID = *reqStruct.CampusID
+		currentToModifiedUser.CampusID.Valid = *reqStruct.CampusID != uuid.Nil
+	}
+
+	tx, err := cfg.db.BeginTx(req.Context(), nil)
+
+	if err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	defer tx.Rollback()
+
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	err = qtx.UpdateUser(req.Context(), database.UpdateUserParams{
 		Name:           currentToModifiedUser.Name,
 		Email:          currentToModifiedUser.Email,
 		HashedPassword: currentToModifiedUser.HashedPassword,
+		LicensePlate:   currentToModifiedUser.LicensePlate,
+		CampusID:       currentToModifiedUser.CampusID,
 		ID:             userID,
 	})
 
@@ -434,9 +785,68 @@ func (cfg *apiConfig) updateUser(res http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	//tokens mailed to the old address must not verify the new one
+	if emailChanged {
+		if err := qtx.DeleteUserVerificationTokens(req.Context(), userID); err != nil {
+			respondWithError(res, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		if err := qtx.UseAllUserPasswordResetTokens(req.Context(), userID); err != nil {
+			respondWithError(res, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+
+	//whoever knew the old password is logged out everywhere but in this session,
+	//which gets a new access token
+	JWT := ""
+
+	if reqStruct.Password != nil {
+		if err := cfg.revokeOtherSessions(req, qtx, userID); err != nil {
+			respondWithError(res, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		if err := qtx.BumpTokenVersion(req.Context(), userID); err != nil {
+			respondWithError(res, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		versionDB, err := qtx.GetTokenVersion(req.Context(), userID)
+
+		if err != nil {
+			respondWithError(res, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		JWT, err = cfg.jwtKeys.MakeJWT(userID, currentToModifiedUser.Role, versionDB.TokenVersion, 15*time.Minute)
+
+		if err != nil {
+			respondWithError(res, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	cfg.tokenVersions.forget(userID)
+
+	//a new email is unverified again until the new address is confirmed
+	if emailChanged {
+		if err := cfg.sendVerificationEmail(req.Context(), userID, currentToModifiedUser.Email); err != nil {
+			log.Printf("Error sending the verification email: %s", err)
+		}
+	}
+
 	responceStruct := struct {
 		Status string `json:"status"`
-	}{"The user has been modified"}
+		// only set when the password changed, the old access token stops working
+		AccessToken string `json:"access_token,omitempty"`
+	}{"The user has been modified", JWT}
 
 	respondWithJSON(res, http.StatusOK, responceStruct)
 
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Shayaan-Kashif/Database-Project/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	// failed logins allowed before an account or an ip gets locked, ips get
+	// more room since many users can share one
+	accountFailureLimit = 5
+	ipFailureLimit      = 20
+	// the first lockout lasts loginLockoutBase and doubles with every further
+	// failure, up to loginLockoutMax
+	loginLockoutBase = 30 * time.Second
+	loginLockoutMax  = time.Hour
+	// failures are forgotten once none happened for this long
+	loginFailureWindow = 24 * time.Hour
+)
+
+// accountThrottleKey normalizes the email so case changes do not get extra
+// attempts. Emails without an account are throttled the same way, so a
+// lockout does not reveal whether an email is registered.
+func accountThrottleKey(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// loginLockout is how long a key with failures failed logins stays locked, 0
+// while it is under limit.
+func loginLockout(failures, limit int32) time.Duration {
+	if failures < limit {
+		return 0
+	}
+
+	doublings := float64(failures - limit)
+	lockout := float64(loginLockoutBase) * math.Pow(2, doublings)
+
+	if lockout > float64(loginLockoutMax) {
+		return loginLockoutMax
+	}
+
+	return time.Duration(lockout)
+}
+
+// loginRetryAfter returns how long the login has to wait when the account or
+// the ip is locked, 0 if it may go ahead.
+func (cfg *apiConfig) loginRetryAfter(ctx context.Context, email, ip string) (time.Duration, error) {
+	lockouts, err := cfg.dbQueries.GetActiveLockouts(ctx, database.GetActiveLockoutsParams{
+		Account: accountThrottleKey(email),
+		Ip:      ip,
+	})
+
+	if err != nil {
+		return 0, err
+	}
+
+	wait := time.Duration(0)
+
+	for _, lockout := range lockouts {
+		wait = max(wait, time.Until(lockout.LockedUntil.Time))
+	}
+
+	return wait, nil
+}
+
+// recordLoginFailure counts a failed login against the account and the ip and
+// locks whichever went over its limit.
+func (cfg *apiConfig) recordLoginFailure(ctx context.Context, email, ip string) error {
+	keys := []struct {
+		kind  string
+		value string
+		limit int32
+	}{
+		{"account", accountThrottleKey(email), accountFailureLimit},
+		{"ip", ip, ipFailureLimit},
+	}
+
+	for _, key := range keys {
+		throttle, err := cfg.dbQueries.RecordLoginFailure(ctx, database.RecordLoginFailureParams{
+			Kind:        key.kind,
+			Value:       key.value,
+			ResetBefore: time.Now().Add(-loginFailureWindow),
+		})
+
+		if err != nil {
+			return err
+		}
+
+		lockout := loginLockout(throttle.Failures, key.limit)
+		if lockout == 0 {
+			continue
+		}
+
+		err = cfg.dbQueries.LockLogin(ctx, database.LockLoginParams{
+			Kind:        key.kind,
+			Value:       key.value,
+			LockedUntil: sql.NullTime{Time: time.Now().Add(lockout), Valid: true},
+		})
+
+		if err != nil {
+			return err
+		}
+
+		auditAfter(ctx, cfg.dbQueries, auditEntry{
+			Action:     "lockout.create",
+			TargetType: "lockout",
+			After: struct {
+				Kind        string    `json:"kind"`
+				Value       string    `json:"value"`
+				Failures    int32     `json:"failures"`
+				LockedUntil time.Time `json:"lockedUntil"`
+			}{key.kind, key.value, throttle.Failures, time.Now().Add(lockout)},
+		})
+	}
+
+	return nil
+}
+
+// respondLockedOut answers with 429 and the seconds to wait in Retry-After.
+func respondLockedOut(res http.ResponseWriter, wait time.Duration) {
+	res.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	respondWithError(res, http.StatusTooManyRequests, "too many failed logins, try again later")
+}
+
+func (cfg *apiConfig) getLockouts(res http.ResponseWriter, req *http.Request) {
+	userID := req.Context().Value(ctxUserID).(uuid.UUID)
+
+	scope, err := cfg.adminCampus(req.Context(), userID)
+	if err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if scope.Valid {
+		respondWithError(res, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	lockoutsDB, err := cfg.dbQueries.GetLockouts(req.Context())
+
+	if err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	response := make([]struct {
+		Kind          string    `json:"kind"`
+		Value         string    `json:"value"`
+		Failures      int32     `json:"failures"`
+		LockedUntil   time.Time `json:"lockedUntil"`
+		LastFailureAt time.Time `json:"lastFailureAt"`
+	}, 0, len(lockoutsDB))
+
+	for _, u := range lockoutsDB {
+		response = append(response, struct {
+			Kind          string    `json:"kind"`
+			Value         string    `json:"value"`
+			Failures      int32     `json:"failures"`
+			LockedUntil   time.Time `json:"lockedUntil"`
+			LastFailureAt time.Time `json:"lastFailureAt"`
+		}{
+			Kind:          u.Kind,
+			Value:         u.Value,
+			Failures:      u.Failures,
+			LockedUntil:   u.LockedUntil.Time,
+			LastFailureAt: u.LastFailureAt,
+		})
+	}
+
+	respondWithJSON(res, http.StatusOK, response)
+}
+
+func (cfg *apiConfig) clearLockout(res http.ResponseWriter, req *http.Request) {
+	userID := req.Context().Value(ctxUserID).(uuid.UUID)
+
+	scope, err := cfg.adminCampus(req.Context(), userID)
+	if err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if scope.Valid {
+		respondWithError(res, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	reqStruct := struct {
+		Kind  *string `json:"kind"`
+		Value *string `json:"value"`
+	}{}
+
+	if err := decodeJSON(req, &reqStruct); err != nil {
+		respondWithError(res, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if reqStruct.Kind == nil || reqStruct.Value == nil {
+		respondWithError(res, http.StatusBadRequest, "invalid JSON structure")
+		return
+	}
+
+	value := *reqStruct.Value
+	if *reqStruct.Kind == "account" {
+		value = accountThrottleKey(value)
+	}
+
+	cleared, err := cfg.dbQueries.ClearLoginThrottle(req.Context(), database.ClearLoginThrottleParams{
+		Kind:  *reqStruct.Kind,
+		Value: value,
+	})
+
+	if err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if cleared == 0 {
+		respondWithError(res, http.StatusNotFound, "no lockout exist for that kind and value")
+		return
+	}
+
+	auditAfter(req.Context(), cfg.dbQueries, auditEntry{
+		Action:     "lockout.clear",
+		TargetType: "lockout",
+		Before: struct {
+			Kind  string `json:"kind"`
+			Value string `json:"value"`
+		}{*reqStruct.Kind, value},
+	})
+
+	respondWithJSON(res, http.StatusOK, struct {
+		Status string `json:"status"`
+	}{"The lockout has been cleared"})
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLoginLockout(t *testing.T) {
+	tests := []struct {
+		name     string
+		failures int32
+		limit    int32
+		want     time.Duration
+	}{
+		{"no failures", 0, accountFailureLimit, 0},
+		{"one under the limit", accountFailureLimit - 1, accountFailureLimit, 0},
+		{"at the limit", accountFailureLimit, accountFailureLimit, 30 * time.Second},
+		{"one over", accountFailureLimit + 1, accountFailureLimit, time.Minute},
+		{"two over", accountFailureLimit + 2, accountFailureLimit, 2 * time.Minute},
+		{"last before the cap", accountFailureLimit + 6, accountFailureLimit, 32 * time.Minute},
+		{"capped", accountFailureLimit + 7, accountFailureLimit, time.Hour},
+		{"far over the cap", 10_000, accountFailureLimit, time.Hour},
+		{"ip under its limit", ipFailureLimit - 1, ipFailureLimit, 0},
+		{"ip at its limit", ipFailureLimit, ipFailureLimit, 30 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := loginLockout(tt.failures, tt.limit); got != tt.want {
+				t.Fatalf("loginLockout(%d, %d) = %s, want %s", tt.failures, tt.limit, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoginLockoutNeverShrinks(t *testing.T) {
+	previous := time.Duration(0)
+
+	for failures := int32(0); failures < 100; failures++ {
+		lockout := loginLockout(failures, accountFailureLimit)
+
+		if lockout < previous {
+			t.Fatalf("loginLockout(%d) = %s, shorter than %s for one failure less", failures, lockout, previous)
+		}
+
+		if lockout > loginLockoutMax {
+			t.Fatalf("loginLockout(%d) = %s, over the %s cap", failures, lockout, loginLockoutMax)
+		}
+
+		previous = lockout
+	}
+}
+
+func TestAccountThrottleKey(t *testing.T) {
+	if a, b := accountThrottleKey(" User@Example.com "), accountThrottleKey("user@example.com"); a != b {
+		t.Fatalf("accountThrottleKey gave %q and %q for the same email", a, b)
+	}
+}
@@ -0,0 +1,457 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Shayaan-Kashif/Database-Project/internal/database"
+	"github.com/google/uuid"
+)
+
+type lotImportRow struct {
+	Name     string     `json:"name"`
+	Slots    *int32     `json:"slots"`
+	CampusID *uuid.UUID `json:"campusID"`
+	parseErr string
+}
+
+type lotImportResult struct {
+	Row    int    `json:"row"`
+	Name   string `json:"name"`
+	Action string `json:"action"`
+	Error  string `json:"error,omitempty"`
+}
+
+var lotExportHeader = []string{"id", "name", "slots", "occupiedSlots", "campusID"}
+
+// parseLotImport reads lots in the csv or json format produced by the export.
+// Values that cannot be parsed are reported on their row instead of failing the
+// whole file.
+func parseLotImport(r io.Reader, format string) ([]lotImportRow, error) {
+	if format == "json" {
+		rawRows := []json.RawMessage{}
+		if err := json.NewDecoder(r).Decode(&rawRows); err != nil {
+			return nil, err
+		}
+
+		rows := make([]lotImportRow, 0, len(rawRows))
+
+		for _, rawRow := range rawRows {
+			row := lotImportRow{}
+			if err := json.Unmarshal(rawRow, &row); err != nil {
+				row.parseErr = lotRowJSONError(err)
+			}
+			rows = append(rows, row)
+		}
+
+		return rows, nil
+	}
+
+	if format != "csv" {
+		return nil, fmt.Errorf("incorrect format input")
+	}
+
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("error reading csv header: %w", err)
+	}
+
+	columns := map[string]int{}
+	for i, column := range header {
+		columns[strings.ToLower(strings.TrimSpace(column))] = i
+	}
+
+	nameColumn, ok := columns["name"]
+	if !ok {
+		return nil, fmt.Errorf("csv header must contain a name column")
+	}
+
+	slotsColumn, ok := columns["slots"]
+	if !ok {
+		return nil, fmt.Errorf("csv header must contain a slots column")
+	}
+
+	campusColumn, hasCampus := columns["campusid"]
+
+	field := func(record []string, i int) string {
+		if i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	rows := []lotImportRow{}
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		row := lotImportRow{Name: field(record, nameColumn)}
+
+		if slots, err := strconv.ParseInt(field(record, slotsColumn), 10, 32); err != nil {
+			row.parseErr = "slots must be a whole number"
+		} else {
+			converted := int32(slots)
+			row.Slots = &converted
+		}
+
+		if hasCampus && field(record, campusColumn) != "" {
+			if campusID, err := uuid.Parse(field(record, campusColumn)); err != nil {
+				row.parseErr = "campusID is not a valid uuid"
+			} else {
+				row.CampusID = &campusID
+			}
+		}
+
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+// lotRowJSONError is the message reported on a json row that does not decode.
+func lotRowJSONError(err error) string {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		switch typeErr.Field {
+		case "name":
+			return "name must be a string"
+		case "slots":
+			return "slots must be a whole number"
+		case "campusID":
+			return "campusID is not a valid uuid"
+		}
+	}
+
+	return "row is not a valid lot: " + err.Error()
+}
+
+// importLots upserts lots by campus and name inside one transaction. Every row
+// is validated and reported; if any row fails, or dryRun is set, nothing is
+// committed. allowedCampus restricts the import to one campus when valid.
+func importLots(ctx context.Context, db *sql.DB, queries *database.Queries, rows []lotImportRow, defaultCampus, allowedCampus uuid.NullUUID, dryRun bool) ([]lotImportResult, bool, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, false, err
+	}
+
+	defer tx.Rollback()
+
+	qtx := queries.WithTx(tx)
+
+	results := make([]lotImportResult, 0, len(rows))
+	seen := map[string]int{}
+	success := true
+
+	for i, row := range rows {
+		result := lotImportResult{Row: i + 1, Name: row.Name}
+
+		action, err := importLotRow(ctx, tx, qtx, row, defaultCampus, allowedCampus, seen, i+1)
+		if err != nil {
+			var rowErr lotRowError
+			if !errors.As(err, &rowErr) {
+				return nil, false, err
+			}
+
+			result.Action = "error"
+			result.Error = rowErr.Error()
+			success = false
+		} else {
+			result.Action = action
+		}
+
+		results = append(results, result)
+	}
+
+	if !success || dryRun {
+		return results, success, nil
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, false, err
+	}
+
+	return results, success, nil
+}
+
+type lotRowError string
+
+func (e lotRowError) Error() string {
+	return string(e)
+}
+
+func importLotRow(ctx context.Context, tx *sql.Tx, qtx *database.Queries, row lotImportRow, defaultCampus, allowedCampus uuid.NullUUID, seen map[string]int, rowNumber int) (string, error) {
+	if row.parseErr != "" {
+		return "", lotRowError(row.parseErr)
+	}
+
+	if row.Name == "" {
+		return "", lotRowError("name cannot be empty")
+	}
+
+	if row.Slots == nil {
+		return "", lotRowError("slots is required")
+	}
+
+	if *row.Slots < 0 {
+		return "", lotRowError("slots cannot be negative")
+	}
+
+	campusID := defaultCampus
+	if row.CampusID != nil {
+		campusID = uuid.NullUUID{UUID: *row.CampusID, Valid: true}
+	}
+
+	if !campusID.Valid {
+		return "", lotRowError("campusID is required")
+	}
+
+	if allowedCampus.Valid && allowedCampus.UUID != campusID.UUID {
+		return "", lotRowError("not allowed to modify lots of this campus")
+	}
+
+	key := campusID.UUID.String() + "/" + row.Name
+	if firstRow, ok := seen[key]; ok {
+		return "", lotRowError(fmt.Sprintf("duplicate of row %d", firstRow))
+	}
+	seen[key] = rowNumber
+
+	//a savepoint keeps one failing row from aborting the whole transaction
+	if _, err := tx.ExecContext(ctx, "SAVEPOINT import_row"); err != nil {
+		return "", err
+	}
+
+	action, err := upsertLot(ctx, qtx, row.Name, *row.Slots, campusID.UUID)
+
+	if err != nil {
+		if _, rollbackErr := tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT import_row"); rollbackErr != nil {
+			return "", rollbackErr
+		}
+		return "", err
+	}
+
+	if _, err := tx.ExecContext(ctx, "RELEASE SAVEPOINT import_row"); err != nil {
+		return "", err
+	}
+
+	return action, nil
+}
+
+func upsertLot(ctx context.Context, qtx *database.Queries, name string, slots int32, campusID uuid.UUID) (string, error) {
+	lotDB, err := qtx.GetParkingLotFromCampusAndName(ctx, database.GetParkingLotFromCampusAndNameParams{
+		CampusID: campusID,
+		Name:     name,
+	})
+
+	if err == sql.ErrNoRows {
+		created, err := qtx.CreateParkingLot(ctx, database.CreateParkingLotParams{
+			Name:     name,
+			Slots:    slots,
+			CampusID: campusID,
+		})
+
+		if hasPgErr, message := handlePgConstraints(err); hasPgErr {
+			return "", lotRowError(message)
+		} else if err != nil {
+			return "", err
+		}
+
+		return "created", audit(ctx, qtx, lotAuditEntry("lot.create", nil, &created))
+	} else if err != nil {
+		return "", err
+	}
+
+	if slots < lotDB.Occupiedslots {
+		return "", lotRowError("slots cannot be smaller than occupied slots")
+	}
+
+	if slots == lotDB.Slots {
+		return "unchanged", nil
+	}
+
+	err = qtx.UpdateParkingLot(ctx, database.UpdateParkingLotParams{
+		Name:  lotDB.Name,
+		Slots: slots,
+		ID:    lotDB.ID,
+	})
+
+	if hasPgErr, message := handlePgConstraints(err); hasPgErr {
+		return "", lotRowError(message)
+	} else if err != nil {
+		return "", err
+	}
+
+	updated := lotDB
+	updated.Slots = slots
+
+	return "updated", audit(ctx, qtx, lotAuditEntry("lot.update", &lotDB, &updated))
+}
+
+func writeLotExport(w io.Writer, format string, lots []database.Parkinglot) error {
+	if format == "json" {
+		response := make([]struct {
+			ID            uuid.UUID `json:"id"`
+			Name          string    `json:"name"`
+			Slots         int32     `json:"slots"`
+			OccupiedSlots int32     `json:"occupiedSlots"`
+			CampusID      uuid.UUID `json:"campusID"`
+		}, 0, len(lots))
+
+		for _, u := range lots {
+			response = append(response, struct {
+				ID            uuid.UUID `json:"id"`
+				Name          string    `json:"name"`
+				Slots         int32     `json:"slots"`
+				OccupiedSlots int32     `json:"occupiedSlots"`
+				CampusID      uuid.UUID `json:"campusID"`
+			}{
+				ID:            u.ID,
+				Name:          u.Name,
+				Slots:         u.Slots,
+				OccupiedSlots: u.Occupiedslots,
+				CampusID:      u.CampusID,
+			})
+		}
+
+		return json.NewEncoder(w).Encode(response)
+	}
+
+	writer := csv.NewWriter(w)
+
+	if err := writer.Write(lotExportHeader); err != nil {
+		return err
+	}
+
+	for _, u := range lots {
+		err := writer.Write([]string{
+			u.ID.String(),
+			u.Name,
+			strconv.Itoa(int(u.Slots)),
+			strconv.Itoa(int(u.Occupiedslots)),
+			u.CampusID.String(),
+		})
+
+		if err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// lotFormat picks csv or json from the format query parameter, falling back to
+// the request content type.
+func lotFormat(req *http.Request) string {
+	if format := req.URL.Query().Get("format"); format != "" {
+		return format
+	}
+
+	if strings.HasPrefix(req.Header.Get("Content-Type"), "text/csv") {
+		return "csv"
+	}
+
+	return "json"
+}
+
+func (cfg *apiConfig) importParkingLots(res http.ResponseWriter, req *http.Request) {
+	userID := req.Context().Value(ctxUserID).(uuid.UUID)
+
+	adminDB, err := cfg.dbQueries.GetUserFromID(req.Context(), userID)
+
+	if err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	rows, err := parseLotImport(req.Body, lotFormat(req))
+
+	if err != nil {
+		respondWithError(res, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	defaultCampus := adminDB.AdminCampusID
+	if !defaultCampus.Valid {
+		defaultCampus = adminDB.CampusID
+	}
+
+	dryRun := req.URL.Query().Get("dryRun") == "true"
+
+	results, success, err := importLots(req.Context(), cfg.db, cfg.dbQueries, rows, defaultCampus, adminDB.AdminCampusID, dryRun)
+
+	if err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	code := http.StatusOK
+	if !success {
+		code = http.StatusBadRequest
+	}
+
+	respondWithJSON(res, code, struct {
+		DryRun    bool              `json:"dryRun"`
+		Committed bool              `json:"committed"`
+		Rows      []lotImportResult `json:"rows"`
+	}{
+		DryRun:    dryRun,
+		Committed: success && !dryRun,
+		Rows:      results,
+	})
+}
+
+func (cfg *apiConfig) exportParkingLots(res http.ResponseWriter, req *http.Request) {
+	format := req.URL.Query().Get("format")
+	if format == "" {
+		format = "csv"
+	}
+
+	if format != "csv" && format != "json" {
+		respondWithError(res, http.StatusBadRequest, "incorrect format input")
+		return
+	}
+
+	campusID, err := cfg.adminCampusFilter(req)
+
+	if err != nil {
+		respondWithError(res, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	lotsDB, err := cfg.dbQueries.GetParkingLots(req.Context(), campusID)
+
+	if err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if format == "csv" {
+		res.Header().Set("Content-Type", "text/csv")
+	} else {
+		res.Header().Set("Content-Type", "application/json")
+	}
+	res.Header().Set("Content-Disposition", "attachment; filename=parkingLots."+format)
+	res.WriteHeader(http.StatusOK)
+
+	if err := writeLotExport(res, format, lotsDB); err != nil {
+		log.Printf("Error exporting parking lots: %s", err)
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseLotImportJSONRowErrors(t *testing.T) {
+	input := `[
+		{"name": "Founders 1", "slots": 10},
+		{"name": "Founders 2", "slots": "10"},
+		{"name": "Founders 3", "slots": 12.5},
+		{"name": "Founders 4", "slots": 10, "campusID": 7},
+		{"name": "Founders 5", "slots": 10, "campusID": "nope"},
+		"Founders 6",
+		{"name": "Founders 7", "slots": 20}
+	]`
+
+	rows, err := parseLotImport(strings.NewReader(input), "json")
+	if err != nil {
+		t.Fatalf("parseLotImport returned %v, want per row errors", err)
+	}
+
+	want := []struct {
+		name     string
+		parseErr string
+	}{
+		{"Founders 1", ""},
+		{"Founders 2", "slots must be a whole number"},
+		{"Founders 3", "slots must be a whole number"},
+		{"Founders 4", "campusID is not a valid uuid"},
+		{"Founders 5", "row is not a valid lot: "},
+		{"", "row is not a valid lot: "},
+		{"Founders 7", ""},
+	}
+
+	if len(rows) != len(want) {
+		t.Fatalf("got %d rows, want %d", len(rows), len(want))
+	}
+
+	for i, w := range want {
+		row := rows[i]
+
+		if row.Name != w.name {
+			t.Errorf("row %d name = %q, want %q", i+1, row.Name, w.name)
+		}
+
+		if !strings.HasPrefix(row.parseErr, w.parseErr) || (w.parseErr == "") != (row.parseErr == "") {
+			t.Errorf("row %d error = %q, want %q", i+1, row.parseErr, w.parseErr)
+		}
+	}
+
+	if rows[6].Slots == nil || *rows[6].Slots != 20 {
+		t.Errorf("row 7 slots = %v, want 20", rows[6].Slots)
+	}
+}
+
+func TestParseLotImportJSONNotAnArray(t *testing.T) {
+	if _, err := parseLotImport(strings.NewReader(`{"name": "Founders 1"}`), "json"); err == nil {
+		t.Fatal("parseLotImport accepted a json object, want an error")
+	}
+}
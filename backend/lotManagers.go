@@ -0,0 +1,219 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/Shayaan-Kashif/Database-Project/internal/database"
+	"github.com/google/uuid"
+)
+
+// respondWithManagedLots lists the lots userID manages as a lot manager.
+func (cfg *apiConfig) respondWithManagedLots(res http.ResponseWriter, req *http.Request, userID uuid.UUID) {
+	lotsDB, err := cfg.dbQueries.GetManagedLots(req.Context(), userID)
+
+	if err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	response := make([]struct {
+		ID            uuid.UUID `json:"id"`
+		Name          string    `json:"name"`
+		Slots         int32     `json:"slots"`
+		Occupiedslots int32     `json:"ocupiedSlots"`
+		CampusID      uuid.UUID `json:"campusID"`
+	}, 0, len(lotsDB))
+
+	for _, u := range lotsDB {
+		response = append(response, struct {
+			ID            uuid.UUID `json:"id"`
+			Name          string    `json:"name"`
+			Slots         int32     `json:"slots"`
+			Occupiedslots int32     `json:"ocupiedSlots"`
+			CampusID      uuid.UUID `json:"campusID"`
+		}{
+			ID:            u.ID,
+			Name:          u.Name,
+			Slots:         u.Slots,
+			Occupiedslots: u.Occupiedslots,
+			CampusID:      u.CampusID,
+		})
+	}
+
+	respondWithJSON(res, http.StatusOK, response)
+}
+
+func (cfg *apiConfig) getMyManagedLots(res http.ResponseWriter, req *http.Request) {
+	userID := req.Context().Value(ctxUserID).(uuid.UUID)
+	cfg.respondWithManagedLots(res, req, userID)
+}
+
+func (cfg *apiConfig) getManagedLots(res http.ResponseWriter, req *http.Request) {
+	if userDB, ok := cfg.managedUser(res, req); ok {
+		cfg.respondWithManagedLots(res, req, userDB.ID)
+	}
+}
+
+// setManagedLots replaces the lots a user manages. Users get the lot_manager
+// role with their first lot and go back to user when the list is emptied.
+func (cfg *apiConfig) setManagedLots(res http.ResponseWriter, req *http.Request) {
+	managed, ok := cfg.managedUser(res, req)
+	if !ok {
+		return
+	}
+
+	userID := managed.ID
+
+	reqStruct := struct {
+		LotIDs *[]uuid.UUID `json:"lotIDs"`
+	}{}
+
+	if err := decodeJSON(req, &reqStruct); err != nil {
+		respondWithError(res, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if reqStruct.LotIDs == nil {
+		respondWithError(res, http.StatusBadRequest, "invalid JSON structure")
+		return
+	}
+
+	for _, lotID := range *reqStruct.LotIDs {
+		lotDB, err := cfg.dbQueries.GetParkingLotFromID(req.Context(), lotID)
+
+		if err == sql.ErrNoRows {
+			respondWithError(res, http.StatusBadRequest, "no parking lot exist for lotID "+lotID.String())
+			return
+		} else if err != nil {
+			respondWithError(res, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		canManage, err := cfg.canManageCampus(req, lotDB.CampusID)
+
+		if err != nil {
+			respondWithError(res, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		if !canManage {
+			respondWithError(res, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+	}
+
+	tx, err := cfg.db.BeginTx(req.Context(), nil)
+
+	if err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	defer tx.Rollback()
+
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	userDB, err := qtx.GetUserFromID(req.Context(), userID)
+
+	if err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	//admins and enforcement officers would lose their own role
+	if userDB.Role != "user" && userDB.Role != "lot_manager" {
+		respondWithError(res, http.StatusConflict, "only users and lot managers can be given lots")
+		return
+	}
+
+	beforeLots, err := qtx.GetManagedLots(req.Context(), userID)
+
+	if err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if err := qtx.DeleteManagedLots(req.Context(), userID); err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	for _, lotID := range *reqStruct.LotIDs {
+		err := qtx.AddManagedLot(req.Context(), database.AddManagedLotParams{
+			UserID:       userID,
+			ParkingLotID: lotID,
+		})
+
+		if err != nil {
+			hasPgErr, message := handlePgConstraints(err)
+
+			if hasPgErr {
+				respondWithError(res, http.StatusBadRequest, message)
+				return
+			}
+
+			respondWithError(res, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+
+	role := "lot_manager"
+	if len(*reqStruct.LotIDs) == 0 {
+		role = "user"
+	}
+
+	_, err = qtx.UpdateUserRole(req.Context(), database.UpdateUserRoleParams{
+		Role: role,
+		ID:   userID,
+	})
+
+	if err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	//lots are checked against the database, only the role is in the access token
+	if role != userDB.Role {
+		if err := qtx.BumpTokenVersion(req.Context(), userID); err != nil {
+			respondWithError(res, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+
+	entry, err := userAuditEntry(req.Context(), qtx, "user.managed_lots", userDB)
+
+	if err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	lotIDs := make([]uuid.UUID, 0, len(beforeLots))
+	for _, l := range beforeLots {
+		lotIDs = append(lotIDs, l.ID)
+	}
+
+	entry.Before = struct {
+		userAudit
+		LotIDs []uuid.UUID `json:"lotIDs"`
+	}{entry.Before.(userAudit), lotIDs}
+
+	entry.After = struct {
+		userAudit
+		LotIDs []uuid.UUID `json:"lotIDs"`
+	}{entry.After.(userAudit), *reqStruct.LotIDs}
+
+	if err := audit(req.Context(), qtx, entry); err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	cfg.tokenVersions.forget(userID)
+
+	cfg.respondWithManagedLots(res, req, userID)
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"embed"
+	"fmt"
+
+	"github.com/pressly/goose/v3"
+)
+
+//go:embed sql/schema/*.sql
+var schemaMigrations embed.FS
+
+const schemaDir = "sql/schema"
+
+// useMigrations points goose at the migrations embedded in the binary, or at
+// dir on disk when it is set. It returns the directory goose should read.
+func useMigrations(dir string) (string, error) {
+	if err := goose.SetDialect("postgres"); err != nil {
+		return "", err
+	}
+
+	if dir != "" {
+		goose.SetBaseFS(nil)
+		return dir, nil
+	}
+
+	goose.SetBaseFS(schemaMigrations)
+	return schemaDir, nil
+}
+
+// latestSchemaVersion is the version of the newest migration shipped in the
+// binary.
+func latestSchemaVersion() (int64, error) {
+	if _, err := useMigrations(""); err != nil {
+		return 0, err
+	}
+
+	migrations, err := goose.CollectMigrations(schemaDir, 0, goose.MaxVersion)
+	if err != nil {
+		return 0, err
+	}
+
+	last, err := migrations.Last()
+	if err != nil {
+		return 0, err
+	}
+
+	return last.Version, nil
+}
+
+// checkSchema makes sure the database is at the schema version the binary was
+// built for, applying pending migrations first when migrate is set.
+func (cfg *apiConfig) checkSchema(migrate bool) error {
+	dir, err := useMigrations("")
+	if err != nil {
+		return err
+	}
+
+	if migrate {
+		if err := goose.Up(cfg.db, dir); err != nil {
+			return err
+		}
+	}
+
+	current, err := goose.GetDBVersion(cfg.db)
+	if err != nil {
+		return err
+	}
+
+	latest, err := latestSchemaVersion()
+	if err != nil {
+		return err
+	}
+
+	if current < latest {
+		return fmt.Errorf("database schema is at version %d but the server needs %d, run `migrate up` or start with --migrate", current, latest)
+	}
+
+	return nil
+}
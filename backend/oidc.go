@@ -0,0 +1,440 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/Shayaan-Kashif/Database-Project/internal/auth"
+	"github.com/Shayaan-Kashif/Database-Project/internal/database"
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+)
+
+// time the user has to login at the identity provider
+const oidcStateTTL = 10 * time.Minute
+
+// oidcClient is the campus identity provider used for single sign-on.
+type oidcClient struct {
+	issuer   string
+	verifier *oidc.IDTokenVerifier
+	oauth2   oauth2.Config
+}
+
+// ssoClaims are the claims of the ID token the user is linked with.
+type ssoClaims struct {
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+	Name          string `json:"name"`
+}
+
+var errSSOEmailUnverified = errors.New("the campus account has no verified email")
+
+// setupOIDC discovers the identity provider of OIDC_ISSUER. Single sign-on is
+// off while OIDC_ISSUER is empty. OIDC_CLIENT_SECRET can be left empty for
+// public clients, the login uses PKCE either way.
+func (cfg *apiConfig) setupOIDC(ctx context.Context) error {
+	issuer := os.Getenv("OIDC_ISSUER")
+	if issuer == "" {
+		return nil
+	}
+
+	clientID := os.Getenv("OIDC_CLIENT_ID")
+	if clientID == "" {
+		return errors.New("OIDC_CLIENT_ID is required when OIDC_ISSUER is set")
+	}
+
+	redirectURL := os.Getenv("OIDC_REDIRECT_URL")
+	if redirectURL == "" {
+		redirectURL = "http://localhost:8080/api/auth/oidc/callback"
+	}
+
+	provider, err := oidc.NewProvider(ctx, issuer)
+	if err != nil {
+		return fmt.Errorf("discovering the OIDC provider %s: %w", issuer, err)
+	}
+
+	cfg.oidc = &oidcClient{
+		issuer:   issuer,
+		verifier: provider.Verifier(&oidc.Config{ClientID: clientID}),
+		oauth2: oauth2.Config{
+			ClientID:     clientID,
+			ClientSecret: os.Getenv("OIDC_CLIENT_SECRET"),
+			Endpoint:     provider.Endpoint(),
+			RedirectURL:  redirectURL,
+			Scopes:       []string{oidc.ScopeOpenID, "email", "profile"},
+		},
+	}
+
+	return nil
+}
+
+func (cfg *apiConfig) getAuthMethods(res http.ResponseWriter, req *http.Request) {
+	respondWithJSON(res, http.StatusOK, struct {
+		Password bool `json:"password"`
+		SSO      bool `json:"sso"`
+	}{
+		Password: !cfg.passwordLoginDisabled,
+		SSO:      cfg.oidc != nil,
+	})
+}
+
+// passwordLoginAllowed answers the password endpoints with 403 while
+// PASSWORD_LOGIN_DISABLED is set, ok is false if it did.
+func (cfg *apiConfig) passwordLoginAllowed(res http.ResponseWriter) bool {
+	if cfg.passwordLoginDisabled {
+		respondWithError(res, http.StatusForbidden, "password login is disabled, use campus SSO")
+		return false
+	}
+
+	return true
+}
+
+func setOIDCStateCookie(res http.ResponseWriter, state string, maxAge int) {
+	http.SetCookie(res, &http.Cookie{
+		Name:     "oidc_state",
+		Value:    state,
+		HttpOnly: true,
+		Secure:   false, //only for dev side, true for production
+		SameSite: http.SameSiteLaxMode,
+		Path:     "/api/auth/oidc",
+		MaxAge:   maxAge,
+	})
+}
+
+// ssoFailed sends the browser back to the login page with the reason, the
+// callback is a redirect from the identity provider so JSON errors would only
+// be shown raw.
+func ssoFailed(res http.ResponseWriter, req *http.Request, reason string) {
+	http.Redirect(res, req, appURL()+"/login?ssoError="+url.QueryEscape(reason), http.StatusFound)
+}
+
+func (cfg *apiConfig) oidcLogin(res http.ResponseWriter, req *http.Request) {
+	if cfg.oidc == nil {
+		respondWithError(res, http.StatusNotFound, "single sign-on is not configured")
+		return
+	}
+
+	state, err := auth.MakeRefreshToken()
+	if err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	nonce, err := auth.MakeRefreshToken()
+	if err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	verifier := oauth2.GenerateVerifier()
+
+	err = cfg.dbQueries.CreateOIDCLoginState(req.Context(), database.CreateOIDCLoginStateParams{
+		StateHash:    auth.HashToken(state),
+		CodeVerifier: verifier,
+		Nonce:        nonce,
+		ExpiresAt:    time.Now().Add(oidcStateTTL),
+	})
+
+	if err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	//the callback only accepts the state together with this cookie, so a login
+	//started in another browser cannot be finished in this one
+	setOIDCStateCookie(res, state, int(oidcStateTTL.Seconds()))
+
+	authURL := cfg.oidc.oauth2.AuthCodeURL(state, oidc.Nonce(nonce), oauth2.S256ChallengeOption(verifier))
+	http.Redirect(res, req, authURL, http.StatusFound)
+}
+
+func (cfg *apiConfig) oidcCallback(res http.ResponseWriter, req *http.Request) {
+	if cfg.oidc == nil {
+		respondWithError(res, http.StatusNotFound, "single sign-on is not configured")
+		return
+	}
+
+	query := req.URL.Query()
+
+	if query.Get("error") != "" {
+		reason := query.Get("error_description")
+		if reason == "" {
+			reason = query.Get("error")
+		}
+
+		ssoFailed(res, req, reason)
+		return
+	}
+
+	cookie, err := req.Cookie("oidc_state")
+	setOIDCStateCookie(res, "", -1)
+
+	if err != nil || cookie.Value != query.Get("state") {
+		ssoFailed(res, req, "login expired, try again")
+		return
+	}
+
+	loginState, err := cfg.dbQueries.UseOIDCLoginState(req.Context(), auth.HashToken(cookie.Value))
+
+	if err == sql.ErrNoRows {
+		ssoFailed(res, req, "login expired, try again")
+		return
+	} else if err != nil {
+		log.Printf("Error reading the OIDC login state: %s", err)
+		ssoFailed(res, req, "single sign-on failed")
+		return
+	}
+
+	token, err := cfg.oidc.oauth2.Exchange(req.Context(), query.Get("code"), oauth2.VerifierOption(loginState.CodeVerifier))
+
+	if err != nil {
+		log.Printf("Error exchanging the OIDC code: %s", err)
+		ssoFailed(res, req, "single sign-on failed")
+		return
+	}
+
+	rawIDToken, ok := token.Extra("id_token").(string)
+
+	if !ok {
+		log.Printf("Error finishing single sign-on: no id_token in the token response")
+		ssoFailed(res, req, "single sign-on failed")
+		return
+	}
+
+	idToken, err := cfg.oidc.verifier.Verify(req.Context(), rawIDToken)
+
+	if err != nil || idToken.Nonce != loginState.Nonce {
+		log.Printf("Error verifying the OIDC id_token: %v", err)
+		ssoFailed(res, req, "single sign-on failed")
+		return
+	}
+
+	claims := ssoClaims{}
+
+	if err := idToken.Claims(&claims); err != nil {
+		log.Printf("Error reading the OIDC claims: %s", err)
+		ssoFailed(res, req, "single sign-on failed")
+		return
+	}
+
+	userDB, err := cfg.ssoUser(req.Context(), idToken.Subject, claims)
+
+	if errors.Is(err, errSSOEmailUnverified) {
+		ssoFailed(res, req, err.Error())
+		return
+	} else if err != nil {
+		log.Printf("Error linking the campus account: %s", err)
+		ssoFailed(res, req, "single sign-on failed")
+		return
+	}
+
+	if userDB.DisabledAt.Valid {
+		ssoFailed(res, req, "account disabled")
+		return
+	}
+
+	//the campus login replaces the password, not the second factor
+	if needsSecondFactor(userDB) {
+		mfaToken, err := cfg.createLoginChallenge(req.Context(), userDB.ID)
+
+		if err != nil {
+			log.Printf("Error creating the login challenge: %s", err)
+			ssoFailed(res, req, "single sign-on failed")
+			return
+		}
+
+		//a cookie only /api/login/totp gets, the url would leave the token in
+		//the browser history and the logs of every proxy on the way
+		setMFACookie(res, mfaToken)
+
+		next := url.Values{}
+		next.Set("mfaRequired", "true")
+		next.Set("enrollmentRequired", fmt.Sprint(!userDB.TotpEnabledAt.Valid))
+
+		http.Redirect(res, req, appURL()+"/login?"+next.Encode(), http.StatusFound)
+		return
+	}
+
+	//the frontend gets the access token by refreshing with the cookie
+	_, refreshToken, err := cfg.createSession(req, userDB)
+
+	if err != nil {
+		log.Printf("Error creating the session: %s", err)
+		ssoFailed(res, req, "single sign-on failed")
+		return
+	}
+
+	setRefreshCookie(res, refreshToken)
+	http.Redirect(res, req, appURL()+"/dashboard", http.StatusFound)
+}
+
+// ssoUser returns the user linked to the campus account. The first login links
+// the account to the user with the same verified email, or creates one.
+func (cfg *apiConfig) ssoUser(ctx context.Context, subject string, claims ssoClaims) (database.User, error) {
+	tx, err := cfg.db.BeginTx(ctx, nil)
+
+	if err != nil {
+		return database.User{}, err
+	}
+
+	defer tx.Rollback()
+
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	userDB, err := qtx.GetUserFromIdentity(ctx, database.GetUserFromIdentityParams{
+		Issuer:  cfg.oidc.issuer,
+		Subject: subject,
+	})
+
+	if err == nil {
+		err = qtx.TouchUserIdentity(ctx, database.TouchUserIdentityParams{
+			Issuer:  cfg.oidc.issuer,
+			Subject: subject,
+			Email:   claims.Email,
+		})
+
+		if err != nil {
+			return database.User{}, err
+		}
+
+		return userDB, tx.Commit()
+	} else if err != sql.ErrNoRows {
+		return database.User{}, err
+	}
+
+	//an email the provider did not verify could belong to anyone
+	if claims.Email == "" || !claims.EmailVerified {
+		return database.User{}, errSSOEmailUnverified
+	}
+
+	//nobody knows this password, users that want one can set it with /api/password/forgot
+	password, err := auth.MakeRefreshToken()
+	if err != nil {
+		return database.User{}, err
+	}
+
+	hashedPassword, err := auth.Hashpassword(password)
+	if err != nil {
+		return database.User{}, err
+	}
+
+	userDB, err = qtx.GetUserFromEmail(ctx, claims.Email)
+	created := err == sql.ErrNoRows
+
+	if created {
+		name := claims.Name
+		if name == "" {
+			name = claims.Email
+		}
+
+		newUser, err := qtx.CreateUser(ctx, database.CreateUserParams{
+			Name:           name,
+			Email:          claims.Email,
+			HashedPassword: hashedPassword,
+			Role:           "user",
+		})
+
+		if err != nil {
+			return database.User{}, err
+		}
+
+		if err := qtx.MarkEmailVerified(ctx, newUser.ID); err != nil {
+			return database.User{}, err
+		}
+
+		userDB.ID = newUser.ID
+	} else if err != nil {
+		return database.User{}, err
+	} else if !userDB.EmailVerifiedAt.Valid {
+		//whoever signed up with the email never proved it is theirs, the campus
+		//account did. they lose the password and their sessions
+		err = qtx.UpdateUserPassword(ctx, database.UpdateUserPasswordParams{
+			HashedPassword: hashedPassword,
+			ID:             userDB.ID,
+		})
+
+		if err != nil {
+			return database.User{}, err
+		}
+
+		if err := qtx.RevokeAllUserTokens(ctx, userDB.ID); err != nil {
+			return database.User{}, err
+		}
+
+		if err := qtx.BumpTokenVersion(ctx, userDB.ID); err != nil {
+			return database.User{}, err
+		}
+
+		if err := qtx.MarkEmailVerified(ctx, userDB.ID); err != nil {
+			return database.User{}, err
+		}
+
+		err = audit(actorContext(ctx, userDB.ID, userDB.Role), qtx, auditEntry{
+			Action:     "user.sso_password_reset",
+			TargetType: "user",
+			TargetID:   userDB.ID,
+			CampusID:   userDB.CampusID,
+		})
+
+		if err != nil {
+			return database.User{}, err
+		}
+	}
+
+	err = qtx.CreateUserIdentity(ctx, database.CreateUserIdentityParams{
+		Issuer:  cfg.oidc.issuer,
+		Subject: subject,
+		UserID:  userDB.ID,
+		Email:   claims.Email,
+	})
+
+	if err != nil {
+		return database.User{}, err
+	}
+
+	//read again for the changes above
+	userDB, err = qtx.GetUserFromID(ctx, userDB.ID)
+	if err != nil {
+		return database.User{}, err
+	}
+
+	identity := struct {
+		Issuer  string `json:"issuer"`
+		Subject string `json:"subject"`
+	}{cfg.oidc.issuer, subject}
+
+	entry := auditEntry{
+		Action:     "user.sso_link",
+		TargetType: "user",
+		TargetID:   userDB.ID,
+		CampusID:   userDB.CampusID,
+		After:      identity,
+	}
+
+	if created {
+		entry.Action = "user.sso_create"
+		entry.After = struct {
+			userAudit
+			Identity any `json:"identity"`
+		}{toUserAudit(userDB), identity}
+	}
+
+	if err := audit(actorContext(ctx, userDB.ID, userDB.Role), qtx, entry); err != nil {
+		return database.User{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return database.User{}, err
+	}
+
+	cfg.tokenVersions.forget(userDB.ID)
+
+	return userDB, nil
+}
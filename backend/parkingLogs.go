@@ -10,22 +10,29 @@ import (
 
 func (cfg *apiConfig) park(res http.ResponseWriter, req *http.Request) {
 	userID := req.Context().Value(ctxUserID).(uuid.UUID)
-	userData, err := cfg.dbQueries.GetUserFromID(req.Context(), userID)
 
+	tx, err := cfg.db.BeginTx(req.Context(), nil)
 	if err != nil {
 		respondWithError(res, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	tx, err := cfg.db.BeginTx(req.Context(), nil)
+	defer tx.Rollback()
+
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	//locked until commit so an admin's force exit cannot exit the user twice
+	userData, err := qtx.GetUserFromIDForUpdate(req.Context(), userID)
+
 	if err != nil {
 		respondWithError(res, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	defer tx.Rollback()
-
-	qtx := cfg.dbQueries.WithTx(tx)
+	if !userData.EmailVerifiedAt.Valid {
+		respondWithError(res, http.StatusForbidden, "email not verified")
+		return
+	}
 
 	requestStruct := struct {
 		ParkinglotID *uuid.UUID `json:"parkingLotID"`
@@ -113,7 +120,7 @@ func (cfg *apiConfig) park(res http.ResponseWriter, req *http.Request) {
 
 	//log data
 	_, err = qtx.CreateLog(req.Context(), database.CreateLogParams{
-		UserID:       userData.ID,
+		UserID:       uuid.NullUUID{UUID: userData.ID, Valid: true},
 		ParkingLotID: *requestStruct.ParkinglotID,
 		EventType:    *requestStruct.Type,
 	})
@@ -147,7 +154,7 @@ func (cfg *apiConfig) park(res http.ResponseWriter, req *http.Request) {
 func (cfg *apiConfig) getParkingLogsFromUserID(res http.ResponseWriter, req *http.Request) {
 	userID := req.Context().Value(ctxUserID).(uuid.UUID)
 
-	parkingLogsDB, err := cfg.dbQueries.GetLogsFromUserID(req.Context(), userID)
+	parkingLogsDB, err := cfg.dbQueries.GetLogsFromUserID(req.Context(), uuid.NullUUID{UUID: userID, Valid: true})
 
 	if err != nil {
 		respondWithError(res, http.StatusInternalServerError, err.Error())
@@ -155,20 +162,20 @@ func (cfg *apiConfig) getParkingLogsFromUserID(res http.ResponseWriter, req *htt
 	}
 
 	response := make([]struct {
-		ID           uuid.UUID `json:"id"`
-		UserID       uuid.UUID `json:"userID"`
-		ParkingLotID uuid.UUID `json:"parkingLotID"`
-		EventType    string    `json:"eventType"`
-		Time         time.Time `json:"time"`
+		ID           uuid.UUID     `json:"id"`
+		UserID       uuid.NullUUID `json:"userID"`
+		ParkingLotID uuid.UUID     `json:"parkingLotID"`
+		EventType    string        `json:"eventType"`
+		Time         time.Time     `json:"time"`
 	}, 0, len(parkingLogsDB))
 
 	for _, u := range parkingLogsDB {
 		response = append(response, struct {
-			ID           uuid.UUID `json:"id"`
-			UserID       uuid.UUID `json:"userID"`
-			ParkingLotID uuid.UUID `json:"parkingLotID"`
-			EventType    string    `json:"eventType"`
-			Time         time.Time `json:"time"`
+			ID           uuid.UUID     `json:"id"`
+			UserID       uuid.NullUUID `json:"userID"`
+			ParkingLotID uuid.UUID     `json:"parkingLotID"`
+			EventType    string        `json:"eventType"`
+			Time         time.Time     `json:"time"`
 		}{
 			ID:           u.ID,
 			UserID:       u.UserID,
@@ -182,14 +189,14 @@ func (cfg *apiConfig) getParkingLogsFromUserID(res http.ResponseWriter, req *htt
 }
 
 func (cfg *apiConfig) getAllParkingLogs(res http.ResponseWriter, req *http.Request) {
-	role := req.Context().Value(ctxRole).(string)
+	campusID, err := cfg.adminCampusFilter(req)
 
-	if role != "admin" {
-		respondWithError(res, http.StatusUnauthorized, "Unauthorized")
+	if err != nil {
+		respondWithError(res, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	parkingLogsDB, err := cfg.dbQueries.GetLogs(req.Context())
+	parkingLogsDB, err := cfg.dbQueries.GetLogs(req.Context(), campusID)
 
 	if err != nil {
 		respondWithError(res, http.StatusInternalServerError, err.Error())
@@ -197,20 +204,20 @@ func (cfg *apiConfig) getAllParkingLogs(res http.ResponseWriter, req *http.Reque
 	}
 
 	response := make([]struct {
-		ID           uuid.UUID `json:"id"`
-		UserID       uuid.UUID `json:"userID"`
-		ParkingLotID uuid.UUID `json:"parkingLotID"`
-		EventType    string    `json:"eventType"`
-		Time         time.Time `json:"time"`
+		ID           uuid.UUID     `json:"id"`
+		UserID       uuid.NullUUID `json:"userID"`
+		ParkingLotID uuid.UUID     `json:"parkingLotID"`
+		EventType    string        `json:"eventType"`
+		Time         time.Time     `json:"time"`
 	}, 0, len(parkingLogsDB))
 
 	for _, u := range parkingLogsDB {
 		response = append(response, struct {
-			ID           uuid.UUID `json:"id"`
-			UserID       uuid.UUID `json:"userID"`
-			ParkingLotID uuid.UUID `json:"parkingLotID"`
-			EventType    string    `json:"eventType"`
-			Time         time.Time `json:"time"`
+			ID           uuid.UUID     `json:"id"`
+			UserID       uuid.NullUUID `json:"userID"`
+			ParkingLotID uuid.UUID     `json:"parkingLotID"`
+			EventType    string        `json:"eventType"`
+			Time         time.Time     `json:"time"`
 		}{
 			ID:           u.ID,
 			UserID:       u.UserID,
@@ -231,7 +238,8 @@ func (cfg *apiConfig) getParkingHistory(res http.ResponseWriter, req *http.Reque
 		return
 	}
 
-	logs, err := cfg.dbQueries.GetLogsFromLotID(req.Context(), lotID)
+	//days are counted in the time zone of the lot's campus by the view
+	days, err := cfg.dbQueries.GetDailyEntriesFromLotID(req.Context(), lotID)
 
 	if err != nil {
 		respondWithError(res, http.StatusInternalServerError, err.Error())
@@ -241,28 +249,16 @@ func (cfg *apiConfig) getParkingHistory(res http.ResponseWriter, req *http.Reque
 	response := make([]struct {
 		Date    string `json:"date"`
 		Entries int    `json:"entries"`
-	}, 0)
-
-	lastDate := ""
-
-	for _, u := range logs {
-		if u.EventType != "entry" {
-			continue
-		}
+	}, 0, len(days))
 
-		if u.Time.Format("2006-01-02") == lastDate {
-			response[len(response)-1].Entries += 1
-		} else {
-			response = append(response, struct {
-				Date    string `json:"date"`
-				Entries int    `json:"entries"`
-			}{
-				Date:    u.Time.Format("2006-01-02"),
-				Entries: 1,
-			})
-
-			lastDate = u.Time.Format("2006-01-02")
-		}
+	for _, u := range days {
+		response = append(response, struct {
+			Date    string `json:"date"`
+			Entries int    `json:"entries"`
+		}{
+			Date:    u.Day.Format("2006-01-02"),
+			Entries: int(u.Totalentries),
+		})
 	}
 
 	respondWithJSON(res, http.StatusOK, response)
@@ -9,7 +9,14 @@ import (
 )
 
 func (cfg *apiConfig) getParkingLots(res http.ResponseWriter, req *http.Request) {
-	parkingLotDB, err := cfg.dbQueries.GetParkingLots(req.Context())
+	campusID, err := campusFilter(req)
+
+	if err != nil {
+		respondWithError(res, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	parkingLotDB, err := cfg.dbQueries.GetParkingLots(req.Context(), campusID)
 
 	if err != nil {
 		respondWithError(res, http.StatusInternalServerError, err.Error())
@@ -21,6 +28,7 @@ func (cfg *apiConfig) getParkingLots(res http.ResponseWriter, req *http.Request)
 		Name          string    `json:"name"`
 		Slots         int32     `json:"slots"`
 		Occupiedslots int32     `json:"ocupiedSlots"`
+		CampusID      uuid.UUID `json:"campusID"`
 	}, 0, len(parkingLotDB))
 
 	for _, u := range parkingLotDB {
@@ -29,11 +37,13 @@ func (cfg *apiConfig) getParkingLots(res http.ResponseWriter, req *http.Request)
 			Name          string    `json:"name"`
 			Slots         int32     `json:"slots"`
 			Occupiedslots int32     `json:"ocupiedSlots"`
+			CampusID      uuid.UUID `json:"campusID"`
 		}{
 			ID:            u.ID,
 			Name:          u.Name,
 			Slots:         u.Slots,
 			Occupiedslots: u.Occupiedslots,
+			CampusID:      u.CampusID,
 		})
 	}
 
@@ -43,8 +53,9 @@ func (cfg *apiConfig) getParkingLots(res http.ResponseWriter, req *http.Request)
 
 func (cfg *apiConfig) createParkingLot(res http.ResponseWriter, req *http.Request) {
 	reqStruct := struct {
-		Name  *string `json:"name"`
-		Slots *int32  `json:"slots"`
+		Name     *string    `json:"name"`
+		Slots    *int32     `json:"slots"`
+		CampusID *uuid.UUID `json:"campusID"`
 	}{}
 
 	if err := decodeJSON(req, &reqStruct); err != nil {
@@ -57,16 +68,48 @@ func (cfg *apiConfig) createParkingLot(res http.ResponseWriter, req *http.Reques
 		return
 	}
 
-	role := req.Context().Value(ctxRole).(string)
+	userID := req.Context().Value(ctxUserID).(uuid.UUID)
 
-	if role != "admin" {
-		respondWithError(res, http.StatusUnauthorized, "Unauthorized")
+	adminDB, err := cfg.dbQueries.GetUserFromID(req.Context(), userID)
+
+	if err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	//campus admins create lots on their campus, other admins default to their home campus
+	campusID := adminDB.AdminCampusID
+
+	if reqStruct.CampusID != nil {
+		if campusID.Valid && campusID.UUID != *reqStruct.CampusID {
+			respondWithError(res, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		campusID = uuid.NullUUID{UUID: *reqStruct.CampusID, Valid: true}
+	} else if !campusID.Valid {
+		campusID = adminDB.CampusID
+	}
+
+	if !campusID.Valid {
+		respondWithError(res, http.StatusBadRequest, "campusID is required")
+		return
+	}
+
+	tx, err := cfg.db.BeginTx(req.Context(), nil)
+
+	if err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	parkingLotDBEntry, err := cfg.dbQueries.CreateParkingLot(req.Context(), database.CreateParkingLotParams{
-		Name:  *reqStruct.Name,
-		Slots: *reqStruct.Slots,
+	defer tx.Rollback()
+
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	parkingLotDBEntry, err := qtx.CreateParkingLot(req.Context(), database.CreateParkingLotParams{
+		Name:     *reqStruct.Name,
+		Slots:    *reqStruct.Slots,
+		CampusID: campusID.UUID,
 	})
 
 	if err != nil {
@@ -82,16 +125,28 @@ func (cfg *apiConfig) createParkingLot(res http.ResponseWriter, req *http.Reques
 
 	}
 
+	if err := audit(req.Context(), qtx, lotAuditEntry("lot.create", nil, &parkingLotDBEntry)); err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
 	responseStruct := struct {
 		ID            uuid.UUID `json:"id"`
 		Name          string    `json:"name"`
 		Slots         int32     `json:"slots"`
 		Occupiedslots int32     `json:"occupiedSlots"`
+		CampusID      uuid.UUID `json:"campusID"`
 	}{
 		ID:            parkingLotDBEntry.ID,
 		Name:          parkingLotDBEntry.Name,
 		Slots:         parkingLotDBEntry.Slots,
 		Occupiedslots: parkingLotDBEntry.Occupiedslots,
+		CampusID:      parkingLotDBEntry.CampusID,
 	}
 
 	respondWithJSON(res, http.StatusCreated, responseStruct)
@@ -121,32 +176,69 @@ func (cfg *apiConfig) getParkingLotFromID(res http.ResponseWriter, req *http.Req
 		Name          string    `json:"name"`
 		Slots         int32     `json:"slots"`
 		Occupiedslots int32     `json:"ocupiedSlots"`
+		CampusID      uuid.UUID `json:"campusID"`
 	}{
 		ID:            parkingLotDB.ID,
 		Name:          parkingLotDB.Name,
 		Slots:         parkingLotDB.Slots,
 		Occupiedslots: parkingLotDB.Occupiedslots,
+		CampusID:      parkingLotDB.CampusID,
 	}
 
 	respondWithJSON(res, http.StatusOK, response)
 }
 
 func (cfg *apiConfig) deleteParkingLot(res http.ResponseWriter, req *http.Request) {
-	role := req.Context().Value(ctxRole).(string)
+	lotID, err := uuid.Parse(req.PathValue("lotID"))
+
+	if err != nil {
+		respondWithError(res, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	lotDB, err := cfg.dbQueries.GetParkingLotFromID(req.Context(), lotID)
+
+	if err == sql.ErrNoRows {
+		respondWithError(res, http.StatusNotFound, "No lot with this ID was found")
+		return
+	} else if err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	canManage, err := cfg.canManageCampus(req, lotDB.CampusID)
+
+	if err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
 
-	if role != "admin" {
+	if !canManage {
 		respondWithError(res, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
 
-	lotID, err := uuid.Parse(req.PathValue("lotID"))
+	tx, err := cfg.db.BeginTx(req.Context(), nil)
 
 	if err != nil {
-		respondWithError(res, http.StatusBadRequest, err.Error())
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	defer tx.Rollback()
+
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	//the logs, reviews and citations of the lot are deleted with it, the audit
+	//log keeps how many there were
+	removed, err := qtx.CountParkingLotDependents(req.Context(), lotID)
+
+	if err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	sqlResult, err := cfg.dbQueries.DeleteParkingLot(req.Context(), lotID)
+	sqlResult, err := qtx.DeleteParkingLot(req.Context(), lotID)
 
 	if err != nil {
 		respondWithError(res, http.StatusInternalServerError, err.Error())
@@ -159,6 +251,29 @@ func (cfg *apiConfig) deleteParkingLot(res http.ResponseWriter, req *http.Reques
 		return
 	}
 
+	entry := lotAuditEntry("lot.delete", &lotDB, nil)
+	entry.Before = struct {
+		lotAudit
+		DeletedLogs      int64 `json:"deletedLogs"`
+		DeletedReviews   int64 `json:"deletedReviews"`
+		DeletedCitations int64 `json:"deletedCitations"`
+	}{
+		lotAudit:         toLotAudit(lotDB),
+		DeletedLogs:      removed.Logs,
+		DeletedReviews:   removed.Reviews,
+		DeletedCitations: removed.Citations,
+	}
+
+	if err := audit(req.Context(), qtx, entry); err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
 	responseStruct := struct {
 		Status string `json:"status"`
 	}{"The parking lot has been deleted"}
@@ -167,13 +282,6 @@ func (cfg *apiConfig) deleteParkingLot(res http.ResponseWriter, req *http.Reques
 }
 
 func (cfg *apiConfig) updateParkingLot(res http.ResponseWriter, req *http.Request) {
-	role := req.Context().Value(ctxRole).(string)
-
-	if role != "admin" {
-		respondWithError(res, http.StatusUnauthorized, "Unauthorized")
-		return
-	}
-
 	lotID, err := uuid.Parse(req.PathValue("lotID"))
 
 	if err != nil {
@@ -208,6 +316,20 @@ func (cfg *apiConfig) updateParkingLot(res http.ResponseWriter, req *http.Reques
 		return
 	}
 
+	canManage, err := cfg.canManageLot(req, currentToModifiedLot)
+
+	if err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if !canManage {
+		respondWithError(res, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	before := currentToModifiedLot
+
 	if reqStruct.Name != nil {
 		if *reqStruct.Name == "" {
 			respondWithError(res, http.StatusBadRequest, "name cannot be empty")
@@ -224,7 +346,18 @@ func (cfg *apiConfig) updateParkingLot(res http.ResponseWriter, req *http.Reques
 		currentToModifiedLot.Slots = *reqStruct.Slots
 	}
 
-	err = cfg.dbQueries.UpdateParkingLot(req.Context(), database.UpdateParkingLotParams{
+	tx, err := cfg.db.BeginTx(req.Context(), nil)
+
+	if err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	defer tx.Rollback()
+
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	err = qtx.UpdateParkingLot(req.Context(), database.UpdateParkingLotParams{
 		Name:  currentToModifiedLot.Name,
 		Slots: currentToModifiedLot.Slots,
 		ID:    lotID,
@@ -242,6 +375,16 @@ func (cfg *apiConfig) updateParkingLot(res http.ResponseWriter, req *http.Reques
 		return
 	}
 
+	if err := audit(req.Context(), qtx, lotAuditEntry("lot.update", &before, &currentToModifiedLot)); err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
 	responceStruct := struct {
 		Status string `json:"status"`
 	}{"The parking lot has been modified"}
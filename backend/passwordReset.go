@@ -0,0 +1,206 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/Shayaan-Kashif/Database-Project/internal/auth"
+	"github.com/Shayaan-Kashif/Database-Project/internal/database"
+	"github.com/Shayaan-Kashif/Database-Project/internal/mail"
+)
+
+const (
+	passwordResetTokenTTL = time.Hour
+	// at most this many reset emails are sent to one account per
+	// passwordResetWindow
+	passwordResetLimit  = 3
+	passwordResetWindow = time.Hour
+)
+
+// sendPasswordReset emails a reset token if email has an account and has not
+// hit the rate limit. Nothing is reported back, callers cannot tell the cases
+// apart.
+func (cfg *apiConfig) sendPasswordReset(ctx context.Context, email string) error {
+	userDB, err := cfg.dbQueries.GetUserFromEmail(ctx, email)
+	if err == sql.ErrNoRows {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	recent, err := cfg.dbQueries.CountRecentPasswordResets(ctx, database.CountRecentPasswordResetsParams{
+		UserID:    userDB.ID,
+		CreatedAt: time.Now().Add(-passwordResetWindow),
+	})
+
+	if err != nil {
+		return err
+	}
+
+	if recent >= passwordResetLimit {
+		log.Printf("password reset rate limit reached for %s", userDB.ID)
+		return nil
+	}
+
+	token, err := auth.MakeRefreshToken()
+	if err != nil {
+		return err
+	}
+
+	err = cfg.dbQueries.CreatePasswordResetToken(ctx, database.CreatePasswordResetTokenParams{
+		TokenHash: auth.HashToken(token),
+		UserID:    userDB.ID,
+		ExpiresAt: time.Now().Add(passwordResetTokenTTL),
+	})
+
+	if err != nil {
+		return err
+	}
+
+	return cfg.mail.Send(ctx, mail.Message{
+		To:      userDB.Email,
+		Subject: "Reset your ParkingGO password",
+		Body: fmt.Sprintf(
+			"Someone asked to reset the password of your ParkingGO account.\n\nChoose a new password within the next hour:\n%s/resetPassword?token=%s\n\nOr use this code: %s\n\nIf it was not you, ignore this email, your password stays the same.\n",
+			appURL(), url.QueryEscape(token), token,
+		),
+	})
+}
+
+func (cfg *apiConfig) forgotPassword(res http.ResponseWriter, req *http.Request) {
+	if !cfg.passwordLoginAllowed(res) {
+		return
+	}
+
+	reqStruct := struct {
+		Email *string `json:"email"`
+	}{}
+
+	if err := decodeJSON(req, &reqStruct); err != nil {
+		respondWithError(res, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if reqStruct.Email == nil || *reqStruct.Email == "" {
+		respondWithError(res, http.StatusBadRequest, "invalid JSON structure")
+		return
+	}
+
+	//sent in the background so the response time does not tell whether the email has an account
+	go func(email string) {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		if err := cfg.sendPasswordReset(ctx, email); err != nil {
+			log.Printf("Error sending the password reset email: %s", err)
+		}
+	}(*reqStruct.Email)
+
+	respondWithJSON(res, http.StatusOK, struct {
+		Status string `json:"status"`
+	}{"If the email has an account, a password reset email has been sent"})
+}
+
+func (cfg *apiConfig) resetPassword(res http.ResponseWriter, req *http.Request) {
+	if !cfg.passwordLoginAllowed(res) {
+		return
+	}
+
+	reqStruct := struct {
+		Token    *string `json:"token"`
+		Password *string `json:"password"`
+	}{}
+
+	if err := decodeJSON(req, &reqStruct); err != nil {
+		respondWithError(res, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if reqStruct.Token == nil || reqStruct.Password == nil {
+		respondWithError(res, http.StatusBadRequest, "invalid JSON structure")
+		return
+	}
+
+	if *reqStruct.Password == "" {
+		respondWithError(res, http.StatusBadRequest, "password cannot be empty")
+		return
+	}
+
+	hashedPassword, err := auth.Hashpassword(*reqStruct.Password)
+
+	if err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	tx, err := cfg.db.BeginTx(req.Context(), nil)
+
+	if err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	defer tx.Rollback()
+
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	//marking the token used in the same statement that checks it keeps it single use
+	userID, err := qtx.UsePasswordResetToken(req.Context(), auth.HashToken(*reqStruct.Token))
+
+	if err == sql.ErrNoRows {
+		respondWithError(res, http.StatusBadRequest, "invalid or expired token")
+		return
+	} else if err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	err = qtx.UpdateUserPassword(req.Context(), database.UpdateUserPasswordParams{
+		HashedPassword: hashedPassword,
+		ID:             userID,
+	})
+
+	if err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	//other emailed reset links stop working once one of them was used
+	if err := qtx.UseAllUserPasswordResetTokens(req.Context(), userID); err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	//whoever knew the old password is logged out everywhere
+	if err := qtx.RevokeAllUserTokens(req.Context(), userID); err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if err := qtx.BumpTokenVersion(req.Context(), userID); err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	//the reset link proved the user can read emails sent to the address
+	if err := qtx.MarkEmailVerified(req.Context(), userID); err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	cfg.tokenVersions.forget(userID)
+
+	respondWithJSON(res, http.StatusOK, struct {
+		Status string `json:"status"`
+	}{"The password has been reset"})
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"net/http"
+	"slices"
+
+	"github.com/Shayaan-Kashif/Database-Project/internal/database"
+	"github.com/google/uuid"
+)
+
+// permission is an action a role can be granted. Which campus or lots it
+// covers is still up to the handler, see canManageCampus and canManageLot.
+type permission string
+
+const (
+	permLotsCreate       permission = "lots:create"
+	permLotsUpdate       permission = "lots:update"
+	permLotsDelete       permission = "lots:delete"
+	permLotsImport       permission = "lots:import"
+	permLotsExport       permission = "lots:export"
+	permReviewsModerate  permission = "reviews:moderate"
+	permLogsReadAll      permission = "logs:read_all"
+	permLogsExport       permission = "logs:export"
+	permUsersReadAll     permission = "users:read_all"
+	permUsersManage      permission = "users:manage"
+	permUsersInvite      permission = "users:invite"
+	permCitationsIssue   permission = "citations:issue"
+	permCitationsReadAll permission = "citations:read_all"
+	permCitationsResolve permission = "citations:resolve"
+	permWalletAdjust     permission = "wallet:adjust"
+	permCampusesManage   permission = "campuses:manage"
+	permLockoutsManage   permission = "lockouts:manage"
+	permAPIKeysManage    permission = "apikeys:manage"
+	permAuditRead        permission = "audit:read"
+)
+
+// rolePermissions is every permission of each role, roles that are not listed
+// have none.
+var rolePermissions = map[string][]permission{
+	"admin": {
+		permLotsCreate, permLotsUpdate, permLotsDelete, permLotsImport, permLotsExport,
+		permReviewsModerate,
+		permLogsReadAll, permLogsExport,
+		permUsersReadAll, permUsersManage, permUsersInvite,
+		permCitationsIssue, permCitationsReadAll, permCitationsResolve,
+		permWalletAdjust,
+		permCampusesManage,
+		permLockoutsManage,
+		permAPIKeysManage,
+		permAuditRead,
+	},
+	"user":        {},
+	"enforcement": {permCitationsIssue, permCitationsReadAll},
+	// only the lots in lot_managers
+	"lot_manager": {permLotsUpdate},
+}
+
+func hasPermission(role string, perm permission) bool {
+	return slices.Contains(rolePermissions[role], perm)
+}
+
+// authorize is authMiddleWare for routes that need perm, users whose role does
+// not have it get 401. API keys are accepted too if perm is one of their
+// scopes.
+func (cfg *apiConfig) authorize(perm permission, next http.HandlerFunc) http.Handler {
+	return cfg.authenticate(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		role := req.Context().Value(ctxRole).(string)
+
+		if !hasPermission(role, perm) {
+			respondWithError(res, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+
+		if scopes, isAPIKey := req.Context().Value(ctxAPIKeyScopes).([]permission); isAPIKey && !slices.Contains(scopes, perm) {
+			respondWithError(res, http.StatusForbidden, "the API key does not have the "+string(perm)+" scope")
+			return
+		}
+
+		next(res, req)
+	}), true)
+}
+
+// canManageLot reports whether the user making the request may modify lotDB.
+// Lot managers only manage the lots assigned to them, admins every lot of the
+// campuses they manage.
+func (cfg *apiConfig) canManageLot(req *http.Request, lotDB database.Parkinglot) (bool, error) {
+	role := req.Context().Value(ctxRole).(string)
+
+	if role == "lot_manager" {
+		userID := req.Context().Value(ctxUserID).(uuid.UUID)
+
+		return cfg.dbQueries.IsLotManager(req.Context(), database.IsLotManagerParams{
+			UserID:       userID,
+			ParkingLotID: lotDB.ID,
+		})
+	}
+
+	return cfg.canManageCampus(req, lotDB.CampusID)
+}
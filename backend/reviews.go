@@ -3,6 +3,7 @@ package main
 import (
 	"database/sql"
 	"net/http"
+	"time"
 
 	"github.com/Shayaan-Kashif/Database-Project/internal/database"
 	"github.com/google/uuid"
@@ -33,7 +34,19 @@ func (cfg *apiConfig) CreateReview(res http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	_, err := cfg.dbQueries.CreateReview(req.Context(), database.CreateReviewParams{
+	userDB, err := cfg.dbQueries.GetUserFromID(req.Context(), userID)
+
+	if err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if !userDB.EmailVerifiedAt.Valid {
+		respondWithError(res, http.StatusForbidden, "email not verified")
+		return
+	}
+
+	_, err = cfg.dbQueries.CreateReview(req.Context(), database.CreateReviewParams{
 		UserID:       userID,
 		ParkingLotID: *reqStruct.ParkingLotID,
 		Title:        *reqStruct.Title,
@@ -162,17 +175,62 @@ func (cfg *apiConfig) DeleteReview(res http.ResponseWriter, req *http.Request) {
 	}
 
 	role := req.Context().Value(ctxRole).(string)
+	userID := req.Context().Value(ctxUserID).(uuid.UUID)
 
-	if role != "admin" {
-		userID := req.Context().Value(ctxUserID).(uuid.UUID)
-
+	if !hasPermission(role, permReviewsModerate) {
 		if userID != *reqStruct.UserID {
 			respondWithError(res, http.StatusUnauthorized, "Unauthorized")
 			return
 		}
+	} else {
+		lotDB, err := cfg.dbQueries.GetParkingLotFromID(req.Context(), *reqStruct.LotID)
+
+		if err == sql.ErrNoRows {
+			respondWithError(res, http.StatusNotFound, "No review with this id was found")
+			return
+		} else if err != nil {
+			respondWithError(res, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		canManage, err := cfg.canManageCampus(req, lotDB.CampusID)
+
+		if err != nil {
+			respondWithError(res, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		if !canManage {
+			respondWithError(res, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+	}
+
+	tx, err := cfg.db.BeginTx(req.Context(), nil)
+
+	if err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
 	}
 
-	sqlResult, err := cfg.dbQueries.DeleteReview(req.Context(), database.DeleteReviewParams{
+	defer tx.Rollback()
+
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	reviewDB, err := qtx.GetReviewByID(req.Context(), database.GetReviewByIDParams{
+		UserID:       *reqStruct.UserID,
+		ParkingLotID: *reqStruct.LotID,
+	})
+
+	if err == sql.ErrNoRows {
+		respondWithError(res, http.StatusNotFound, "No review with this id was found")
+		return
+	} else if err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	sqlResult, err := qtx.DeleteReview(req.Context(), database.DeleteReviewParams{
 		UserID:       *reqStruct.UserID,
 		ParkingLotID: *reqStruct.LotID,
 	})
@@ -188,6 +246,48 @@ func (cfg *apiConfig) DeleteReview(res http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	//users deleting their own review is not an admin action
+	if userID != reviewDB.UserID {
+		lotDB, err := qtx.GetParkingLotFromID(req.Context(), reviewDB.ParkingLotID)
+
+		if err != nil {
+			respondWithError(res, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		err = audit(req.Context(), qtx, auditEntry{
+			Action:     "review.delete",
+			TargetType: "review",
+			TargetID:   reviewDB.UserID,
+			CampusID:   uuid.NullUUID{UUID: lotDB.CampusID, Valid: true},
+			Before: struct {
+				UserID       uuid.UUID `json:"userID"`
+				ParkingLotID uuid.UUID `json:"parkingLotID"`
+				Title        string    `json:"title"`
+				Description  string    `json:"description"`
+				Score        int32     `json:"score"`
+				CreatedAt    time.Time `json:"createdAt"`
+			}{
+				UserID:       reviewDB.UserID,
+				ParkingLotID: reviewDB.ParkingLotID,
+				Title:        reviewDB.Title,
+				Description:  reviewDB.Description.String,
+				Score:        reviewDB.Score,
+				CreatedAt:    reviewDB.CreatedAt,
+			},
+		})
+
+		if err != nil {
+			respondWithError(res, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
 	responseStruct := struct {
 		Status string `json:"status"`
 	}{"The review has been deleted"}
@@ -9,20 +9,32 @@ import (
 	"log"
 	"net/http"
 	"os"
+	// campus time zones have to load on hosts without zoneinfo files
+	_ "time/tzdata"
 
 	"github.com/Shayaan-Kashif/Database-Project/internal/auth"
 	"github.com/Shayaan-Kashif/Database-Project/internal/database"
+	"github.com/Shayaan-Kashif/Database-Project/internal/mail"
+	"github.com/Shayaan-Kashif/Database-Project/internal/payments"
+	"github.com/google/uuid"
 	"github.com/jackc/pgconn"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/stdlib"
 	"github.com/joho/godotenv"
+	"github.com/pressly/goose/v3"
 )
 
 type apiConfig struct {
 	dbQueries *database.Queries
-	JWTSecret string
-	adminCode string
+	jwtKeys   *auth.JWTKeys
 	db        *sql.DB
+	payments  payments.Provider
+	mail      mail.Sender
+	// nil while single sign-on is not configured
+	oidc                  *oidcClient
+	passwordLoginDisabled bool
+	apiKeyLimiter         *rateLimiter
+	tokenVersions         *tokenVersions
 }
 
 type ctxkey string
@@ -30,6 +42,10 @@ type ctxkey string
 const (
 	ctxUserID ctxkey = "userID"
 	ctxRole   ctxkey = "role"
+	// the scopes of the API key the request was made with, unset for users
+	ctxAPIKeyScopes ctxkey = "apiKeyScopes"
+	// auditSource of the request, see audit
+	ctxAuditSource ctxkey = "auditSource"
 )
 
 func main() {
@@ -51,55 +67,130 @@ func main() {
 
 	apiConfig := apiConfig{
 		dbQueries: database.New(db),
-		JWTSecret: os.Getenv("JWTSecret"),
-		adminCode: os.Getenv("ADMINCODE"),
 		db:        db,
+		payments:  payments.StubProvider{},
+		mail:      mailSender(),
+
+		passwordLoginDisabled: os.Getenv("PASSWORD_LOGIN_DISABLED") == "true",
+		apiKeyLimiter:         newRateLimiter(apiKeyRateLimit, apiKeyRateWindow),
+		tokenVersions:         newTokenVersions(),
+	}
+
+	trustedProxies, err = parseTrustedProxies(os.Getenv("TRUSTED_PROXIES"))
+	if err != nil {
+		log.Fatalf("Failed to parse TRUSTED_PROXIES: %v", err)
 	}
 
+	if err := apiConfig.runCommand(os.Args[1:]); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// serve registers every route and blocks running the http server.
+func (cfg *apiConfig) serve() error {
 	serverMux := http.NewServeMux()
 
 	server := http.Server{
 		Addr:    ":8080",
-		Handler: withCORS(serverMux),
+		Handler: withCORS(withAuditSource(serverMux)),
 	}
 
-	serverMux.HandleFunc("GET /api/health", readiness)
-	serverMux.HandleFunc("POST /api/testDB", apiConfig.testDB)
-	serverMux.HandleFunc("POST /api/users", apiConfig.signUp)
-	serverMux.HandleFunc("POST /api/login", apiConfig.login)
-	serverMux.HandleFunc("POST /api/logout", apiConfig.logout)
-	serverMux.Handle("GET /api/user", apiConfig.authMiddleWare(http.HandlerFunc(apiConfig.getUserFromID)))
-	serverMux.Handle("GET /api/users", apiConfig.authMiddleWare(http.HandlerFunc(apiConfig.getAllUsers)))
-	serverMux.HandleFunc("POST /api/refresh", apiConfig.refresh)
-	serverMux.HandleFunc("GET /api/parkingLots", apiConfig.getParkingLots)
-	serverMux.HandleFunc("GET /api/parkingLots/{lotID}", apiConfig.getParkingLotFromID)
-	serverMux.Handle("POST /api/parkingLots", apiConfig.authMiddleWare(http.HandlerFunc(apiConfig.createParkingLot)))
-	serverMux.Handle("POST /api/reviews", apiConfig.authMiddleWare(http.HandlerFunc(apiConfig.CreateReview)))
-	serverMux.Handle("PATCH /api/reviews/{lotID}", apiConfig.authMiddleWare(http.HandlerFunc(apiConfig.ModifyReview)))
-	serverMux.Handle("DELETE /api/reviews", apiConfig.authMiddleWare(http.HandlerFunc(apiConfig.DeleteReview)))
-	serverMux.HandleFunc("GET /api/reviews/{lotID}", apiConfig.getReviewsFromLotID)
-	serverMux.HandleFunc("GET /api/topRatedLots", apiConfig.getTopRatedLots)
-	serverMux.Handle("GET /api/avgTimeParked", apiConfig.authMiddleWare(http.HandlerFunc(apiConfig.getAvgTimeParkedFromUserID)))
-	serverMux.HandleFunc("GET /api/countOfLogsPerUser", apiConfig.getCountOfLogsPerUser)
-	serverMux.Handle("GET /api/highestLowestRatings", apiConfig.authMiddleWare(http.HandlerFunc(apiConfig.getHighestLowestRatingsFromUserID)))
-	serverMux.HandleFunc("GET /api/averageLotRating/{lotID}", apiConfig.getAverageLotRatingFromID)
-	serverMux.HandleFunc("GET /api/countOfReviewsPerUser", apiConfig.getCountOfReviewsPerUser)
-	serverMux.HandleFunc("GET /api/countOfReviewsPerLot", apiConfig.getCountOfReviewsPerLot)
-	serverMux.HandleFunc("GET /api/countOfLogsPerLot", apiConfig.getCountOfLogsPerLot)
-	serverMux.HandleFunc("GET /api/fullLots", apiConfig.getFullLots)
-	serverMux.Handle("POST /api/park", apiConfig.authMiddleWare(http.HandlerFunc(apiConfig.park)))
-	serverMux.Handle("GET /api/parkingLogs", apiConfig.authMiddleWare(http.HandlerFunc(apiConfig.getParkingLogsFromUserID)))
-	serverMux.Handle("GET /api/parkingLogsAll", apiConfig.authMiddleWare(http.HandlerFunc(apiConfig.getAllParkingLogs)))
-	serverMux.HandleFunc("GET /api/parkingHistory/{lotID}", apiConfig.getParkingHistory)
-	serverMux.Handle("DELETE /api/user", apiConfig.authMiddleWare(http.HandlerFunc(apiConfig.deleteUser)))
-	serverMux.Handle("PATCH /api/user", apiConfig.authMiddleWare(http.HandlerFunc(apiConfig.updateUser)))
-	serverMux.Handle("DELETE /api/parkingLots/{lotID}", apiConfig.authMiddleWare(http.HandlerFunc(apiConfig.deleteParkingLot)))
-	serverMux.Handle("PATCH /api/parkingLots/{lotID}", apiConfig.authMiddleWare(http.HandlerFunc(apiConfig.updateParkingLot)))
+	serverMux.HandleFunc("GET /api/health", cfg.readiness)
+	serverMux.HandleFunc("POST /api/testDB", cfg.testDB)
+	serverMux.HandleFunc("POST /api/users", cfg.signUp)
+	serverMux.HandleFunc("POST /api/users/verify", cfg.verifyEmail)
+	serverMux.HandleFunc("POST /api/users/verify/resend", cfg.resendVerification)
+	serverMux.HandleFunc("POST /api/password/forgot", cfg.forgotPassword)
+	serverMux.HandleFunc("POST /api/password/reset", cfg.resetPassword)
+	serverMux.HandleFunc("POST /api/login", cfg.login)
+	serverMux.HandleFunc("POST /api/login/totp", cfg.loginTOTP)
+	serverMux.HandleFunc("POST /api/login/totp/enroll", cfg.loginEnrollTOTP)
+	serverMux.HandleFunc("POST /api/logout", cfg.logout)
+	serverMux.HandleFunc("GET /.well-known/jwks.json", cfg.getJWKS)
+	serverMux.HandleFunc("GET /api/auth/methods", cfg.getAuthMethods)
+	serverMux.HandleFunc("GET /api/auth/oidc/login", cfg.oidcLogin)
+	serverMux.HandleFunc("GET /api/auth/oidc/callback", cfg.oidcCallback)
+	serverMux.Handle("GET /api/user", cfg.authMiddleWare(http.HandlerFunc(cfg.getUserFromID)))
+	serverMux.Handle("GET /api/users", cfg.authorize(permUsersReadAll, cfg.getAllUsers))
+	serverMux.Handle("GET /api/user/totp", cfg.authMiddleWare(http.HandlerFunc(cfg.getTOTPStatus)))
+	serverMux.Handle("POST /api/user/totp", cfg.authMiddleWare(http.HandlerFunc(cfg.enrollTOTP)))
+	serverMux.Handle("POST /api/user/totp/verify", cfg.authMiddleWare(http.HandlerFunc(cfg.verifyTOTP)))
+	serverMux.Handle("DELETE /api/user/totp", cfg.authMiddleWare(http.HandlerFunc(cfg.disableTOTP)))
+	serverMux.Handle("POST /api/user/totp/recoveryCodes", cfg.authMiddleWare(http.HandlerFunc(cfg.regenerateRecoveryCodes)))
+	serverMux.Handle("GET /api/user/sessions", cfg.authMiddleWare(http.HandlerFunc(cfg.getSessions)))
+	serverMux.Handle("DELETE /api/user/sessions", cfg.authMiddleWare(http.HandlerFunc(cfg.deleteAllSessions)))
+	serverMux.Handle("DELETE /api/user/sessions/{sessionID}", cfg.authMiddleWare(http.HandlerFunc(cfg.deleteSession)))
+	serverMux.Handle("GET /api/user/managedLots", cfg.authMiddleWare(http.HandlerFunc(cfg.getMyManagedLots)))
+	serverMux.Handle("GET /api/users/{userID}/sessions", cfg.authorize(permUsersManage, cfg.getUserSessions))
+	serverMux.Handle("DELETE /api/users/{userID}/sessions", cfg.authorize(permUsersManage, cfg.deleteAllUserSessions))
+	serverMux.Handle("DELETE /api/users/{userID}/sessions/{sessionID}", cfg.authorize(permUsersManage, cfg.deleteUserSession))
+	serverMux.Handle("GET /api/users/{userID}/managedLots", cfg.authorize(permUsersManage, cfg.getManagedLots))
+	serverMux.Handle("PUT /api/users/{userID}/managedLots", cfg.authorize(permUsersManage, cfg.setManagedLots))
+	serverMux.Handle("PATCH /api/users/{userID}/role", cfg.authorize(permUsersManage, cfg.updateUserRole))
+	serverMux.Handle("POST /api/users/{userID}/disable", cfg.authorize(permUsersManage, cfg.disableUser))
+	serverMux.Handle("POST /api/users/{userID}/enable", cfg.authorize(permUsersManage, cfg.enableUser))
+	serverMux.Handle("POST /api/users/{userID}/forceExit", cfg.authorize(permUsersManage, cfg.forceExitUser))
+	serverMux.Handle("POST /api/users/{userID}/resetPassword", cfg.authorize(permUsersManage, cfg.adminResetPassword))
+	serverMux.Handle("DELETE /api/users/{userID}", cfg.authorize(permUsersManage, cfg.adminDeleteUser))
+	serverMux.HandleFunc("POST /api/refresh", cfg.refresh)
+	serverMux.HandleFunc("GET /api/parkingLots", cfg.getParkingLots)
+	serverMux.HandleFunc("GET /api/parkingLots/{lotID}", cfg.getParkingLotFromID)
+	serverMux.Handle("POST /api/parkingLots", cfg.authorize(permLotsCreate, cfg.createParkingLot))
+	serverMux.Handle("POST /api/reviews", cfg.authMiddleWare(http.HandlerFunc(cfg.CreateReview)))
+	serverMux.Handle("PATCH /api/reviews/{lotID}", cfg.authMiddleWare(http.HandlerFunc(cfg.ModifyReview)))
+	serverMux.Handle("DELETE /api/reviews", cfg.authMiddleWare(http.HandlerFunc(cfg.DeleteReview)))
+	serverMux.HandleFunc("GET /api/reviews/{lotID}", cfg.getReviewsFromLotID)
+	serverMux.HandleFunc("GET /api/topRatedLots", cfg.getTopRatedLots)
+	serverMux.Handle("GET /api/avgTimeParked", cfg.authMiddleWare(http.HandlerFunc(cfg.getAvgTimeParkedFromUserID)))
+	serverMux.HandleFunc("GET /api/countOfLogsPerUser", cfg.getCountOfLogsPerUser)
+	serverMux.Handle("GET /api/highestLowestRatings", cfg.authMiddleWare(http.HandlerFunc(cfg.getHighestLowestRatingsFromUserID)))
+	serverMux.HandleFunc("GET /api/averageLotRating/{lotID}", cfg.getAverageLotRatingFromID)
+	serverMux.HandleFunc("GET /api/countOfReviewsPerUser", cfg.getCountOfReviewsPerUser)
+	serverMux.HandleFunc("GET /api/countOfReviewsPerLot", cfg.getCountOfReviewsPerLot)
+	serverMux.HandleFunc("GET /api/countOfLogsPerLot", cfg.getCountOfLogsPerLot)
+	serverMux.HandleFunc("GET /api/fullLots", cfg.getFullLots)
+	serverMux.Handle("POST /api/park", cfg.authMiddleWare(http.HandlerFunc(cfg.park)))
+	serverMux.Handle("GET /api/parkingLogs", cfg.authMiddleWare(http.HandlerFunc(cfg.getParkingLogsFromUserID)))
+	serverMux.Handle("GET /api/parkingLogsAll", cfg.authorize(permLogsReadAll, cfg.getAllParkingLogs))
+	serverMux.HandleFunc("GET /api/parkingHistory/{lotID}", cfg.getParkingHistory)
+	serverMux.Handle("DELETE /api/user", cfg.authMiddleWare(http.HandlerFunc(cfg.deleteUser)))
+	serverMux.Handle("POST /api/user/deletion/cancel", cfg.authMiddleWare(http.HandlerFunc(cfg.cancelAccountDeletion)))
+	serverMux.Handle("GET /api/user/export", cfg.authMiddleWare(http.HandlerFunc(cfg.exportUser)))
+	serverMux.Handle("PATCH /api/user", cfg.authMiddleWare(http.HandlerFunc(cfg.updateUser)))
+	serverMux.Handle("DELETE /api/parkingLots/{lotID}", cfg.authorize(permLotsDelete, cfg.deleteParkingLot))
+	serverMux.Handle("PATCH /api/parkingLots/{lotID}", cfg.authorize(permLotsUpdate, cfg.updateParkingLot))
+	serverMux.Handle("GET /api/wallet", cfg.authMiddleWare(http.HandlerFunc(cfg.getWallet)))
+	serverMux.Handle("GET /api/wallet/transactions", cfg.authMiddleWare(http.HandlerFunc(cfg.getWalletTransactions)))
+	serverMux.Handle("POST /api/wallet/topup", cfg.authMiddleWare(http.HandlerFunc(cfg.topUpWallet)))
+	serverMux.Handle("POST /api/wallet/transactions", cfg.authorize(permWalletAdjust, cfg.createWalletTransaction))
+	serverMux.Handle("POST /api/citations", cfg.authorize(permCitationsIssue, cfg.createCitation))
+	serverMux.Handle("GET /api/citations", cfg.authMiddleWare(http.HandlerFunc(cfg.getCitationsFromUserID)))
+	serverMux.Handle("GET /api/citationsAll", cfg.authorize(permCitationsReadAll, cfg.getAllCitations))
+	serverMux.Handle("GET /api/citations/{citationID}", cfg.authMiddleWare(http.HandlerFunc(cfg.getCitationFromID)))
+	serverMux.Handle("POST /api/citations/{citationID}/appeal", cfg.authMiddleWare(http.HandlerFunc(cfg.appealCitation)))
+	serverMux.Handle("POST /api/citations/{citationID}/resolve", cfg.authorize(permCitationsResolve, cfg.resolveCitation))
+	serverMux.Handle("GET /api/enforcement/check", cfg.authorize(permCitationsIssue, cfg.checkParkingStatus))
+	serverMux.HandleFunc("GET /api/campuses", cfg.getCampuses)
+	serverMux.Handle("POST /api/campuses", cfg.authorize(permCampusesManage, cfg.createCampus))
+	serverMux.Handle("PATCH /api/campuses/{campusID}", cfg.authorize(permCampusesManage, cfg.updateCampusTimeZone))
+	serverMux.Handle("POST /api/campuses/{campusID}/admins", cfg.authorize(permCampusesManage, cfg.assignCampusAdmin))
+	serverMux.Handle("POST /api/admin/parkingLots/import", cfg.authorize(permLotsImport, cfg.importParkingLots))
+	serverMux.Handle("GET /api/admin/parkingLots/export", cfg.authorize(permLotsExport, cfg.exportParkingLots))
+	serverMux.Handle("GET /api/admin/lockouts", cfg.authorize(permLockoutsManage, cfg.getLockouts))
+	serverMux.Handle("DELETE /api/admin/lockouts", cfg.authorize(permLockoutsManage, cfg.clearLockout))
+	serverMux.Handle("POST /api/admin/invitations", cfg.authorize(permUsersInvite, cfg.createAdminInvitation))
+	serverMux.Handle("GET /api/admin/invitations", cfg.authorize(permUsersInvite, cfg.getAdminInvitations))
+	serverMux.Handle("DELETE /api/admin/invitations/{invitationID}", cfg.authorize(permUsersInvite, cfg.revokeAdminInvitation))
+	serverMux.Handle("POST /api/admin/apiKeys", cfg.authorize(permAPIKeysManage, cfg.createAPIKey))
+	serverMux.Handle("GET /api/admin/apiKeys", cfg.authorize(permAPIKeysManage, cfg.getAPIKeys))
+	serverMux.Handle("DELETE /api/admin/apiKeys/{keyID}", cfg.authorize(permAPIKeysManage, cfg.revokeAPIKey))
+	serverMux.Handle("GET /api/admin/parkingLogs/export", cfg.authorize(permLogsExport, cfg.exportParkingLogs))
+	serverMux.Handle("GET /api/admin/auditLog", cfg.authorize(permAuditRead, cfg.getAuditLog))
+	serverMux.Handle("GET /api/admin/auditLog/export", cfg.authorize(permAuditRead, cfg.exportAuditLog))
 
 	fmt.Println("server is running on http://localhost:8080")
 
-	server.ListenAndServe()
-
+	return server.ListenAndServe()
 }
 
 func respondWithError(res http.ResponseWriter, code int, msg string) {
@@ -143,10 +234,18 @@ func decodeJSON[T any](req *http.Request, insert *T) error {
 	return nil
 }
 
-func readiness(res http.ResponseWriter, req *http.Request) {
+func (cfg *apiConfig) readiness(res http.ResponseWriter, req *http.Request) {
+	version, err := goose.GetDBVersionContext(req.Context(), cfg.db)
+
+	if err != nil {
+		respondWithError(res, http.StatusServiceUnavailable, fmt.Sprintf("db error: %v", err))
+		return
+	}
+
 	status := struct {
-		Status string `json:"status"`
-	}{Status: "the server is running fine"}
+		Status        string `json:"status"`
+		SchemaVersion int64  `json:"schemaVersion"`
+	}{Status: "the server is running fine", SchemaVersion: version}
 	respondWithJSON(res, 200, status)
 }
 
@@ -182,7 +281,13 @@ func withCORS(next http.Handler) http.Handler {
 	})
 }
 
+// authMiddleWare only lets requests with a valid access token through. API
+// keys are refused, routes that take them are registered with authorize.
 func (cfg *apiConfig) authMiddleWare(next http.Handler) http.Handler {
+	return cfg.authenticate(next, false)
+}
+
+func (cfg *apiConfig) authenticate(next http.Handler, allowAPIKeys bool) http.Handler {
 	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
 		token, err := auth.GetBearerToken(req.Header)
 		if err != nil {
@@ -190,16 +295,42 @@ func (cfg *apiConfig) authMiddleWare(next http.Handler) http.Handler {
 			return
 		}
 
-		userID, role, err := auth.ValidateJWT(token, cfg.JWTSecret)
+		if prefix, isAPIKey := auth.APIKeyLookupPrefix(token); isAPIKey {
+			if !allowAPIKeys {
+				respondWithError(res, http.StatusForbidden, "API keys cannot be used on this endpoint")
+				return
+			}
+
+			cfg.serveWithAPIKey(res, req, token, prefix, next)
+			return
+		}
+
+		userID, role, version, err := cfg.jwtKeys.ValidateJWT(token)
 		if err != nil {
 			respondWithError(res, http.StatusUnauthorized, err.Error())
 			return
 		}
 
+		//the role in the token is only good while the user has not been
+		//demoted, disabled or logged out everywhere since
+		err = cfg.checkTokenVersion(req.Context(), userID, version)
+
+		if err == errUserDisabled {
+			respondWithError(res, http.StatusForbidden, err.Error())
+			return
+		} else if err == errTokenRevoked || err == errUserNotFound {
+			respondWithError(res, http.StatusUnauthorized, err.Error())
+			return
+		} else if err != nil {
+			respondWithError(res, http.StatusInternalServerError, err.Error())
+			return
+		}
+
 		ctx := req.Context()
 
 		ctx = context.WithValue(ctx, ctxUserID, userID)
 		ctx = context.WithValue(ctx, ctxRole, role)
+		ctx = context.WithValue(ctx, ctxAuditSource, requestAuditSource(req, userID, role, uuid.NullUUID{}))
 
 		next.ServeHTTP(res, req.WithContext(ctx))
 
@@ -0,0 +1,326 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"time"
+
+	"github.com/Shayaan-Kashif/Database-Project/internal/auth"
+	"github.com/Shayaan-Kashif/Database-Project/internal/database"
+	"github.com/google/uuid"
+)
+
+// trustedProxies are the addresses allowed to tell with X-Forwarded-For who
+// they forward requests for, set from TRUSTED_PROXIES on startup.
+var trustedProxies []netip.Prefix
+
+// parseTrustedProxies reads a comma separated list of addresses and CIDR
+// ranges.
+func parseTrustedProxies(raw string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+
+	for _, entry := range strings.Split(raw, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		if strings.Contains(entry, "/") {
+			prefix, err := netip.ParsePrefix(entry)
+			if err != nil {
+				return nil, fmt.Errorf("%q is not an address or CIDR range", entry)
+			}
+			prefixes = append(prefixes, prefix.Masked())
+			continue
+		}
+
+		addr, err := netip.ParseAddr(entry)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not an address or CIDR range", entry)
+		}
+		addr = addr.Unmap()
+		prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+
+	return prefixes, nil
+}
+
+func isTrustedProxy(ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+
+	for _, prefix := range trustedProxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// clientIP is the address the request came from, without the port. Requests
+// of a trusted proxy come from the last address in X-Forwarded-For that is not
+// a trusted proxy itself, anyone else could claim any address with the header.
+func clientIP(req *http.Request) string {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		host = req.RemoteAddr
+	}
+
+	if !isTrustedProxy(host) {
+		return host
+	}
+
+	hops := strings.Split(strings.Join(req.Header.Values("X-Forwarded-For"), ","), ",")
+
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if hop == "" {
+			continue
+		}
+
+		//garbage is not an address, the last proxy is all that is known
+		if _, err := netip.ParseAddr(hop); err != nil {
+			return host
+		}
+
+		if !isTrustedProxy(hop) {
+			return hop
+		}
+
+		host = hop
+	}
+
+	return host
+}
+
+// respondWithSessions lists the live sessions of userID. The session of the
+// request's own refresh cookie, if any, is flagged as current.
+func (cfg *apiConfig) respondWithSessions(res http.ResponseWriter, req *http.Request, userID uuid.UUID) {
+	sessionsDB, err := cfg.dbQueries.GetLiveSessionsFromUserID(req.Context(), userID)
+
+	if err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	currentID := uuid.Nil
+
+	if refreshToken, err := auth.GetRefreshTokenFromCookie(req); err == nil {
+		if dbToken, err := cfg.dbQueries.GetRefreshToken(req.Context(), refreshToken); err == nil {
+			currentID = dbToken.FamilyID
+		}
+	}
+
+	response := make([]struct {
+		ID         uuid.UUID `json:"id"`
+		UserAgent  string    `json:"userAgent"`
+		IP         string    `json:"ip"`
+		CreatedAt  time.Time `json:"createdAt"`
+		LastUsedAt time.Time `json:"lastUsedAt"`
+		ExpiresAt  time.Time `json:"expiresAt"`
+		Current    bool      `json:"current"`
+	}, 0, len(sessionsDB))
+
+	for _, u := range sessionsDB {
+		response = append(response, struct {
+			ID         uuid.UUID `json:"id"`
+			UserAgent  string    `json:"userAgent"`
+			IP         string    `json:"ip"`
+			CreatedAt  time.Time `json:"createdAt"`
+			LastUsedAt time.Time `json:"lastUsedAt"`
+			ExpiresAt  time.Time `json:"expiresAt"`
+			Current    bool      `json:"current"`
+		}{
+			ID:         u.ID,
+			UserAgent:  u.UserAgent,
+			IP:         u.Ip,
+			CreatedAt:  u.CreatedAt,
+			LastUsedAt: u.LastUsedAt,
+			ExpiresAt:  u.ExpiresAt,
+			Current:    u.ID == currentID,
+		})
+	}
+
+	respondWithJSON(res, http.StatusOK, response)
+}
+
+func (cfg *apiConfig) revokeSession(res http.ResponseWriter, req *http.Request, userID uuid.UUID) {
+	sessionID, err := uuid.Parse(req.PathValue("sessionID"))
+
+	if err != nil {
+		respondWithError(res, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	revoked, err := cfg.dbQueries.RevokeUserSession(req.Context(), database.RevokeUserSessionParams{
+		FamilyID: sessionID,
+		UserID:   userID,
+	})
+
+	if err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if revoked == 0 {
+		respondWithError(res, http.StatusNotFound, "no session exist for that sessionID")
+		return
+	}
+
+	entry := auditEntry{Action: "session.revoke", TargetType: "session", TargetID: sessionID}
+
+	if userDB, err := cfg.dbQueries.GetUserFromID(req.Context(), userID); err == nil {
+		entry.CampusID = userDB.CampusID
+	}
+
+	entry.Before = struct {
+		UserID uuid.UUID `json:"userID"`
+	}{userID}
+
+	auditAfter(req.Context(), cfg.dbQueries, entry)
+
+	respondWithJSON(res, http.StatusOK, struct {
+		Status string `json:"status"`
+	}{"The session has been revoked"})
+}
+
+// revokeOtherSessions revokes every session of userID but the one of the
+// request's refresh cookie. Without a cookie of userID every session goes.
+func (cfg *apiConfig) revokeOtherSessions(req *http.Request, qtx *database.Queries, userID uuid.UUID) error {
+	if refreshToken, err := auth.GetRefreshTokenFromCookie(req); err == nil {
+		if dbToken, err := qtx.GetRefreshToken(req.Context(), refreshToken); err == nil && dbToken.UserID == userID {
+			return qtx.RevokeOtherUserTokens(req.Context(), database.RevokeOtherUserTokensParams{
+				UserID:   userID,
+				FamilyID: dbToken.FamilyID,
+			})
+		}
+	}
+
+	return qtx.RevokeAllUserTokens(req.Context(), userID)
+}
+
+// revokeAllSessions logs userID out everywhere, access tokens included.
+func (cfg *apiConfig) revokeAllSessions(res http.ResponseWriter, req *http.Request, userID uuid.UUID) {
+	tx, err := cfg.db.BeginTx(req.Context(), nil)
+
+	if err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	defer tx.Rollback()
+
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	if err := qtx.RevokeAllUserTokens(req.Context(), userID); err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if err := qtx.BumpTokenVersion(req.Context(), userID); err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	userDB, err := qtx.GetUserFromID(req.Context(), userID)
+
+	if err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if err := auditUser(req.Context(), qtx, "session.revoke_all", userDB); err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	cfg.tokenVersions.forget(userID)
+
+	respondWithJSON(res, http.StatusOK, struct {
+		Status string `json:"status"`
+	}{"Every session has been revoked"})
+}
+
+func (cfg *apiConfig) getSessions(res http.ResponseWriter, req *http.Request) {
+	userID := req.Context().Value(ctxUserID).(uuid.UUID)
+	cfg.respondWithSessions(res, req, userID)
+}
+
+func (cfg *apiConfig) deleteSession(res http.ResponseWriter, req *http.Request) {
+	userID := req.Context().Value(ctxUserID).(uuid.UUID)
+	cfg.revokeSession(res, req, userID)
+}
+
+func (cfg *apiConfig) deleteAllSessions(res http.ResponseWriter, req *http.Request) {
+	userID := req.Context().Value(ctxUserID).(uuid.UUID)
+
+	//this device is logged out too
+	clearRefreshCookie(res)
+	cfg.revokeAllSessions(res, req, userID)
+}
+
+// managedUser reads the userID path value for the admin user endpoints and
+// checks the admin may manage that user. It responds itself when not.
+func (cfg *apiConfig) managedUser(res http.ResponseWriter, req *http.Request) (database.User, bool) {
+	userID, err := uuid.Parse(req.PathValue("userID"))
+
+	if err != nil {
+		respondWithError(res, http.StatusBadRequest, err.Error())
+		return database.User{}, false
+	}
+
+	userDB, err := cfg.dbQueries.GetUserFromID(req.Context(), userID)
+
+	if err == sql.ErrNoRows {
+		respondWithError(res, http.StatusNotFound, "no user exist for that userID")
+		return database.User{}, false
+	} else if err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return database.User{}, false
+	}
+
+	allowed, err := cfg.canManageUser(req, userDB)
+
+	if err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return database.User{}, false
+	}
+
+	if !allowed {
+		respondWithError(res, http.StatusUnauthorized, "Unauthorized")
+		return database.User{}, false
+	}
+
+	return userDB, true
+}
+
+func (cfg *apiConfig) getUserSessions(res http.ResponseWriter, req *http.Request) {
+	if userDB, ok := cfg.managedUser(res, req); ok {
+		cfg.respondWithSessions(res, req, userDB.ID)
+	}
+}
+
+func (cfg *apiConfig) deleteUserSession(res http.ResponseWriter, req *http.Request) {
+	if userDB, ok := cfg.managedUser(res, req); ok {
+		cfg.revokeSession(res, req, userDB.ID)
+	}
+}
+
+func (cfg *apiConfig) deleteAllUserSessions(res http.ResponseWriter, req *http.Request) {
+	if userDB, ok := cfg.managedUser(res, req); ok {
+		cfg.revokeAllSessions(res, req, userDB.ID)
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	proxies, err := parseTrustedProxies("127.0.0.1, 10.0.0.0/8")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	trustedProxies = proxies
+	t.Cleanup(func() { trustedProxies = nil })
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		forwarded  []string
+		want       string
+	}{
+		{"direct", "203.0.113.7:51000", nil, "203.0.113.7"},
+		{"untrusted proxy", "203.0.113.7:51000", []string{"198.51.100.1"}, "203.0.113.7"},
+		{"trusted proxy", "127.0.0.1:51000", []string{"198.51.100.1"}, "198.51.100.1"},
+		{"trusted proxy without header", "127.0.0.1:51000", nil, "127.0.0.1"},
+		{"spoofed first hop", "127.0.0.1:51000", []string{"1.2.3.4, 198.51.100.1"}, "198.51.100.1"},
+		{"chain of trusted proxies", "127.0.0.1:51000", []string{"198.51.100.1, 10.1.2.3"}, "198.51.100.1"},
+		{"repeated headers", "127.0.0.1:51000", []string{"1.2.3.4", "198.51.100.1"}, "198.51.100.1"},
+		{"only trusted hops", "127.0.0.1:51000", []string{"10.1.2.3"}, "10.1.2.3"},
+		{"garbage hop", "127.0.0.1:51000", []string{"not-an-ip"}, "127.0.0.1"},
+		{"ipv6", "[2001:db8::1]:51000", nil, "2001:db8::1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/refresh", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for _, value := range tt.forwarded {
+				req.Header.Add("X-Forwarded-For", value)
+			}
+
+			if got := clientIP(req); got != tt.want {
+				t.Errorf("clientIP = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTrustedProxiesInvalid(t *testing.T) {
+	for _, raw := range []string{"localhost", "10.0.0.0/33", "1.2.3"} {
+		if _, err := parseTrustedProxies(raw); err == nil {
+			t.Errorf("parseTrustedProxies(%q) accepted it", raw)
+		}
+	}
+}
@@ -0,0 +1,542 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Shayaan-Kashif/Database-Project/internal/auth"
+	"github.com/Shayaan-Kashif/Database-Project/internal/database"
+	"github.com/google/uuid"
+)
+
+const simulatorPassword = "simulator"
+
+type simEvent struct {
+	at   time.Time
+	user int
+	kind string
+}
+
+type simLot struct {
+	id       uuid.UUID
+	slots    int32
+	occupied int32
+}
+
+// simulator drives fake users in and out of the lots. Lot occupancy is tracked
+// in memory, it assumes no one else is parking while it runs.
+type simulator struct {
+	cfg     *apiConfig
+	rand    *rand.Rand
+	mode    string
+	clock   string
+	speed   float64
+	workers int
+
+	users []uuid.UUID
+
+	mu       sync.Mutex
+	lots     []*simLot
+	parkedAt map[int]*simLot
+
+	applied   int
+	lotFull   int
+	failed    int
+	latencies []time.Duration
+}
+
+func (cfg *apiConfig) simulateCommand(args []string) error {
+	flags := flag.NewFlagSet("simulate", flag.ContinueOnError)
+	users := flags.Int("users", 100, "number of fake users to drive")
+	days := flags.Int("days", 1, "number of days to simulate")
+	start := flags.String("start", "", "first simulated day (YYYY-MM-DD), defaults to the days leading up to today")
+	mode := flags.String("mode", "queries", "queries writes through database.Queries, handler calls the park handler")
+	clock := flags.String("clock", "simulated", "simulated runs as fast as possible, real waits for each event")
+	speed := flags.Float64("speed", 60, "with --clock real, how many simulated seconds pass per real second")
+	workers := flags.Int("workers", 8, "events of the same minute are applied concurrently by this many workers")
+	seed := flags.Int64("seed", time.Now().UnixNano(), "random seed, reuse it to replay the same traffic")
+	campus := flags.String("campus", "", "only use lots of this campus")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if *mode != "queries" && *mode != "handler" {
+		return errors.New("--mode must be queries or handler")
+	}
+
+	if *clock != "simulated" && *clock != "real" {
+		return errors.New("--clock must be simulated or real")
+	}
+
+	if *users <= 0 || *days <= 0 || *workers <= 0 || *speed <= 0 {
+		return errors.New("--users, --days, --workers and --speed must be positive")
+	}
+
+	campusID, err := parseCampusFlag(*campus)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+
+	//days are scheduled in the campus' time zone, like the history endpoint counts them
+	loc, err := cfg.campusLocation(ctx, campusID)
+	if err != nil {
+		return err
+	}
+
+	today := time.Now().In(loc)
+	firstDay := time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, loc).AddDate(0, 0, 1-*days)
+
+	if *clock == "real" {
+		firstDay = time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, loc)
+	}
+
+	if *start != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", *start, loc)
+		if err != nil {
+			return err
+		}
+		firstDay = parsed
+	}
+
+	lotsDB, err := cfg.dbQueries.GetParkingLots(ctx, campusID)
+	if err != nil {
+		return err
+	}
+
+	if len(lotsDB) == 0 {
+		return errors.New("there are no parking lots to simulate")
+	}
+
+	sim := &simulator{
+		cfg:      cfg,
+		rand:     rand.New(rand.NewSource(*seed)),
+		mode:     *mode,
+		clock:    *clock,
+		speed:    *speed,
+		workers:  *workers,
+		parkedAt: map[int]*simLot{},
+	}
+
+	for _, lot := range lotsDB {
+		sim.lots = append(sim.lots, &simLot{id: lot.ID, slots: lot.Slots, occupied: lot.Occupiedslots})
+	}
+
+	if err := sim.createUsers(ctx, *users); err != nil {
+		return err
+	}
+
+	events := []simEvent{}
+	for day := 0; day < *days; day++ {
+		events = append(events, sim.scheduleDay(firstDay.AddDate(0, 0, day))...)
+	}
+
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].at.Before(events[j].at)
+	})
+
+	fmt.Printf("simulating %d events for %d users over %d days (seed %d)\n", len(events), *users, *days, *seed)
+
+	began := time.Now()
+	if err := sim.run(ctx, events); err != nil {
+		return err
+	}
+
+	sim.report(time.Since(began))
+	return nil
+}
+
+// createUsers reuses the fake users of earlier runs so repeated simulations do
+// not keep growing the users table.
+func (sim *simulator) createUsers(ctx context.Context, count int) error {
+	hashedPassword, err := auth.Hashpassword(simulatorPassword)
+	if err != nil {
+		return err
+	}
+
+	for i := 1; i <= count; i++ {
+		email := fmt.Sprintf("sim%d@simulator.local", i)
+
+		userDB, err := sim.cfg.dbQueries.GetUserFromEmail(ctx, email)
+		if err == nil {
+			if userDB.ParkingLotID.Valid {
+				for _, lot := range sim.lots {
+					if lot.id == userDB.ParkingLotID.UUID {
+						sim.parkedAt[i-1] = lot
+					}
+				}
+			}
+
+			sim.users = append(sim.users, userDB.ID)
+			continue
+		} else if err != sql.ErrNoRows {
+			return err
+		}
+
+		createdUser, err := sim.cfg.dbQueries.CreateUser(ctx, database.CreateUserParams{
+			Name:           fmt.Sprintf("Simulated User %d", i),
+			Email:          email,
+			HashedPassword: hashedPassword,
+			Role:           "user",
+		})
+
+		if err != nil {
+			return err
+		}
+
+		//the park handler refuses unverified users
+		if err := sim.cfg.dbQueries.MarkEmailVerified(ctx, createdUser.ID); err != nil {
+			return err
+		}
+
+		sim.users = append(sim.users, createdUser.ID)
+	}
+
+	return nil
+}
+
+// clockTime returns the wall clock time minutes after midnight of day, so an
+// 8:30 arrival stays at 8:30 on the days clocks change.
+func clockTime(day time.Time, minutes int) time.Time {
+	return time.Date(day.Year(), day.Month(), day.Day(), 0, minutes, 0, 0, day.Location())
+}
+
+// around returns a time of day normally distributed around hour, in minutes.
+func (sim *simulator) around(day time.Time, hour, stddevMinutes float64) time.Time {
+	minutes := hour*60 + sim.rand.NormFloat64()*stddevMinutes
+	minutes = min(max(minutes, 6*60), 23*60)
+	return clockTime(day, int(minutes))
+}
+
+// scheduleDay builds one day of traffic: a morning arrival peak, a spread of
+// later arrivals, lunchtime exits and re-entries and an evening exit peak.
+func (sim *simulator) scheduleDay(day time.Time) []simEvent {
+	attendance := 0.85
+	if day.Weekday() == time.Saturday || day.Weekday() == time.Sunday {
+		attendance = 0.15
+	}
+
+	events := []simEvent{}
+
+	for user := range sim.users {
+		if sim.rand.Float64() > attendance {
+			continue
+		}
+
+		arrival := sim.around(day, 8.5, 40)
+		if sim.rand.Float64() < 0.35 {
+			arrival = clockTime(day, 10*60+sim.rand.Intn(4*60))
+		}
+
+		departure := sim.around(day, 17, 75)
+		if departure.Before(arrival.Add(time.Hour)) {
+			departure = arrival.Add(time.Hour + time.Duration(sim.rand.Intn(3*60))*time.Minute)
+		}
+
+		events = append(events, simEvent{at: arrival, user: user, kind: "entry"})
+
+		lunch := sim.around(day, 12, 20)
+		if lunch.After(arrival.Add(30*time.Minute)) && sim.rand.Float64() < 0.25 {
+			back := lunch.Add(time.Duration(30+sim.rand.Intn(45)) * time.Minute)
+
+			if back.Before(departure.Add(-30 * time.Minute)) {
+				events = append(events,
+					simEvent{at: lunch, user: user, kind: "exit"},
+					simEvent{at: back, user: user, kind: "entry"},
+				)
+			}
+		}
+
+		events = append(events, simEvent{at: departure, user: user, kind: "exit"})
+	}
+
+	return events
+}
+
+// run applies the events minute by minute. Events in the same minute belong to
+// different users so they are applied concurrently.
+func (sim *simulator) run(ctx context.Context, events []simEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	simStart := events[0].at
+	realStart := time.Now()
+
+	for i := 0; i < len(events); {
+		minute := events[i].at.Truncate(time.Minute)
+
+		j := i
+		for j < len(events) && events[j].at.Truncate(time.Minute).Equal(minute) {
+			j++
+		}
+
+		if sim.clock == "real" {
+			wait := time.Duration(float64(minute.Sub(simStart))/sim.speed) - time.Since(realStart)
+			if wait > 0 {
+				time.Sleep(wait)
+			}
+		}
+
+		batch := make(chan simEvent)
+		var wg sync.WaitGroup
+		var errOnce sync.Once
+		var runErr error
+
+		for w := 0; w < sim.workers; w++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for event := range batch {
+					if err := sim.apply(ctx, event); err != nil {
+						errOnce.Do(func() { runErr = err })
+					}
+				}
+			}()
+		}
+
+		for _, event := range events[i:j] {
+			batch <- event
+		}
+		close(batch)
+		wg.Wait()
+
+		if runErr != nil {
+			return runErr
+		}
+
+		i = j
+	}
+
+	return nil
+}
+
+// pickLot chooses a lot with free slots, weighted by how many are free, and
+// reserves a slot in it.
+func (sim *simulator) pickLot(exclude map[*simLot]bool) *simLot {
+	sim.mu.Lock()
+	defer sim.mu.Unlock()
+
+	total := int32(0)
+	for _, lot := range sim.lots {
+		if !exclude[lot] {
+			total += max(lot.slots-lot.occupied, 0)
+		}
+	}
+
+	if total == 0 {
+		return nil
+	}
+
+	pick := sim.rand.Int31n(total)
+	for _, lot := range sim.lots {
+		if exclude[lot] {
+			continue
+		}
+
+		free := max(lot.slots-lot.occupied, 0)
+		if pick < free {
+			lot.occupied++
+			return lot
+		}
+		pick -= free
+	}
+
+	return nil
+}
+
+func (sim *simulator) apply(ctx context.Context, event simEvent) error {
+	sim.mu.Lock()
+	parked := sim.parkedAt[event.user]
+	sim.mu.Unlock()
+
+	if event.kind == "exit" {
+		if parked == nil {
+			return nil
+		}
+
+		ok, err := sim.park(ctx, event, parked.id)
+		if err != nil || !ok {
+			return err
+		}
+
+		sim.mu.Lock()
+		parked.occupied--
+		delete(sim.parkedAt, event.user)
+		sim.mu.Unlock()
+		return nil
+	}
+
+	if parked != nil {
+		return nil
+	}
+
+	//if a lot turns out to be full, try a few others like a driver would
+	tried := map[*simLot]bool{}
+	for attempt := 0; attempt < 3; attempt++ {
+		lot := sim.pickLot(tried)
+		if lot == nil {
+			break
+		}
+
+		ok, err := sim.park(ctx, event, lot.id)
+		if err != nil {
+			return err
+		}
+
+		if ok {
+			sim.mu.Lock()
+			sim.parkedAt[event.user] = lot
+			sim.mu.Unlock()
+			return nil
+		}
+
+		sim.mu.Lock()
+		lot.occupied--
+		sim.mu.Unlock()
+		tried[lot] = true
+	}
+
+	sim.mu.Lock()
+	sim.lotFull++
+	sim.mu.Unlock()
+	return nil
+}
+
+// park records a single entry or exit. It returns false when the park was
+// rejected, for example because the lot was full.
+func (sim *simulator) park(ctx context.Context, event simEvent, lotID uuid.UUID) (bool, error) {
+	userID := sim.users[event.user]
+	began := time.Now()
+
+	var ok bool
+	var err error
+
+	if sim.mode == "handler" {
+		ok, err = sim.parkThroughHandler(ctx, userID, lotID, event.kind)
+	} else {
+		ok, err = sim.parkThroughQueries(ctx, userID, lotID, event.kind, event.at)
+	}
+
+	sim.mu.Lock()
+	defer sim.mu.Unlock()
+
+	sim.latencies = append(sim.latencies, time.Since(began))
+	if ok {
+		sim.applied++
+	} else {
+		sim.failed++
+	}
+
+	return ok, err
+}
+
+// parkThroughHandler calls the real park handler, the logs get the current
+// time rather than the simulated one.
+func (sim *simulator) parkThroughHandler(ctx context.Context, userID, lotID uuid.UUID, kind string) (bool, error) {
+	body, err := json.Marshal(struct {
+		ParkingLotID uuid.UUID `json:"parkingLotID"`
+		Type         string    `json:"type"`
+	}{lotID, kind})
+
+	if err != nil {
+		return false, err
+	}
+
+	ctx = context.WithValue(ctx, ctxUserID, userID)
+	ctx = context.WithValue(ctx, ctxRole, "user")
+
+	req := httptest.NewRequestWithContext(ctx, http.MethodPost, "/api/park", bytes.NewReader(body))
+	res := httptest.NewRecorder()
+
+	sim.cfg.park(res, req)
+
+	if res.Code >= http.StatusInternalServerError {
+		return false, fmt.Errorf("park handler failed: %s", res.Body.String())
+	}
+
+	return res.Code == http.StatusAccepted, nil
+}
+
+// parkThroughQueries makes the same changes as the park handler but stamps the
+// log with the simulated time.
+func (sim *simulator) parkThroughQueries(ctx context.Context, userID, lotID uuid.UUID, kind string, at time.Time) (bool, error) {
+	tx, err := sim.cfg.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+
+	defer tx.Rollback()
+
+	qtx := sim.cfg.dbQueries.WithTx(tx)
+
+	increment, parkingLotID := int32(1), uuid.NullUUID{UUID: lotID, Valid: true}
+	if kind == "exit" {
+		increment, parkingLotID = -1, uuid.NullUUID{}
+	}
+
+	err = qtx.UpdateOccupiedSlot(ctx, database.UpdateOccupiedSlotParams{
+		Occupiedslots: increment,
+		ID:            lotID,
+	})
+
+	if hasPgErr, _ := handlePgConstraints(err); hasPgErr {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	err = qtx.UpdateUserParkingLot(ctx, database.UpdateUserParkingLotParams{
+		ParkingLotID: parkingLotID,
+		ID:           userID,
+	})
+
+	if err != nil {
+		return false, err
+	}
+
+	_, err = qtx.CreateLogAt(ctx, database.CreateLogAtParams{
+		UserID:       uuid.NullUUID{UUID: userID, Valid: true},
+		ParkingLotID: lotID,
+		EventType:    kind,
+		Time:         at,
+	})
+
+	if err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit()
+}
+
+func (sim *simulator) report(elapsed time.Duration) {
+	fmt.Printf("applied %d events in %s (%.1f events/s)\n", sim.applied, elapsed.Round(time.Millisecond), float64(sim.applied+sim.failed)/elapsed.Seconds())
+	fmt.Printf("rejected parks: %d, drivers who found every lot full: %d\n", sim.failed, sim.lotFull)
+
+	if len(sim.latencies) == 0 {
+		return
+	}
+
+	sort.Slice(sim.latencies, func(i, j int) bool {
+		return sim.latencies[i] < sim.latencies[j]
+	})
+
+	percentile := func(p float64) time.Duration {
+		return sim.latencies[int(p*float64(len(sim.latencies)-1))]
+	}
+
+	fmt.Printf("park latency p50 %s, p95 %s, p99 %s, max %s\n", percentile(0.50), percentile(0.95), percentile(0.99), sim.latencies[len(sim.latencies)-1])
+}
@@ -0,0 +1,42 @@
+-- name: CreateAdminInvitation :one
+INSERT INTO admin_invitations(id, token_hash, role, admin_campus_id, email, created_by, expires_at, created_at)
+VALUES (
+    gen_random_uuid(),
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    NOW()
+)
+RETURNING *;
+
+-- name: GetAdminInvitations :many
+SELECT * FROM admin_invitations
+WHERE sqlc.narg('admin_campus_id')::uuid IS NULL OR admin_campus_id = sqlc.narg('admin_campus_id')
+ORDER BY created_at DESC;
+
+-- name: GetAdminInvitationFromID :one
+SELECT * FROM admin_invitations
+WHERE id = $1;
+
+-- name: RedeemAdminInvitation :one
+UPDATE admin_invitations
+SET redeemed_at = NOW()
+WHERE token_hash = sqlc.arg('token_hash')
+AND redeemed_at IS NULL
+AND revoked_at IS NULL
+AND expires_at > NOW()
+AND (email IS NULL OR LOWER(email) = LOWER(sqlc.arg('redeemer_email')::text))
+RETURNING *;
+
+-- name: SetAdminInvitationRedeemer :exec
+UPDATE admin_invitations
+SET redeemed_by = $1
+WHERE id = $2;
+
+-- name: RevokeAdminInvitation :execrows
+UPDATE admin_invitations
+SET revoked_at = NOW()
+WHERE id = $1 AND redeemed_at IS NULL AND revoked_at IS NULL;
@@ -0,0 +1,37 @@
+-- name: CreateAPIKey :one
+INSERT INTO api_keys(id, name, key_prefix, key_hash, scopes, created_by, expires_at, created_at)
+VALUES (
+    gen_random_uuid(),
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    NOW()
+)
+RETURNING *;
+
+-- name: GetAPIKeyFromPrefix :one
+SELECT api_keys.*, users.role, users.disabled_at FROM api_keys
+INNER JOIN users ON users.id = api_keys.created_by
+WHERE api_keys.key_prefix = $1;
+
+-- name: TouchAPIKey :exec
+UPDATE api_keys
+SET last_used_at = NOW()
+WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < NOW() - INTERVAL '1 minute');
+
+-- name: GetAPIKeys :many
+SELECT * FROM api_keys
+WHERE sqlc.narg('created_by')::uuid IS NULL OR created_by = sqlc.narg('created_by')
+ORDER BY created_at DESC;
+
+-- name: GetAPIKeyFromID :one
+SELECT * FROM api_keys
+WHERE id = $1;
+
+-- name: RevokeAPIKey :execrows
+UPDATE api_keys
+SET revoked_at = NOW()
+WHERE id = $1 AND revoked_at IS NULL;
@@ -0,0 +1,42 @@
+-- name: CreateAuditEntry :exec
+INSERT INTO audit_log(id, actor_id, actor_role, api_key_id, action, target_type, target_id, campus_id, before, after, ip, user_agent, method, path, created_at)
+VALUES (
+    gen_random_uuid(),
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7,
+    $8,
+    $9,
+    $10,
+    $11,
+    $12,
+    $13,
+    NOW()
+);
+
+-- name: GetAuditEntries :many
+SELECT * FROM audit_log
+WHERE (sqlc.narg('actor_id')::uuid IS NULL OR actor_id = sqlc.narg('actor_id'))
+AND (sqlc.narg('action')::text IS NULL OR action = sqlc.narg('action'))
+AND (sqlc.narg('target_type')::text IS NULL OR target_type = sqlc.narg('target_type'))
+AND (sqlc.narg('target_id')::uuid IS NULL OR target_id = sqlc.narg('target_id'))
+AND (sqlc.narg('campus_id')::uuid IS NULL OR campus_id = sqlc.narg('campus_id'))
+AND (sqlc.narg('from_time')::timestamptz IS NULL OR created_at >= sqlc.narg('from_time'))
+AND (sqlc.narg('to_time')::timestamptz IS NULL OR created_at < sqlc.narg('to_time'))
+ORDER BY created_at DESC, id
+LIMIT sqlc.arg('page_size') OFFSET sqlc.arg('page_offset');
+
+-- name: GetAuditEntriesForExport :many
+SELECT * FROM audit_log
+WHERE (sqlc.narg('actor_id')::uuid IS NULL OR actor_id = sqlc.narg('actor_id'))
+AND (sqlc.narg('action')::text IS NULL OR action = sqlc.narg('action'))
+AND (sqlc.narg('target_type')::text IS NULL OR target_type = sqlc.narg('target_type'))
+AND (sqlc.narg('target_id')::uuid IS NULL OR target_id = sqlc.narg('target_id'))
+AND (sqlc.narg('campus_id')::uuid IS NULL OR campus_id = sqlc.narg('campus_id'))
+AND (sqlc.narg('from_time')::timestamptz IS NULL OR created_at >= sqlc.narg('from_time'))
+AND (sqlc.narg('to_time')::timestamptz IS NULL OR created_at < sqlc.narg('to_time'))
+ORDER BY created_at ASC, id;
@@ -0,0 +1,24 @@
+-- name: CreateCampus :one
+INSERT INTO campuses(id, name, created_at, time_zone)
+VALUES (
+    gen_random_uuid(),
+    $1,
+    NOW(),
+    $2
+) RETURNING *;
+
+-- name: GetCampuses :many
+SELECT *
+FROM campuses
+ORDER BY name ASC;
+
+-- name: GetCampusFromID :one
+SELECT *
+FROM campuses
+WHERE id = $1;
+
+-- name: UpdateCampusTimeZone :one
+UPDATE campuses
+SET time_zone = $2
+WHERE id = $1
+RETURNING *;
@@ -0,0 +1,69 @@
+-- name: CreateCitation :one
+INSERT INTO citations(id, officer_id, user_id, license_plate, parking_lot_id, parking_log_id, violation, notes, status, created_at, updated_at)
+VALUES (
+    gen_random_uuid(),
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7,
+    'issued',
+    NOW(),
+    NOW()
+) RETURNING *;
+
+-- name: CreateCitationPhoto :exec
+INSERT INTO citation_photos(id, citation_id, url, created_at)
+VALUES (
+    gen_random_uuid(),
+    $1,
+    $2,
+    NOW()
+);
+
+-- name: GetCitationFromID :one
+SELECT *
+FROM citations
+WHERE id = $1;
+
+-- name: GetCitationPhotos :many
+SELECT *
+FROM citation_photos
+WHERE citation_id = $1
+ORDER BY created_at ASC;
+
+-- name: GetCitationsFromUserID :many
+SELECT *
+FROM citations
+WHERE user_id = $1
+ORDER BY created_at DESC;
+
+-- name: GetCitations :many
+SELECT *
+FROM citations
+ORDER BY created_at DESC;
+
+-- name: AppealCitation :execresult
+UPDATE citations
+SET status = 'appealed',
+appeal_reason = $1,
+appealed_at = NOW(),
+updated_at = NOW()
+WHERE id = $2 AND user_id = $3 AND status = 'issued';
+
+-- name: ResolveCitation :one
+UPDATE citations
+SET status = $1,
+resolved_by = $2,
+resolved_at = NOW(),
+updated_at = NOW()
+WHERE id = $3 AND status = 'appealed'
+RETURNING *;
+
+-- name: SetCitationFine :one
+UPDATE citations
+SET fine_transaction_id = $1
+WHERE id = $2
+RETURNING *;
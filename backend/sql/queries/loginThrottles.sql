@@ -0,0 +1,40 @@
+-- name: GetActiveLockouts :many
+SELECT *
+FROM login_throttles
+WHERE locked_until > NOW() AND (
+    (kind = 'account' AND value = sqlc.arg('account'))
+    OR (kind = 'ip' AND value = sqlc.arg('ip'))
+);
+
+-- name: RecordLoginFailure :one
+INSERT INTO login_throttles(kind, value, failures, locked_until, last_failure_at)
+VALUES (
+    sqlc.arg('kind'),
+    sqlc.arg('value'),
+    1,
+    NULL,
+    NOW()
+)
+ON CONFLICT (kind, value) DO UPDATE
+SET failures = CASE WHEN login_throttles.last_failure_at < sqlc.arg('reset_before') THEN 1 ELSE login_throttles.failures + 1 END,
+last_failure_at = NOW()
+RETURNING *;
+
+-- name: LockLogin :exec
+UPDATE login_throttles
+SET locked_until = $3
+WHERE kind = $1 AND value = $2;
+
+-- name: ClearLoginThrottle :execrows
+DELETE FROM login_throttles
+WHERE kind = $1 AND value = $2;
+
+-- name: GetLockouts :many
+SELECT *
+FROM login_throttles
+WHERE locked_until > NOW()
+ORDER BY locked_until DESC;
+
+-- name: PurgeStaleLoginThrottles :execrows
+DELETE FROM login_throttles
+WHERE last_failure_at < $1 AND (locked_until IS NULL OR locked_until < NOW());
@@ -0,0 +1,23 @@
+-- name: IsLotManager :one
+SELECT EXISTS (
+    SELECT 1 FROM lot_managers
+    WHERE user_id = $1 AND parking_lot_id = $2
+);
+
+-- name: GetManagedLots :many
+SELECT parkinglots.* FROM parkinglots
+INNER JOIN lot_managers ON lot_managers.parking_lot_id = parkinglots.id
+WHERE lot_managers.user_id = $1
+ORDER BY parkinglots.name;
+
+-- name: AddManagedLot :exec
+INSERT INTO lot_managers(user_id, parking_lot_id, created_at)
+VALUES (
+    $1,
+    $2,
+    NOW()
+);
+
+-- name: DeleteManagedLots :exec
+DELETE FROM lot_managers
+WHERE user_id = $1;
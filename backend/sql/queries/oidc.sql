@@ -0,0 +1,45 @@
+-- name: CreateOIDCLoginState :exec
+INSERT INTO oidc_login_states(state_hash, code_verifier, nonce, expires_at, created_at)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    NOW()
+);
+
+-- name: UseOIDCLoginState :one
+DELETE FROM oidc_login_states
+WHERE state_hash = $1 AND expires_at > NOW()
+RETURNING *;
+
+-- name: PurgeExpiredOIDCLoginStates :execrows
+DELETE FROM oidc_login_states
+WHERE expires_at < NOW();
+
+-- name: GetUserFromIdentity :one
+SELECT users.* FROM users
+INNER JOIN user_identities ON user_identities.user_id = users.id
+WHERE user_identities.issuer = $1 AND user_identities.subject = $2;
+
+-- name: CreateUserIdentity :exec
+INSERT INTO user_identities(issuer, subject, user_id, email, created_at, last_login_at)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    NOW(),
+    NOW()
+);
+
+-- name: TouchUserIdentity :exec
+UPDATE user_identities
+SET email = $3,
+last_login_at = NOW()
+WHERE issuer = $1 AND subject = $2;
+
+-- name: GetIdentitiesFromUserID :many
+SELECT * FROM user_identities
+WHERE user_id = $1
+ORDER BY created_at;
@@ -8,8 +8,21 @@ VALUES (
     NOW()
 ) RETURNING *;
 
+-- name: CreateLogAt :one
+INSERT INTO parking_logs(id, user_id, parking_lot_id, event_type, time)
+VALUES (
+    gen_random_uuid(),
+    $1,
+    $2,
+    $3,
+    $4
+) RETURNING *;
+
 -- name: GetLogs :many
-SELECT * FROM parking_logs;
+SELECT * FROM parking_logs
+WHERE sqlc.narg('campus_id')::uuid IS NULL OR parking_lot_id IN (
+    SELECT id FROM parkinglots WHERE campus_id = sqlc.narg('campus_id')
+);
 
 -- name: GetLogsFromUserID :many
 SELECT *
@@ -20,4 +33,76 @@ WHERE user_id = $1;
 SELECT *
 FROM parking_logs
 WHERE parking_lot_id = $1
-ORDER BY time ASC;
\ No newline at end of file
+ORDER BY time ASC;
+
+-- name: GetLatestLogFromUserAndLot :one
+SELECT *
+FROM parking_logs
+WHERE user_id = $1 AND parking_lot_id = $2
+ORDER BY time DESC
+LIMIT 1;
+
+-- name: GetLogsForExport :many
+SELECT parking_logs.*, parkinglots.name AS lot_name
+FROM parking_logs
+INNER JOIN parkinglots ON parking_logs.parking_lot_id = parkinglots.id
+WHERE (sqlc.narg('from_time')::timestamptz IS NULL OR parking_logs.time >= sqlc.narg('from_time'))
+AND (sqlc.narg('to_time')::timestamptz IS NULL OR parking_logs.time < sqlc.narg('to_time'))
+AND (sqlc.narg('lot_id')::uuid IS NULL OR parking_logs.parking_lot_id = sqlc.narg('lot_id'))
+AND (sqlc.narg('event_type')::text IS NULL OR parking_logs.event_type = sqlc.narg('event_type'))
+AND (sqlc.narg('campus_id')::uuid IS NULL OR parkinglots.campus_id = sqlc.narg('campus_id'))
+ORDER BY parking_logs.time ASC;
+
+-- name: RestoreLog :execrows
+INSERT INTO parking_logs(id, user_id, parking_lot_id, event_type, time)
+SELECT sqlc.arg('id')::uuid, (SELECT users.id FROM users WHERE users.id = sqlc.narg('user_id')::uuid), sqlc.arg('parking_lot_id')::uuid, sqlc.arg('event_type')::text, sqlc.arg('time')::timestamptz
+WHERE EXISTS (SELECT 1 FROM parkinglots WHERE parkinglots.id = sqlc.arg('parking_lot_id'))
+ON CONFLICT DO NOTHING;
+
+-- name: GetParkingLogPartitions :many
+SELECT child.relname::text AS name
+FROM pg_inherits
+INNER JOIN pg_class parent ON pg_inherits.inhparent = parent.oid
+INNER JOIN pg_class child ON pg_inherits.inhrelid = child.oid
+WHERE parent.relname = 'parking_logs'
+ORDER BY child.relname;
+
+-- name: CreateParkingLogsPartition :exec
+SELECT create_parking_logs_partition(sqlc.arg('month')::timestamptz);
+
+-- name: DropParkingLogsPartition :exec
+SELECT drop_parking_logs_partition(sqlc.arg('month')::timestamptz);
+
+-- name: DetachParkingLogsPartition :exec
+SELECT detach_parking_logs_partition(sqlc.arg('month')::timestamptz);
+
+-- name: AttachParkingLogsPartition :exec
+SELECT attach_parking_logs_partition(sqlc.arg('month')::timestamptz);
+
+-- name: GetDetachedParkingLogPartitions :many
+SELECT relname::text AS name
+FROM pg_class
+WHERE relkind = 'r' AND relname ~ '^parking_logs_[0-9]{4}_[0-9]{2}$'
+AND NOT EXISTS (
+    SELECT 1 FROM pg_inherits
+    WHERE pg_inherits.inhrelid = pg_class.oid
+)
+ORDER BY relname;
+
+-- name: GetDetachedParkingLogs :many
+SELECT *
+FROM detached_parking_logs(sqlc.arg('month')::timestamptz);
+
+-- name: MarkLogMonthRestored :exec
+INSERT INTO restored_log_months(month, restored_at)
+VALUES (sqlc.arg('month')::timestamptz, NOW())
+ON CONFLICT (month) DO UPDATE SET restored_at = NOW();
+
+-- name: ClearLogMonthRestored :exec
+DELETE FROM restored_log_months
+WHERE month = sqlc.arg('month')::timestamptz;
+
+-- name: GetRestoredLogMonths :many
+SELECT month
+FROM restored_log_months
+ORDER BY month;
@@ -1,14 +1,16 @@
 -- name: GetParkingLots :many
 SELECT *
-FROM parkinglots;
+FROM parkinglots
+WHERE sqlc.narg('campus_id')::uuid IS NULL OR campus_id = sqlc.narg('campus_id');
 
 -- name: CreateParkingLot :one
-INSERT INTO parkinglots(id, name, slots, occupiedslots)
+INSERT INTO parkinglots(id, name, slots, occupiedslots, campus_id)
 VALUES (
     gen_random_uuid(),
     $1,
     $2,
-    0
+    0,
+    $3
 )
 RETURNING *;
 
@@ -30,4 +32,28 @@ WHERE id = $1;
 UPDATE parkinglots
 SET name = $1,
 slots = $2
-WHERE id = $3;
\ No newline at end of file
+WHERE id = $3;
+
+-- name: GetParkingLotFromCampusAndName :one
+SELECT *
+FROM parkinglots
+WHERE campus_id = $1 AND name = $2;
+
+
+-- name: RecountOccupiedSlots :many
+UPDATE parkinglots
+SET occupiedslots = counts.parked
+FROM (
+    SELECT parkinglots.id, COUNT(users.id) AS parked
+    FROM parkinglots
+    LEFT JOIN users ON users.parking_lot_id = parkinglots.id
+    GROUP BY parkinglots.id
+) AS counts
+WHERE parkinglots.id = counts.id AND parkinglots.occupiedslots <> counts.parked
+RETURNING parkinglots.*;
+
+-- name: CountParkingLotDependents :one
+SELECT
+    (SELECT COUNT(*) FROM parking_logs WHERE parking_logs.parking_lot_id = sqlc.arg('id')) AS logs,
+    (SELECT COUNT(*) FROM reviews WHERE reviews.parking_lot_id = sqlc.arg('id')) AS reviews,
+    (SELECT COUNT(*) FROM citations WHERE citations.parking_lot_id = sqlc.arg('id')) AS citations;
@@ -0,0 +1,28 @@
+-- name: CreatePasswordResetToken :exec
+INSERT INTO password_reset_tokens(token_hash, user_id, expires_at, used_at, created_at)
+VALUES (
+    $1,
+    $2,
+    $3,
+    NULL,
+    NOW()
+);
+
+-- name: CountRecentPasswordResets :one
+SELECT COUNT(*) FROM password_reset_tokens
+WHERE user_id = $1 AND created_at > $2;
+
+-- name: UsePasswordResetToken :one
+UPDATE password_reset_tokens
+SET used_at = NOW()
+WHERE token_hash = $1 AND used_at IS NULL AND expires_at > NOW()
+RETURNING user_id;
+
+-- name: UseAllUserPasswordResetTokens :exec
+UPDATE password_reset_tokens
+SET used_at = NOW()
+WHERE user_id = $1 AND used_at IS NULL;
+
+-- name: PurgeExpiredPasswordResetTokens :execrows
+DELETE FROM password_reset_tokens
+WHERE expires_at < NOW();
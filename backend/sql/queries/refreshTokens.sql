@@ -1,15 +1,17 @@
 -- name: CreateRefreshToken :one
-INSERT INTO refresh_tokens(token, user_id, expires_at, revoked_at)
+INSERT INTO refresh_tokens(token, user_id, expires_at, revoked_at, family_id, rotated_at)
 VALUES(
     $1,
     $2,
-    $3,
+    LEAST($3, (SELECT expires_at FROM sessions WHERE id = $4)),
+    NULL,
+    $4,
     NULL
 ) RETURNING *;
 
 
 -- name: GetRefreshToken :one
-SELECT refresh_tokens.*, users.role FROM refresh_tokens
+SELECT refresh_tokens.*, users.role, users.disabled_at, users.totp_enabled_at, users.token_version FROM refresh_tokens
 INNER JOIN users ON refresh_tokens.user_id = users.id 
 WHERE refresh_tokens.token = $1;
 
@@ -17,3 +19,28 @@ WHERE refresh_tokens.token = $1;
 UPDATE refresh_tokens
 SET revoked_at = NOW()
 WHERE token = $1;
+
+
+-- name: RevokeAllUserTokens :exec
+UPDATE refresh_tokens
+SET revoked_at = NOW()
+WHERE user_id = $1 AND revoked_at IS NULL;
+
+-- name: RevokeOtherUserTokens :exec
+UPDATE refresh_tokens
+SET revoked_at = NOW()
+WHERE user_id = $1 AND family_id <> $2 AND revoked_at IS NULL;
+
+-- name: PurgeExpiredTokens :execrows
+DELETE FROM refresh_tokens
+WHERE expires_at < NOW();
+
+-- name: RotateRefreshToken :execrows
+UPDATE refresh_tokens
+SET rotated_at = NOW()
+WHERE token = $1 AND rotated_at IS NULL AND revoked_at IS NULL;
+
+-- name: RevokeTokenFamily :exec
+UPDATE refresh_tokens
+SET revoked_at = NOW()
+WHERE family_id = $1 AND revoked_at IS NULL;
@@ -0,0 +1,42 @@
+-- name: CreateSession :exec
+INSERT INTO sessions(id, user_id, user_agent, ip, created_at, last_used_at, expires_at)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    NOW(),
+    NOW(),
+    $5
+);
+
+-- name: TouchSession :exec
+UPDATE sessions
+SET user_agent = $2,
+ip = $3,
+last_used_at = NOW()
+WHERE id = $1;
+
+-- name: GetLiveSessionsFromUserID :many
+SELECT *
+FROM sessions
+WHERE user_id = $1 AND EXISTS (
+    SELECT 1 FROM refresh_tokens
+    WHERE refresh_tokens.family_id = sessions.id
+    AND refresh_tokens.revoked_at IS NULL
+    AND refresh_tokens.rotated_at IS NULL
+    AND refresh_tokens.expires_at > NOW()
+)
+ORDER BY last_used_at DESC;
+
+-- name: RevokeUserSession :execrows
+UPDATE refresh_tokens
+SET revoked_at = NOW()
+WHERE family_id = $1 AND user_id = $2 AND revoked_at IS NULL;
+
+-- name: PurgeDeadSessions :execrows
+DELETE FROM sessions
+WHERE NOT EXISTS (
+    SELECT 1 FROM refresh_tokens
+    WHERE refresh_tokens.family_id = sessions.id
+);
@@ -0,0 +1,67 @@
+-- name: SetTOTPSecret :exec
+UPDATE users
+SET totp_secret = $2,
+totp_enabled_at = NULL,
+totp_last_step = NULL
+WHERE id = $1;
+
+-- name: EnableTOTP :exec
+UPDATE users
+SET totp_enabled_at = NOW(),
+totp_last_step = $2
+WHERE id = $1;
+
+-- name: UseTOTPStep :execrows
+UPDATE users
+SET totp_last_step = $2
+WHERE id = $1 AND (totp_last_step IS NULL OR totp_last_step < $2);
+
+-- name: DisableTOTP :exec
+UPDATE users
+SET totp_secret = NULL,
+totp_enabled_at = NULL,
+totp_last_step = NULL
+WHERE id = $1;
+
+-- name: CreateRecoveryCode :exec
+INSERT INTO totp_recovery_codes(code_hash, user_id, used_at, created_at)
+VALUES (
+    $1,
+    $2,
+    NULL,
+    NOW()
+);
+
+-- name: UseRecoveryCode :execrows
+UPDATE totp_recovery_codes
+SET used_at = NOW()
+WHERE code_hash = $1 AND user_id = $2 AND used_at IS NULL;
+
+-- name: CountUnusedRecoveryCodes :one
+SELECT COUNT(*) FROM totp_recovery_codes
+WHERE user_id = $1 AND used_at IS NULL;
+
+-- name: DeleteUserRecoveryCodes :exec
+DELETE FROM totp_recovery_codes
+WHERE user_id = $1;
+
+-- name: CreateLoginChallenge :exec
+INSERT INTO login_challenges(token_hash, user_id, expires_at, created_at)
+VALUES (
+    $1,
+    $2,
+    $3,
+    NOW()
+);
+
+-- name: GetLoginChallenge :one
+SELECT * FROM login_challenges
+WHERE token_hash = $1 AND expires_at > NOW();
+
+-- name: DeleteLoginChallenge :execrows
+DELETE FROM login_challenges
+WHERE token_hash = $1;
+
+-- name: PurgeExpiredLoginChallenges :execrows
+DELETE FROM login_challenges
+WHERE expires_at < NOW();
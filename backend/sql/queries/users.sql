@@ -21,13 +21,19 @@ WHERE email = $1;
 SELECT * FROM users
 WHERE id  = $1; 
 
+-- name: GetUserFromIDForUpdate :one
+SELECT * FROM users
+WHERE id = $1
+FOR UPDATE;
+
 -- name: UpdateUserParkingLot :exec
 UPDATE users
 SET parking_lot_id = $1
 WHERE id = $2;
 
 -- name: GetAllUsers :many
-SELECT * FROM users;
+SELECT * FROM users
+WHERE sqlc.narg('campus_id')::uuid IS NULL OR campus_id = sqlc.narg('campus_id');
 
 -- name: DeleteUser :execresult
 DELETE FROM users WHERE id = $1;
@@ -36,6 +42,100 @@ DELETE FROM users WHERE id = $1;
 UPDATE users
 SET name = $1,
 email = $2,
+email_verified_at = CASE WHEN email = $2 THEN email_verified_at ELSE NULL END,
 hashed_password = $3,
+license_plate = $4,
+campus_id = $5,
+updated_at = NOW()
+WHERE id = $6;
+
+-- name: GetUserFromLicensePlate :one
+SELECT * FROM users
+WHERE license_plate = $1;
+
+-- name: SetUserAdminCampus :exec
+UPDATE users
+SET role = 'admin',
+admin_campus_id = $1,
+updated_at = NOW()
+WHERE id = $2;
+
+-- name: SetUserRoleAndCampus :exec
+UPDATE users
+SET role = $1,
+admin_campus_id = $2,
+updated_at = NOW()
+WHERE id = $3;
+-- name: UpdateUserRole :execresult
+UPDATE users
+SET role = $1,
+admin_campus_id = NULL,
 updated_at = NOW()
-WHERE id = $4;
\ No newline at end of file
+WHERE id = $2;
+
+-- name: DisableUser :execresult
+UPDATE users
+SET disabled_at = NOW(),
+updated_at = NOW()
+WHERE id = $1;
+
+-- name: EnableUser :execresult
+UPDATE users
+SET disabled_at = NULL,
+updated_at = NOW()
+WHERE id = $1;
+
+-- name: MarkEmailVerified :exec
+UPDATE users
+SET email_verified_at = NOW(),
+updated_at = NOW()
+WHERE id = $1 AND email_verified_at IS NULL;
+
+-- name: MarkEmailVerifiedFromToken :one
+UPDATE users
+SET email_verified_at = COALESCE(users.email_verified_at, NOW()),
+updated_at = NOW()
+FROM email_verification_tokens
+WHERE email_verification_tokens.token_hash = $1
+AND email_verification_tokens.expires_at > NOW()
+AND users.id = email_verification_tokens.user_id
+AND users.email = email_verification_tokens.email
+RETURNING users.id;
+
+-- name: UpdateUserPassword :exec
+UPDATE users
+SET hashed_password = $1,
+updated_at = NOW()
+WHERE id = $2;
+
+-- name: GetTokenVersion :one
+SELECT token_version, disabled_at FROM users
+WHERE id = $1;
+
+-- name: BumpTokenVersion :exec
+UPDATE users
+SET token_version = token_version + 1
+WHERE id = $1;
+
+-- name: ScheduleUserDeletion :one
+UPDATE users
+SET deletion_scheduled_at = NOW() + make_interval(days => sqlc.arg('grace_days')::int),
+updated_at = NOW()
+WHERE id = sqlc.arg('id') AND deletion_scheduled_at IS NULL
+RETURNING deletion_scheduled_at;
+
+-- name: CancelUserDeletion :execrows
+UPDATE users
+SET deletion_scheduled_at = NULL,
+updated_at = NOW()
+WHERE id = $1 AND deletion_scheduled_at IS NOT NULL;
+
+-- name: GetUsersDueForDeletion :many
+SELECT id FROM users
+WHERE deletion_scheduled_at <= NOW()
+ORDER BY deletion_scheduled_at;
+
+-- name: LockUserDueForDeletion :one
+SELECT * FROM users
+WHERE id = $1 AND deletion_scheduled_at <= NOW()
+FOR UPDATE;
@@ -0,0 +1,21 @@
+-- name: CreateVerificationToken :exec
+INSERT INTO email_verification_tokens(token_hash, user_id, expires_at, created_at, email)
+VALUES (
+    $1,
+    $2,
+    $3,
+    NOW(),
+    $4
+);
+
+-- name: CountRecentVerificationTokens :one
+SELECT COUNT(*) FROM email_verification_tokens
+WHERE user_id = $1 AND created_at > $2;
+
+-- name: DeleteUserVerificationTokens :exec
+DELETE FROM email_verification_tokens
+WHERE user_id = $1;
+
+-- name: PurgeExpiredVerificationTokens :execrows
+DELETE FROM email_verification_tokens
+WHERE expires_at < NOW();
@@ -3,6 +3,12 @@ SELECT *
 FROM user_reviews_with_lot 
 WHERE lotid = $1;
 
+-- name: GetReviewsFromUserID :many
+SELECT *
+FROM user_reviews_with_lot
+WHERE userid = $1
+ORDER BY created_at;
+
 -- name: GetTopRatedLots :many
 SELECT *
 FROM top_rated_lot;
@@ -14,7 +20,8 @@ WHERE user_id = $1;
 
 -- name: GetCountOfLogsPerUser :many
 SELECT *
-FROM count_of_logs_per_user;
+FROM count_of_logs_per_user
+WHERE sqlc.narg('campus_id')::uuid IS NULL OR campus_id = sqlc.narg('campus_id');
 
 -- name: GetHighestLowestRatingsFromUserID :many
 SELECT *
@@ -28,16 +35,35 @@ WHERE lotid = $1;
 
 -- name: GetCountOfLogsPerLot :many
 SELECT *
-FROM count_of_logs_per_lot;
+FROM count_of_logs_per_lot
+WHERE sqlc.narg('campus_id')::uuid IS NULL OR campus_id = sqlc.narg('campus_id');
 
 -- name: GetCountOfReviewsPerUser :many
 SELECT *
-FROM count_of_reviews_per_user;
+FROM count_of_reviews_per_user
+WHERE sqlc.narg('campus_id')::uuid IS NULL OR campus_id = sqlc.narg('campus_id');
 
 -- name: GetCountOfReviewsPerLot :many
 SELECT *
-FROM count_of_review_per_lot;
+FROM count_of_review_per_lot
+WHERE sqlc.narg('campus_id')::uuid IS NULL OR campus_id = sqlc.narg('campus_id');
 
 -- name: GetFullLots :many
 SELECT *
-FROM full_parking_lots;
\ No newline at end of file
+FROM full_parking_lots
+WHERE sqlc.narg('campus_id')::uuid IS NULL OR campus_id = sqlc.narg('campus_id');
+
+-- name: GetTopRatedLotsFromCampusID :many
+SELECT lotid, lotname, averagerating
+FROM average_lot_ratings
+WHERE campus_id = $1 AND averagerating = (
+    SELECT MAX(averagerating)
+    FROM average_lot_ratings
+    WHERE campus_id = $1
+);
+
+-- name: GetDailyEntriesFromLotID :many
+SELECT *
+FROM daily_lot_entries
+WHERE lotid = $1
+ORDER BY day ASC;
@@ -0,0 +1,80 @@
+-- name: CreateLedgerAccount :exec
+INSERT INTO ledger_accounts(id, name, user_id, balance, created_at)
+VALUES (
+    gen_random_uuid(),
+    $1,
+    $2,
+    0,
+    NOW()
+)
+ON CONFLICT (name) DO NOTHING;
+
+-- name: GetLedgerAccountForUpdate :one
+SELECT *
+FROM ledger_accounts
+WHERE name = $1
+FOR UPDATE;
+
+-- name: UpdateLedgerAccountBalance :exec
+UPDATE ledger_accounts
+SET balance = $1
+WHERE id = $2;
+
+-- name: CreateLedgerTransaction :one
+INSERT INTO ledger_transactions(id, kind, description, reference, refund_of, created_at)
+VALUES (
+    gen_random_uuid(),
+    $1,
+    $2,
+    $3,
+    $4,
+    NOW()
+) RETURNING *;
+
+-- name: CreateLedgerEntry :one
+INSERT INTO ledger_entries(id, transaction_id, account_id, amount, balance, created_at)
+VALUES (
+    gen_random_uuid(),
+    $1,
+    $2,
+    $3,
+    $4,
+    NOW()
+) RETURNING *;
+
+-- name: GetWalletFromUserID :one
+SELECT *
+FROM ledger_accounts
+WHERE user_id = $1;
+
+-- name: GetWalletTransactionsFromUserID :many
+SELECT ledger_transactions.id, ledger_transactions.kind, ledger_transactions.description, ledger_transactions.reference, ledger_entries.amount, ledger_entries.balance, ledger_entries.created_at
+FROM ledger_entries
+INNER JOIN ledger_transactions ON ledger_entries.transaction_id = ledger_transactions.id
+INNER JOIN ledger_accounts ON ledger_entries.account_id = ledger_accounts.id
+WHERE ledger_accounts.user_id = $1
+ORDER BY ledger_entries.created_at DESC;
+
+-- name: GetRefundableCharge :one
+SELECT ledger_transactions.id, ledger_transactions.kind, ledger_accounts.name AS account_name, ledger_entries.amount
+FROM ledger_transactions
+INNER JOIN ledger_entries ON ledger_entries.transaction_id = ledger_transactions.id
+INNER JOIN ledger_accounts ON ledger_entries.account_id = ledger_accounts.id
+WHERE ledger_transactions.id = $1
+AND ledger_transactions.kind IN ('parking_fee', 'fine')
+AND ledger_entries.amount > 0
+AND EXISTS (
+    SELECT 1
+    FROM ledger_entries wallet_entries
+    INNER JOIN ledger_accounts wallets ON wallet_entries.account_id = wallets.id
+    WHERE wallet_entries.transaction_id = ledger_transactions.id
+    AND wallets.user_id = $2
+)
+FOR UPDATE OF ledger_transactions;
+
+-- name: GetRefundedAmount :one
+SELECT COALESCE(SUM(ledger_entries.amount), 0)::BIGINT AS refunded
+FROM ledger_transactions
+INNER JOIN ledger_entries ON ledger_entries.transaction_id = ledger_transactions.id
+WHERE ledger_transactions.refund_of = $1
+AND ledger_entries.amount > 0;
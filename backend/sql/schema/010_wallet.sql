@@ -0,0 +1,60 @@
+-- +goose Up
+CREATE TABLE ledger_accounts(
+    id UUID PRIMARY KEY,
+    name TEXT UNIQUE NOT NULL,
+    user_id UUID UNIQUE,
+    balance BIGINT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE SET NULL
+);
+
+CREATE TABLE ledger_transactions(
+    id UUID PRIMARY KEY,
+    kind TEXT CHECK (kind in ('topup', 'parking_fee', 'fine', 'refund')) NOT NULL,
+    description TEXT,
+    reference TEXT,
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE TABLE ledger_entries(
+    id UUID PRIMARY KEY,
+    transaction_id UUID NOT NULL,
+    account_id UUID NOT NULL,
+    amount BIGINT CHECK (amount <> 0) NOT NULL,
+    balance BIGINT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    FOREIGN KEY (transaction_id) REFERENCES ledger_transactions(id),
+    FOREIGN KEY (account_id) REFERENCES ledger_accounts(id)
+);
+
+CREATE INDEX ledger_entries_account_idx ON ledger_entries(account_id, created_at);
+
+-- +goose StatementBegin
+CREATE FUNCTION ledger_immutable() RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION 'ledger rows are immutable';
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TRIGGER ledger_transactions_immutable
+BEFORE UPDATE OR DELETE ON ledger_transactions
+FOR EACH ROW EXECUTE FUNCTION ledger_immutable();
+
+CREATE TRIGGER ledger_entries_immutable
+BEFORE UPDATE OR DELETE ON ledger_entries
+FOR EACH ROW EXECUTE FUNCTION ledger_immutable();
+
+INSERT INTO ledger_accounts(id, name, user_id, balance, created_at)
+VALUES
+(gen_random_uuid(), 'system:payment_provider', NULL, 0, NOW()),
+(gen_random_uuid(), 'system:parking_revenue', NULL, 0, NOW()),
+(gen_random_uuid(), 'system:fine_revenue', NULL, 0, NOW());
+
+
+
+-- +goose Down
+DROP TABLE ledger_entries;
+DROP TABLE ledger_transactions;
+DROP TABLE ledger_accounts;
+DROP FUNCTION ledger_immutable;
@@ -0,0 +1,56 @@
+-- +goose Up
+ALTER TABLE users
+DROP CONSTRAINT users_role_check;
+
+ALTER TABLE users
+ADD CONSTRAINT users_role_check CHECK (role in ('admin', 'user', 'enforcement'));
+
+ALTER TABLE users
+ADD COLUMN license_plate TEXT UNIQUE;
+
+CREATE TABLE citations(
+    id UUID PRIMARY KEY,
+    officer_id UUID,
+    user_id UUID,
+    license_plate TEXT,
+    parking_lot_id UUID NOT NULL,
+    parking_log_id UUID,
+    violation TEXT CHECK (violation in ('overstay', 'no_permit', 'wrong_lot')) NOT NULL,
+    notes TEXT,
+    status TEXT CHECK (status in ('issued', 'appealed', 'upheld', 'dismissed')) NOT NULL,
+    appeal_reason TEXT,
+    appealed_at TIMESTAMP,
+    resolved_by UUID,
+    resolved_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    CHECK (user_id IS NOT NULL OR license_plate IS NOT NULL),
+    FOREIGN KEY (officer_id) REFERENCES users(id) ON DELETE SET NULL,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (parking_lot_id) REFERENCES parkinglots(id) ON DELETE CASCADE,
+    FOREIGN KEY (parking_log_id) REFERENCES parking_logs(id) ON DELETE SET NULL,
+    FOREIGN KEY (resolved_by) REFERENCES users(id) ON DELETE SET NULL
+);
+
+CREATE TABLE citation_photos(
+    id UUID PRIMARY KEY,
+    citation_id UUID NOT NULL,
+    url TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    FOREIGN KEY (citation_id) REFERENCES citations(id) ON DELETE CASCADE
+);
+
+
+
+-- +goose Down
+DROP TABLE citation_photos;
+DROP TABLE citations;
+
+ALTER TABLE users
+DROP COLUMN license_plate;
+
+ALTER TABLE users
+DROP CONSTRAINT users_role_check;
+
+ALTER TABLE users
+ADD CONSTRAINT users_role_check CHECK (role in ('admin', 'user'));
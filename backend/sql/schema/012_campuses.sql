@@ -0,0 +1,149 @@
+-- +goose Up
+CREATE TABLE campuses(
+    id UUID PRIMARY KEY,
+    name TEXT UNIQUE NOT NULL,
+    created_at TIMESTAMP NOT NULL
+);
+
+INSERT INTO campuses(id, name, created_at)
+VALUES (gen_random_uuid(), 'North Oshawa', NOW());
+
+ALTER TABLE parkinglots
+ADD COLUMN campus_id UUID REFERENCES campuses(id) ON DELETE RESTRICT;
+
+UPDATE parkinglots
+SET campus_id = (SELECT id FROM campuses WHERE name = 'North Oshawa');
+
+ALTER TABLE parkinglots
+ALTER COLUMN campus_id SET NOT NULL;
+
+ALTER TABLE parkinglots
+DROP CONSTRAINT parkinglots_name_key;
+
+ALTER TABLE parkinglots
+ADD CONSTRAINT parkinglots_campus_name_key UNIQUE (campus_id, name);
+
+-- campus_id is the user's home campus, admin_campus_id restricts an admin to one campus
+ALTER TABLE users
+ADD COLUMN campus_id UUID REFERENCES campuses(id) ON DELETE SET NULL;
+
+ALTER TABLE users
+ADD COLUMN admin_campus_id UUID REFERENCES campuses(id) ON DELETE RESTRICT;
+
+CREATE OR REPLACE VIEW Top_Rated_Lot AS
+SELECT P.ID, P.Name, ROUND(AVG(R.Score), 2), P.Campus_ID
+FROM ParkingLots P
+JOIN Reviews R ON P.ID = R.Parking_Lot_ID
+GROUP BY P.ID, P.Name
+HAVING AVG(R.Score) >= ALL (
+    SELECT AVG(R2.Score)
+    FROM Reviews R2
+    JOIN ParkingLots P2 ON R2.Parking_Lot_ID = P2.ID
+    WHERE P2.ID <> P.ID
+    GROUP BY P2.ID
+);
+
+CREATE OR REPLACE VIEW Count_Of_Logs_Per_User AS
+SELECT U.ID AS UserID, U.Name AS UserName, COUNT(PL.ID) AS TotalEntries, U.Campus_ID
+FROM Users U 
+FULL OUTER JOIN Parking_Logs PL ON U.ID = PL.User_ID
+GROUP BY U.ID;
+
+CREATE OR REPLACE VIEW Average_Lot_Ratings AS
+SELECT P.ID AS LotID, P.Name AS LotName, ROUND(AVG(R.Score), 2) AS AverageRating, COUNT(R.User_ID) AS TotalReviews, P.Campus_ID
+FROM ParkingLots P
+LEFT JOIN Reviews R ON P.ID = R.Parking_Lot_ID
+GROUP BY P.ID
+ORDER BY AverageRating DESC;
+
+CREATE OR REPLACE VIEW Count_Of_Logs_Per_Lot AS
+SELECT P.ID AS LotID, P.Name AS LotName, COUNT(PL.ID) AS TotalEntries, P.Campus_ID
+FROM Parking_Logs PL
+JOIN ParkingLots P ON PL.Parking_Lot_ID = P.ID
+GROUP BY P.ID;
+
+CREATE OR REPLACE VIEW Count_Of_Reviews_Per_User AS
+SELECT U.ID AS UserID, U.Name AS UserName, COUNT(R.Parking_Lot_ID) AS TotalReviews, U.Campus_ID
+FROM Reviews R
+JOIN Users U ON R.USER_ID = U.ID
+GROUP BY U.ID;
+
+CREATE OR REPLACE VIEW Count_Of_Review_Per_Lot AS
+SELECT P.ID AS LotID, P.Name AS LotName, COUNT(R.User_ID) AS TotalReviews, P.Campus_ID
+FROM Reviews R
+JOIN ParkingLots P ON R.Parking_Lot_ID = P.ID
+GROUP BY P.ID;
+
+CREATE OR REPLACE VIEW Full_Parking_Lots AS 
+SELECT ID, Name, Slots, OccupiedSlots, Campus_ID
+FROM ParkingLots
+WHERE OccupiedSlots = Slots;
+
+-- +goose Down
+DROP VIEW Top_Rated_Lot, Count_Of_Logs_Per_User, Average_Lot_Ratings, Count_Of_Logs_Per_Lot, Count_Of_Reviews_Per_User, Count_Of_Review_Per_Lot, Full_Parking_Lots;
+
+CREATE VIEW Top_Rated_Lot AS
+SELECT P.ID, P.Name, ROUND(AVG(R.Score), 2)
+FROM ParkingLots P
+JOIN Reviews R ON P.ID = R.Parking_Lot_ID
+GROUP BY P.ID, P.Name
+HAVING AVG(R.Score) >= ALL (
+    SELECT AVG(R2.Score)
+    FROM Reviews R2
+    JOIN ParkingLots P2 ON R2.Parking_Lot_ID = P2.ID
+    WHERE P2.ID <> P.ID
+    GROUP BY P2.ID
+);
+
+CREATE VIEW Count_Of_Logs_Per_User AS
+SELECT U.ID AS UserID, U.Name AS UserName, COUNT(PL.ID) AS TotalEntries
+FROM Users U 
+FULL OUTER JOIN Parking_Logs PL ON U.ID = PL.User_ID
+GROUP BY U.ID;
+
+CREATE VIEW Average_Lot_Ratings AS
+SELECT P.ID AS LotID, P.Name AS LotName, ROUND(AVG(R.Score), 2) AS AverageRating, COUNT(R.User_ID) AS TotalReviews
+FROM ParkingLots P
+LEFT JOIN Reviews R ON P.ID = R.Parking_Lot_ID
+GROUP BY P.ID
+ORDER BY AverageRating DESC;
+
+CREATE VIEW Count_Of_Logs_Per_Lot AS
+SELECT P.ID AS LotID, P.Name AS LotName, COUNT(PL.ID) AS TotalEntries
+FROM Parking_Logs PL
+JOIN ParkingLots P ON PL.Parking_Lot_ID = P.ID
+GROUP BY P.ID;
+
+CREATE VIEW Count_Of_Reviews_Per_User AS
+SELECT U.ID AS UserID, U.Name AS UserName, COUNT(R.Parking_Lot_ID) AS TotalReviews
+FROM Reviews R
+JOIN Users U ON R.USER_ID = U.ID
+GROUP BY U.ID;
+
+CREATE VIEW Count_Of_Review_Per_Lot AS
+SELECT P.ID AS LotID, P.Name AS LotName, COUNT(R.User_ID) AS TotalReviews
+FROM Reviews R
+JOIN ParkingLots P ON R.Parking_Lot_ID = P.ID
+GROUP BY P.ID;
+
+CREATE VIEW Full_Parking_Lots AS 
+SELECT ID, Name, Slots, OccupiedSlots
+FROM ParkingLots
+WHERE OccupiedSlots = Slots;
+
+ALTER TABLE users
+DROP COLUMN admin_campus_id;
+
+ALTER TABLE users
+DROP COLUMN campus_id;
+
+ALTER TABLE parkinglots
+DROP CONSTRAINT parkinglots_campus_name_key;
+
+ALTER TABLE parkinglots
+ADD CONSTRAINT parkinglots_name_key UNIQUE (name);
+
+ALTER TABLE parkinglots
+DROP COLUMN campus_id;
+
+DROP TABLE campuses;
@@ -0,0 +1,9 @@
+-- +goose Up
+ALTER TABLE users
+ADD COLUMN disabled_at TIMESTAMP;
+
+
+
+-- +goose Down
+ALTER TABLE users
+DROP COLUMN disabled_at;
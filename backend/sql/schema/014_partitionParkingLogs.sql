@@ -0,0 +1,185 @@
+-- +goose Up
+-- the views reading parking_logs have to be recreated on the partitioned table
+DROP VIEW Avg_Parking_Time_Per_User, Count_Of_Logs_Per_User, Count_Of_Logs_Per_Lot;
+
+-- a primary key on a partitioned table has to include the partition key, so
+-- parking_logs(id) can no longer be referenced
+ALTER TABLE citations
+DROP CONSTRAINT citations_parking_log_id_fkey;
+
+ALTER TABLE parking_logs RENAME TO parking_logs_unpartitioned;
+
+CREATE TABLE parking_logs(
+    id UUID NOT NULL,
+    user_id UUID NOT NULL,
+    parking_lot_id UUID NOT NULL,
+    event_type TEXT CHECK (event_type in ('entry', 'exit')) NOT NULL,
+    time TIMESTAMP NOT NULL,
+    PRIMARY KEY (id, time),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (parking_lot_id) REFERENCES parkinglots(id) ON DELETE CASCADE
+) PARTITION BY RANGE (time);
+
+CREATE INDEX parking_logs_parking_lot_id_time_idx ON parking_logs(parking_lot_id, time);
+CREATE INDEX parking_logs_user_id_idx ON parking_logs(user_id);
+
+-- catches rows outside of every monthly partition until their month is created
+CREATE TABLE parking_logs_default PARTITION OF parking_logs DEFAULT;
+
+-- +goose StatementBegin
+CREATE FUNCTION create_parking_logs_partition(month TIMESTAMP) RETURNS VOID AS $$
+DECLARE
+    start_time TIMESTAMP := date_trunc('month', month);
+    end_time TIMESTAMP := date_trunc('month', month) + INTERVAL '1 month';
+    partition_name TEXT := 'parking_logs_' || to_char(month, 'YYYY_MM');
+BEGIN
+    IF to_regclass(partition_name) IS NOT NULL THEN
+        RETURN;
+    END IF;
+
+    -- rows of that month in the default partition would block the new partition
+    CREATE TEMP TABLE parking_logs_moved AS
+    SELECT * FROM parking_logs_default
+    WHERE time >= start_time AND time < end_time;
+
+    DELETE FROM parking_logs_default
+    WHERE time >= start_time AND time < end_time;
+
+    EXECUTE format(
+        'CREATE TABLE %I PARTITION OF parking_logs FOR VALUES FROM (%L) TO (%L)',
+        partition_name, start_time, end_time
+    );
+
+    INSERT INTO parking_logs SELECT * FROM parking_logs_moved;
+    DROP TABLE parking_logs_moved;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE FUNCTION drop_parking_logs_partition(month TIMESTAMP) RETURNS VOID AS $$
+DECLARE
+    partition_name TEXT := 'parking_logs_' || to_char(month, 'YYYY_MM');
+BEGIN
+    IF to_regclass(partition_name) IS NULL THEN
+        RETURN;
+    END IF;
+
+    EXECUTE format('ALTER TABLE parking_logs DETACH PARTITION %I', partition_name);
+    EXECUTE format('DROP TABLE %I', partition_name);
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+DO $$
+DECLARE
+    month TIMESTAMP;
+BEGIN
+    FOR month IN
+        SELECT DISTINCT date_trunc('month', time) FROM parking_logs_unpartitioned
+        UNION
+        SELECT generate_series(date_trunc('month', LOCALTIMESTAMP), date_trunc('month', LOCALTIMESTAMP) + INTERVAL '2 months', INTERVAL '1 month')
+    LOOP
+        PERFORM create_parking_logs_partition(month);
+    END LOOP;
+END;
+$$;
+-- +goose StatementEnd
+
+INSERT INTO parking_logs
+SELECT * FROM parking_logs_unpartitioned;
+
+DROP TABLE parking_logs_unpartitioned;
+
+CREATE VIEW Avg_Parking_Time_Per_User AS
+WITH MatchedSessions AS (
+    SELECT Entry.User_ID, Entry.Parking_Lot_ID, Entry.Time AS Entry_Time,
+    (
+        SELECT MIN(Exit.Time)
+        FROM Parking_Logs Exit
+        WHERE Exit.User_ID = Entry.User_ID AND Exit.Parking_Lot_ID = Entry.Parking_Lot_ID AND Exit.Event_Type = 'exit' AND Exit.Time > Entry.Time
+    ) AS Exit_Time
+    FROM Parking_Logs Entry
+    WHERE Entry.Event_Type = 'entry'
+)
+SELECT U.ID AS User_ID, U.Name AS User_Name, ROUND(AVG(EXTRACT(EPOCH FROM (M.Exit_Time - M.Entry_Time)) / 60), 2) AS Avg_Minutes_Parked
+FROM Users U
+JOIN MatchedSessions M ON U.ID = M.User_ID 
+WHERE M.Exit_Time IS NOT NULL
+GROUP BY U.ID
+ORDER BY Avg_Minutes_Parked;
+
+CREATE VIEW Count_Of_Logs_Per_User AS
+SELECT U.ID AS UserID, U.Name AS UserName, COUNT(PL.ID) AS TotalEntries, U.Campus_ID
+FROM Users U 
+FULL OUTER JOIN Parking_Logs PL ON U.ID = PL.User_ID
+GROUP BY U.ID;
+
+CREATE VIEW Count_Of_Logs_Per_Lot AS
+SELECT P.ID AS LotID, P.Name AS LotName, COUNT(PL.ID) AS TotalEntries, P.Campus_ID
+FROM Parking_Logs PL
+JOIN ParkingLots P ON PL.Parking_Lot_ID = P.ID
+GROUP BY P.ID;
+
+
+
+-- +goose Down
+DROP VIEW Avg_Parking_Time_Per_User, Count_Of_Logs_Per_User, Count_Of_Logs_Per_Lot;
+
+ALTER TABLE parking_logs RENAME TO parking_logs_partitioned;
+
+CREATE TABLE parking_logs(
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL,
+    parking_lot_id UUID NOT NULL,
+    event_type TEXT CHECK (event_type in ('entry', 'exit')) NOT NULL,
+    time TIMESTAMP NOT NULL,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (parking_lot_id) REFERENCES parkinglots(id) ON DELETE CASCADE
+);
+
+INSERT INTO parking_logs
+SELECT * FROM parking_logs_partitioned;
+
+DROP TABLE parking_logs_partitioned;
+
+DROP FUNCTION create_parking_logs_partition(TIMESTAMP);
+DROP FUNCTION drop_parking_logs_partition(TIMESTAMP);
+
+UPDATE citations
+SET parking_log_id = NULL
+WHERE parking_log_id NOT IN (SELECT id FROM parking_logs);
+
+ALTER TABLE citations
+ADD CONSTRAINT citations_parking_log_id_fkey FOREIGN KEY (parking_log_id) REFERENCES parking_logs(id) ON DELETE SET NULL;
+
+CREATE VIEW Avg_Parking_Time_Per_User AS
+WITH MatchedSessions AS (
+    SELECT Entry.User_ID, Entry.Parking_Lot_ID, Entry.Time AS Entry_Time,
+    (
+        SELECT MIN(Exit.Time)
+        FROM Parking_Logs Exit
+        WHERE Exit.User_ID = Entry.User_ID AND Exit.Parking_Lot_ID = Entry.Parking_Lot_ID AND Exit.Event_Type = 'exit' AND Exit.Time > Entry.Time
+    ) AS Exit_Time
+    FROM Parking_Logs Entry
+    WHERE Entry.Event_Type = 'entry'
+)
+SELECT U.ID AS User_ID, U.Name AS User_Name, ROUND(AVG(EXTRACT(EPOCH FROM (M.Exit_Time - M.Entry_Time)) / 60), 2) AS Avg_Minutes_Parked
+FROM Users U
+JOIN MatchedSessions M ON U.ID = M.User_ID 
+WHERE M.Exit_Time IS NOT NULL
+GROUP BY U.ID
+ORDER BY Avg_Minutes_Parked;
+
+CREATE VIEW Count_Of_Logs_Per_User AS
+SELECT U.ID AS UserID, U.Name AS UserName, COUNT(PL.ID) AS TotalEntries, U.Campus_ID
+FROM Users U 
+FULL OUTER JOIN Parking_Logs PL ON U.ID = PL.User_ID
+GROUP BY U.ID;
+
+CREATE VIEW Count_Of_Logs_Per_Lot AS
+SELECT P.ID AS LotID, P.Name AS LotName, COUNT(PL.ID) AS TotalEntries, P.Campus_ID
+FROM Parking_Logs PL
+JOIN ParkingLots P ON PL.Parking_Lot_ID = P.ID
+GROUP BY P.ID;
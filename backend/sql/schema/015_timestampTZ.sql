@@ -0,0 +1,397 @@
+-- +goose Up
+-- existing values were written by NOW() as wall clock times of the session
+-- time zone, converting without USING reads them back in that same zone
+DROP VIEW User_Highest_Lowest_Ratings, User_Reviews_With_Lot, Avg_Parking_Time_Per_User, Count_Of_Logs_Per_User, Count_Of_Logs_Per_Lot;
+
+ALTER TABLE tests
+ALTER COLUMN created_at TYPE TIMESTAMPTZ,
+ALTER COLUMN updated_at TYPE TIMESTAMPTZ;
+
+ALTER TABLE users
+ALTER COLUMN created_at TYPE TIMESTAMPTZ,
+ALTER COLUMN updated_at TYPE TIMESTAMPTZ,
+ALTER COLUMN disabled_at TYPE TIMESTAMPTZ;
+
+ALTER TABLE reviews
+ALTER COLUMN created_at TYPE TIMESTAMPTZ,
+ALTER COLUMN updated_at TYPE TIMESTAMPTZ;
+
+ALTER TABLE refresh_tokens
+ALTER COLUMN expires_at TYPE TIMESTAMPTZ,
+ALTER COLUMN revoked_at TYPE TIMESTAMPTZ;
+
+ALTER TABLE ledger_accounts
+ALTER COLUMN created_at TYPE TIMESTAMPTZ;
+
+ALTER TABLE ledger_transactions
+ALTER COLUMN created_at TYPE TIMESTAMPTZ;
+
+ALTER TABLE ledger_entries
+ALTER COLUMN created_at TYPE TIMESTAMPTZ;
+
+ALTER TABLE citations
+ALTER COLUMN appealed_at TYPE TIMESTAMPTZ,
+ALTER COLUMN resolved_at TYPE TIMESTAMPTZ,
+ALTER COLUMN created_at TYPE TIMESTAMPTZ,
+ALTER COLUMN updated_at TYPE TIMESTAMPTZ;
+
+ALTER TABLE citation_photos
+ALTER COLUMN created_at TYPE TIMESTAMPTZ;
+
+ALTER TABLE campuses
+ALTER COLUMN created_at TYPE TIMESTAMPTZ;
+
+-- IANA name of the zone the campus' days are counted in
+ALTER TABLE campuses
+ADD COLUMN time_zone TEXT NOT NULL DEFAULT 'America/Toronto';
+
+-- the partition key of parking_logs cannot change type, so the table and its
+-- partitions are rebuilt
+CREATE TABLE parking_logs_local AS
+SELECT * FROM parking_logs;
+
+DROP TABLE parking_logs;
+
+DROP FUNCTION create_parking_logs_partition(TIMESTAMP);
+DROP FUNCTION drop_parking_logs_partition(TIMESTAMP);
+
+CREATE TABLE parking_logs(
+    id UUID NOT NULL,
+    user_id UUID NOT NULL,
+    parking_lot_id UUID NOT NULL,
+    event_type TEXT CHECK (event_type in ('entry', 'exit')) NOT NULL,
+    time TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (id, time),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (parking_lot_id) REFERENCES parkinglots(id) ON DELETE CASCADE
+) PARTITION BY RANGE (time);
+
+CREATE INDEX parking_logs_parking_lot_id_time_idx ON parking_logs(parking_lot_id, time);
+CREATE INDEX parking_logs_user_id_idx ON parking_logs(user_id);
+
+CREATE TABLE parking_logs_default PARTITION OF parking_logs DEFAULT;
+
+-- partitions hold UTC calendar months so they do not depend on the session
+-- time zone
+-- +goose StatementBegin
+CREATE FUNCTION create_parking_logs_partition(month TIMESTAMPTZ) RETURNS VOID AS $$
+DECLARE
+    start_time TIMESTAMPTZ := date_trunc('month', month AT TIME ZONE 'UTC') AT TIME ZONE 'UTC';
+    end_time TIMESTAMPTZ := (date_trunc('month', month AT TIME ZONE 'UTC') + INTERVAL '1 month') AT TIME ZONE 'UTC';
+    partition_name TEXT := 'parking_logs_' || to_char(month AT TIME ZONE 'UTC', 'YYYY_MM');
+BEGIN
+    IF to_regclass(partition_name) IS NOT NULL THEN
+        RETURN;
+    END IF;
+
+    -- rows of that month in the default partition would block the new partition
+    CREATE TEMP TABLE parking_logs_moved AS
+    SELECT * FROM parking_logs_default
+    WHERE time >= start_time AND time < end_time;
+
+    DELETE FROM parking_logs_default
+    WHERE time >= start_time AND time < end_time;
+
+    EXECUTE format(
+        'CREATE TABLE %I PARTITION OF parking_logs FOR VALUES FROM (%L) TO (%L)',
+        partition_name, start_time, end_time
+    );
+
+    INSERT INTO parking_logs SELECT * FROM parking_logs_moved;
+    DROP TABLE parking_logs_moved;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE FUNCTION drop_parking_logs_partition(month TIMESTAMPTZ) RETURNS VOID AS $$
+DECLARE
+    partition_name TEXT := 'parking_logs_' || to_char(month AT TIME ZONE 'UTC', 'YYYY_MM');
+BEGIN
+    IF to_regclass(partition_name) IS NULL THEN
+        RETURN;
+    END IF;
+
+    EXECUTE format('ALTER TABLE parking_logs DETACH PARTITION %I', partition_name);
+    EXECUTE format('DROP TABLE %I', partition_name);
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+DO $$
+DECLARE
+    month TIMESTAMPTZ;
+BEGIN
+    FOR month IN
+        SELECT DISTINCT date_trunc('month', time::TIMESTAMPTZ AT TIME ZONE 'UTC') AT TIME ZONE 'UTC' FROM parking_logs_local
+        UNION
+        SELECT generate_series(date_trunc('month', NOW() AT TIME ZONE 'UTC'), date_trunc('month', NOW() AT TIME ZONE 'UTC') + INTERVAL '2 months', INTERVAL '1 month') AT TIME ZONE 'UTC'
+    LOOP
+        PERFORM create_parking_logs_partition(month);
+    END LOOP;
+END;
+$$;
+-- +goose StatementEnd
+
+INSERT INTO parking_logs
+SELECT * FROM parking_logs_local;
+
+DROP TABLE parking_logs_local;
+
+CREATE VIEW User_Reviews_With_Lot AS
+SELECT U.ID AS UserID, U.Name AS UserName, P.ID AS LotID, P.Name AS LotName, R.Title, R.Description, R.Score, R.Created_At, R.Updated_At
+FROM Reviews R
+JOIN Users U ON R.User_ID = U.ID
+JOIN ParkingLots P ON R.Parking_Lot_ID = P.ID;
+
+CREATE VIEW User_Highest_Lowest_Ratings AS
+SELECT UserID, UserName, LotID, LotName, Title, Description, Score, Created_At, Updated_At, 'Highest' AS ReviewType
+FROM User_Reviews_With_Lot URLV
+WHERE (UserID, LotID) = (
+    SELECT R.User_ID, R.Parking_Lot_ID
+    FROM Reviews R
+    WHERE R.User_ID = URLV.UserID
+    ORDER BY R.Score DESC, R.Created_At DESC
+    LIMIT 1
+)
+UNION ALL
+SELECT UserID, UserName, LotID, LotName, Title, Description, Score, Created_At, Updated_At, 'Lowest' AS ReviewType
+FROM User_Reviews_With_Lot URLV
+WHERE (UserID, LotID) = (
+    SELECT R.User_ID, R.Parking_Lot_ID
+    FROM Reviews R
+    WHERE R.User_ID = URLV.UserID
+    ORDER BY R.Score ASC, R.Created_At DESC
+    LIMIT 1
+);
+
+CREATE VIEW Avg_Parking_Time_Per_User AS
+WITH MatchedSessions AS (
+    SELECT Entry.User_ID, Entry.Parking_Lot_ID, Entry.Time AS Entry_Time,
+    (
+        SELECT MIN(Exit.Time)
+        FROM Parking_Logs Exit
+        WHERE Exit.User_ID = Entry.User_ID AND Exit.Parking_Lot_ID = Entry.Parking_Lot_ID AND Exit.Event_Type = 'exit' AND Exit.Time > Entry.Time
+    ) AS Exit_Time
+    FROM Parking_Logs Entry
+    WHERE Entry.Event_Type = 'entry'
+)
+SELECT U.ID AS User_ID, U.Name AS User_Name, ROUND(AVG(EXTRACT(EPOCH FROM (M.Exit_Time - M.Entry_Time)) / 60), 2) AS Avg_Minutes_Parked
+FROM Users U
+JOIN MatchedSessions M ON U.ID = M.User_ID
+WHERE M.Exit_Time IS NOT NULL
+GROUP BY U.ID
+ORDER BY Avg_Minutes_Parked;
+
+CREATE VIEW Count_Of_Logs_Per_User AS
+SELECT U.ID AS UserID, U.Name AS UserName, COUNT(PL.ID) AS TotalEntries, U.Campus_ID
+FROM Users U
+FULL OUTER JOIN Parking_Logs PL ON U.ID = PL.User_ID
+GROUP BY U.ID;
+
+CREATE VIEW Count_Of_Logs_Per_Lot AS
+SELECT P.ID AS LotID, P.Name AS LotName, COUNT(PL.ID) AS TotalEntries, P.Campus_ID
+FROM Parking_Logs PL
+JOIN ParkingLots P ON PL.Parking_Lot_ID = P.ID
+GROUP BY P.ID;
+
+-- entries per lot per day, days start at midnight in the campus' time zone
+CREATE VIEW Daily_Lot_Entries AS
+SELECT P.ID AS LotID, (PL.Time AT TIME ZONE C.Time_Zone)::DATE AS Day, COUNT(PL.ID) AS TotalEntries, P.Campus_ID
+FROM Parking_Logs PL
+JOIN ParkingLots P ON PL.Parking_Lot_ID = P.ID
+JOIN Campuses C ON P.Campus_ID = C.ID
+WHERE PL.Event_Type = 'entry'
+GROUP BY P.ID, Day;
+
+-- +goose Down
+DROP VIEW Daily_Lot_Entries, User_Highest_Lowest_Ratings, User_Reviews_With_Lot, Avg_Parking_Time_Per_User, Count_Of_Logs_Per_User, Count_Of_Logs_Per_Lot;
+
+CREATE TABLE parking_logs_utc AS
+SELECT * FROM parking_logs;
+
+DROP TABLE parking_logs;
+
+DROP FUNCTION create_parking_logs_partition(TIMESTAMPTZ);
+DROP FUNCTION drop_parking_logs_partition(TIMESTAMPTZ);
+
+CREATE TABLE parking_logs(
+    id UUID NOT NULL,
+    user_id UUID NOT NULL,
+    parking_lot_id UUID NOT NULL,
+    event_type TEXT CHECK (event_type in ('entry', 'exit')) NOT NULL,
+    time TIMESTAMP NOT NULL,
+    PRIMARY KEY (id, time),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (parking_lot_id) REFERENCES parkinglots(id) ON DELETE CASCADE
+) PARTITION BY RANGE (time);
+
+CREATE INDEX parking_logs_parking_lot_id_time_idx ON parking_logs(parking_lot_id, time);
+CREATE INDEX parking_logs_user_id_idx ON parking_logs(user_id);
+
+CREATE TABLE parking_logs_default PARTITION OF parking_logs DEFAULT;
+
+-- +goose StatementBegin
+CREATE FUNCTION create_parking_logs_partition(month TIMESTAMP) RETURNS VOID AS $$
+DECLARE
+    start_time TIMESTAMP := date_trunc('month', month);
+    end_time TIMESTAMP := date_trunc('month', month) + INTERVAL '1 month';
+    partition_name TEXT := 'parking_logs_' || to_char(month, 'YYYY_MM');
+BEGIN
+    IF to_regclass(partition_name) IS NOT NULL THEN
+        RETURN;
+    END IF;
+
+    -- rows of that month in the default partition would block the new partition
+    CREATE TEMP TABLE parking_logs_moved AS
+    SELECT * FROM parking_logs_default
+    WHERE time >= start_time AND time < end_time;
+
+    DELETE FROM parking_logs_default
+    WHERE time >= start_time AND time < end_time;
+
+    EXECUTE format(
+        'CREATE TABLE %I PARTITION OF parking_logs FOR VALUES FROM (%L) TO (%L)',
+        partition_name, start_time, end_time
+    );
+
+    INSERT INTO parking_logs SELECT * FROM parking_logs_moved;
+    DROP TABLE parking_logs_moved;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE FUNCTION drop_parking_logs_partition(month TIMESTAMP) RETURNS VOID AS $$
+DECLARE
+    partition_name TEXT := 'parking_logs_' || to_char(month, 'YYYY_MM');
+BEGIN
+    IF to_regclass(partition_name) IS NULL THEN
+        RETURN;
+    END IF;
+
+    EXECUTE format('ALTER TABLE parking_logs DETACH PARTITION %I', partition_name);
+    EXECUTE format('DROP TABLE %I', partition_name);
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+DO $$
+DECLARE
+    month TIMESTAMP;
+BEGIN
+    FOR month IN
+        SELECT DISTINCT date_trunc('month', time::TIMESTAMP) FROM parking_logs_utc
+        UNION
+        SELECT generate_series(date_trunc('month', LOCALTIMESTAMP), date_trunc('month', LOCALTIMESTAMP) + INTERVAL '2 months', INTERVAL '1 month')
+    LOOP
+        PERFORM create_parking_logs_partition(month);
+    END LOOP;
+END;
+$$;
+-- +goose StatementEnd
+
+INSERT INTO parking_logs
+SELECT * FROM parking_logs_utc;
+
+DROP TABLE parking_logs_utc;
+
+ALTER TABLE campuses
+DROP COLUMN time_zone;
+
+ALTER TABLE campuses
+ALTER COLUMN created_at TYPE TIMESTAMP;
+
+ALTER TABLE citation_photos
+ALTER COLUMN created_at TYPE TIMESTAMP;
+
+ALTER TABLE citations
+ALTER COLUMN appealed_at TYPE TIMESTAMP,
+ALTER COLUMN resolved_at TYPE TIMESTAMP,
+ALTER COLUMN created_at TYPE TIMESTAMP,
+ALTER COLUMN updated_at TYPE TIMESTAMP;
+
+ALTER TABLE ledger_entries
+ALTER COLUMN created_at TYPE TIMESTAMP;
+
+ALTER TABLE ledger_transactions
+ALTER COLUMN created_at TYPE TIMESTAMP;
+
+ALTER TABLE ledger_accounts
+ALTER COLUMN created_at TYPE TIMESTAMP;
+
+ALTER TABLE refresh_tokens
+ALTER COLUMN expires_at TYPE TIMESTAMP,
+ALTER COLUMN revoked_at TYPE TIMESTAMP;
+
+ALTER TABLE reviews
+ALTER COLUMN created_at TYPE TIMESTAMP,
+ALTER COLUMN updated_at TYPE TIMESTAMP;
+
+ALTER TABLE users
+ALTER COLUMN created_at TYPE TIMESTAMP,
+ALTER COLUMN updated_at TYPE TIMESTAMP,
+ALTER COLUMN disabled_at TYPE TIMESTAMP;
+
+ALTER TABLE tests
+ALTER COLUMN created_at TYPE TIMESTAMP,
+ALTER COLUMN updated_at TYPE TIMESTAMP;
+
+CREATE VIEW User_Reviews_With_Lot AS
+SELECT U.ID AS UserID, U.Name AS UserName, P.ID AS LotID, P.Name AS LotName, R.Title, R.Description, R.Score, R.Created_At, R.Updated_At
+FROM Reviews R
+JOIN Users U ON R.User_ID = U.ID
+JOIN ParkingLots P ON R.Parking_Lot_ID = P.ID;
+
+CREATE VIEW User_Highest_Lowest_Ratings AS
+SELECT UserID, UserName, LotID, LotName, Title, Description, Score, Created_At, Updated_At, 'Highest' AS ReviewType
+FROM User_Reviews_With_Lot URLV
+WHERE (UserID, LotID) = (
+    SELECT R.User_ID, R.Parking_Lot_ID
+    FROM Reviews R
+    WHERE R.User_ID = URLV.UserID
+    ORDER BY R.Score DESC, R.Created_At DESC
+    LIMIT 1
+)
+UNION ALL
+SELECT UserID, UserName, LotID, LotName, Title, Description, Score, Created_At, Updated_At, 'Lowest' AS ReviewType
+FROM User_Reviews_With_Lot URLV
+WHERE (UserID, LotID) = (
+    SELECT R.User_ID, R.Parking_Lot_ID
+    FROM Reviews R
+    WHERE R.User_ID = URLV.UserID
+    ORDER BY R.Score ASC, R.Created_At DESC
+    LIMIT 1
+);
+
+CREATE VIEW Avg_Parking_Time_Per_User AS
+WITH MatchedSessions AS (
+    SELECT Entry.User_ID, Entry.Parking_Lot_ID, Entry.Time AS Entry_Time,
+    (
+        SELECT MIN(Exit.Time)
+        FROM Parking_Logs Exit
+        WHERE Exit.User_ID = Entry.User_ID AND Exit.Parking_Lot_ID = Entry.Parking_Lot_ID AND Exit.Event_Type = 'exit' AND Exit.Time > Entry.Time
+    ) AS Exit_Time
+    FROM Parking_Logs Entry
+    WHERE Entry.Event_Type = 'entry'
+)
+SELECT U.ID AS User_ID, U.Name AS User_Name, ROUND(AVG(EXTRACT(EPOCH FROM (M.Exit_Time - M.Entry_Time)) / 60), 2) AS Avg_Minutes_Parked
+FROM Users U
+JOIN MatchedSessions M ON U.ID = M.User_ID
+WHERE M.Exit_Time IS NOT NULL
+GROUP BY U.ID
+ORDER BY Avg_Minutes_Parked;
+
+CREATE VIEW Count_Of_Logs_Per_User AS
+SELECT U.ID AS UserID, U.Name AS UserName, COUNT(PL.ID) AS TotalEntries, U.Campus_ID
+FROM Users U
+FULL OUTER JOIN Parking_Logs PL ON U.ID = PL.User_ID
+GROUP BY U.ID;
+
+CREATE VIEW Count_Of_Logs_Per_Lot AS
+SELECT P.ID AS LotID, P.Name AS LotName, COUNT(PL.ID) AS TotalEntries, P.Campus_ID
+FROM Parking_Logs PL
+JOIN ParkingLots P ON PL.Parking_Lot_ID = P.ID
+GROUP BY P.ID;
@@ -0,0 +1,24 @@
+-- +goose Up
+ALTER TABLE users
+ADD COLUMN email_verified_at TIMESTAMPTZ;
+
+-- accounts created before verification existed keep working
+UPDATE users
+SET email_verified_at = created_at;
+
+-- only a sha256 hash of the emailed token is stored
+CREATE TABLE email_verification_tokens(
+    token_hash TEXT PRIMARY KEY,
+    user_id UUID NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX email_verification_tokens_user_id_idx ON email_verification_tokens(user_id);
+
+-- +goose Down
+DROP TABLE email_verification_tokens;
+
+ALTER TABLE users
+DROP COLUMN email_verified_at;
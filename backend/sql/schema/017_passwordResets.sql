@@ -0,0 +1,15 @@
+-- +goose Up
+-- only a sha256 hash of the emailed token is stored
+CREATE TABLE password_reset_tokens(
+    token_hash TEXT PRIMARY KEY,
+    user_id UUID NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    used_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX password_reset_tokens_user_id_created_at_idx ON password_reset_tokens(user_id, created_at);
+
+-- +goose Down
+DROP TABLE password_reset_tokens;
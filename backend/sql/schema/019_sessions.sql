@@ -0,0 +1,27 @@
+-- +goose Up
+-- a session is one login, its id is the family id of its refresh tokens
+CREATE TABLE sessions(
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL,
+    user_agent TEXT NOT NULL,
+    ip TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    last_used_at TIMESTAMPTZ NOT NULL,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX sessions_user_id_idx ON sessions(user_id);
+
+-- logins from before sessions existed have no device information
+INSERT INTO sessions(id, user_id, user_agent, ip, created_at, last_used_at)
+SELECT DISTINCT ON (family_id) family_id, user_id, '', '', NOW(), NOW()
+FROM refresh_tokens;
+
+ALTER TABLE refresh_tokens
+ADD CONSTRAINT refresh_tokens_family_id_fkey FOREIGN KEY (family_id) REFERENCES sessions(id) ON DELETE CASCADE;
+
+-- +goose Down
+ALTER TABLE refresh_tokens
+DROP CONSTRAINT refresh_tokens_family_id_fkey;
+
+DROP TABLE sessions;
@@ -0,0 +1,14 @@
+-- +goose Up
+-- failed logins per account (kind 'account', value is the email) and per
+-- client (kind 'ip'), locked_until is set once too many failures piled up
+CREATE TABLE login_throttles(
+    kind TEXT CHECK (kind in ('account', 'ip')) NOT NULL,
+    value TEXT NOT NULL,
+    failures INTEGER NOT NULL,
+    locked_until TIMESTAMPTZ,
+    last_failure_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (kind, value)
+);
+
+-- +goose Down
+DROP TABLE login_throttles;
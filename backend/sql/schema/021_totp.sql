@@ -0,0 +1,45 @@
+-- +goose Up
+-- the secret has to be readable to check codes, so it is stored as is.
+-- totp_last_step is the step of the last accepted code, codes of that step or
+-- older are refused so a seen code cannot be replayed
+ALTER TABLE users
+ADD COLUMN totp_secret TEXT,
+ADD COLUMN totp_enabled_at TIMESTAMPTZ,
+ADD COLUMN totp_last_step BIGINT;
+
+-- only a sha256 hash of each recovery code is stored
+CREATE TABLE totp_recovery_codes(
+    code_hash TEXT PRIMARY KEY,
+    user_id UUID NOT NULL,
+    used_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX totp_recovery_codes_user_id_idx ON totp_recovery_codes(user_id);
+
+-- a password that was accepted, waiting for the second factor
+CREATE TABLE login_challenges(
+    token_hash TEXT PRIMARY KEY,
+    user_id UUID NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- admins have to enroll, logging them out makes them do it on their next login
+UPDATE refresh_tokens
+SET revoked_at = NOW()
+WHERE revoked_at IS NULL AND user_id IN (
+    SELECT id FROM users WHERE role = 'admin'
+);
+
+-- +goose Down
+DROP TABLE login_challenges;
+
+DROP TABLE totp_recovery_codes;
+
+ALTER TABLE users
+DROP COLUMN totp_last_step,
+DROP COLUMN totp_enabled_at,
+DROP COLUMN totp_secret;
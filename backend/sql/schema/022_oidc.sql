@@ -0,0 +1,30 @@
+-- +goose Up
+-- an account at the campus identity provider, subject is the provider's
+-- stable id for it. email is what the provider reported on the last login
+CREATE TABLE user_identities(
+    issuer TEXT NOT NULL,
+    subject TEXT NOT NULL,
+    user_id UUID NOT NULL,
+    email TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    last_login_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (issuer, subject),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX user_identities_user_id_idx ON user_identities(user_id);
+
+-- a login sent to the identity provider, waiting for its callback. only a
+-- sha256 hash of the state is stored, the state itself is in a cookie
+CREATE TABLE oidc_login_states(
+    state_hash TEXT PRIMARY KEY,
+    code_verifier TEXT NOT NULL,
+    nonce TEXT NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL
+);
+
+-- +goose Down
+DROP TABLE oidc_login_states;
+
+DROP TABLE user_identities;
@@ -0,0 +1,29 @@
+-- +goose Up
+ALTER TABLE users
+DROP CONSTRAINT users_role_check;
+
+ALTER TABLE users
+ADD CONSTRAINT users_role_check CHECK (role in ('admin', 'user', 'enforcement', 'lot_manager'));
+
+-- the lots a lot_manager may edit
+CREATE TABLE lot_managers(
+    user_id UUID NOT NULL,
+    parking_lot_id UUID NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (user_id, parking_lot_id),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (parking_lot_id) REFERENCES parkingLots(id) ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE lot_managers;
+
+UPDATE users
+SET role = 'user'
+WHERE role = 'lot_manager';
+
+ALTER TABLE users
+DROP CONSTRAINT users_role_check;
+
+ALTER TABLE users
+ADD CONSTRAINT users_role_check CHECK (role in ('admin', 'user', 'enforcement'));
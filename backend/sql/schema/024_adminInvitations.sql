@@ -0,0 +1,20 @@
+-- +goose Up
+-- replaces the ADMINCODE signup. only a sha256 hash of the token is stored,
+-- email restricts who can redeem it when set
+CREATE TABLE admin_invitations(
+    id UUID PRIMARY KEY,
+    token_hash TEXT NOT NULL UNIQUE,
+    role TEXT NOT NULL CHECK (role in ('admin', 'enforcement')),
+    admin_campus_id UUID REFERENCES campuses(id) ON DELETE CASCADE,
+    email TEXT,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    redeemed_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    redeemed_at TIMESTAMPTZ,
+    revoked_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL,
+    CHECK (admin_campus_id IS NULL OR role = 'admin')
+);
+
+-- +goose Down
+DROP TABLE admin_invitations;
@@ -0,0 +1,21 @@
+-- +goose Up
+-- keys for integrations like the campus signage, they act with the permissions
+-- of their creator but only within scopes (space separated permissions).
+-- only a sha256 hash of the key is stored, key_prefix is kept to find it and to
+-- tell keys apart
+CREATE TABLE api_keys(
+    id UUID PRIMARY KEY,
+    name TEXT NOT NULL,
+    key_prefix TEXT NOT NULL UNIQUE,
+    key_hash TEXT NOT NULL,
+    scopes TEXT NOT NULL,
+    created_by UUID NOT NULL,
+    expires_at TIMESTAMPTZ,
+    last_used_at TIMESTAMPTZ,
+    revoked_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL,
+    FOREIGN KEY (created_by) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE api_keys;
@@ -0,0 +1,9 @@
+-- +goose Up
+-- access tokens carry the version they were issued with and stop working once
+-- it is bumped, on a role change, password change, disable or logout everywhere
+ALTER TABLE users
+ADD COLUMN token_version INTEGER NOT NULL DEFAULT 0;
+
+-- +goose Down
+ALTER TABLE users
+DROP COLUMN token_version;
@@ -0,0 +1,41 @@
+-- +goose Up
+-- append only record of admin actions. actor_id and target_id are not foreign
+-- keys so entries outlive the users and lots they are about. before and after
+-- hold the target as JSON, null when it did not exist
+CREATE TABLE audit_log(
+    id UUID PRIMARY KEY,
+    actor_id UUID,
+    actor_role TEXT,
+    api_key_id UUID,
+    action TEXT NOT NULL,
+    target_type TEXT NOT NULL,
+    target_id UUID,
+    campus_id UUID,
+    before JSONB NOT NULL,
+    after JSONB NOT NULL,
+    ip TEXT NOT NULL,
+    user_agent TEXT NOT NULL,
+    method TEXT NOT NULL,
+    path TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX audit_log_created_at_idx ON audit_log(created_at);
+CREATE INDEX audit_log_actor_idx ON audit_log(actor_id, created_at);
+CREATE INDEX audit_log_target_idx ON audit_log(target_id, created_at);
+
+-- +goose StatementBegin
+CREATE FUNCTION audit_log_immutable() RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION 'audit log entries are immutable';
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TRIGGER audit_log_immutable
+BEFORE UPDATE OR DELETE ON audit_log
+FOR EACH ROW EXECUTE FUNCTION audit_log_immutable();
+
+-- +goose Down
+DROP TABLE audit_log;
+DROP FUNCTION audit_log_immutable;
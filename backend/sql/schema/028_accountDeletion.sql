@@ -0,0 +1,84 @@
+-- +goose Up
+-- users deleting their account are only deleted once deletion_scheduled_at
+-- has passed, until then they can cancel
+ALTER TABLE users
+ADD COLUMN deletion_scheduled_at TIMESTAMPTZ;
+
+CREATE INDEX users_deletion_scheduled_at_idx ON users(deletion_scheduled_at)
+WHERE deletion_scheduled_at IS NOT NULL;
+
+-- parking logs outlive their user without the user_id, so the lot statistics
+-- stay the same after a user is deleted
+ALTER TABLE parking_logs
+ALTER COLUMN user_id DROP NOT NULL;
+
+ALTER TABLE parking_logs
+DROP CONSTRAINT parking_logs_user_id_fkey;
+
+ALTER TABLE parking_logs
+ADD CONSTRAINT parking_logs_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE SET NULL;
+
+-- citations are records of the campus and are kept too, an open fine cannot be
+-- wiped by deleting the account. the citation keeps the user's plate, a
+-- citation of a user without one is kept with neither (issuing one still needs
+-- either a user or a plate)
+ALTER TABLE citations
+DROP CONSTRAINT citations_check;
+
+ALTER TABLE citations
+DROP CONSTRAINT citations_user_id_fkey;
+
+ALTER TABLE citations
+ADD CONSTRAINT citations_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE SET NULL;
+
+-- +goose StatementBegin
+CREATE FUNCTION keep_citation_plates() RETURNS TRIGGER AS $$
+BEGIN
+    UPDATE citations
+    SET license_plate = OLD.license_plate
+    WHERE user_id = OLD.id AND license_plate IS NULL;
+
+    RETURN OLD;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TRIGGER users_keep_citation_plates
+BEFORE DELETE ON users
+FOR EACH ROW EXECUTE FUNCTION keep_citation_plates();
+
+-- +goose Down
+DROP TRIGGER users_keep_citation_plates ON users;
+
+DROP FUNCTION keep_citation_plates();
+
+-- the citations of deleted users go with them like before
+DELETE FROM citations
+WHERE user_id IS NULL AND license_plate IS NULL;
+
+ALTER TABLE citations
+DROP CONSTRAINT citations_user_id_fkey;
+
+ALTER TABLE citations
+ADD CONSTRAINT citations_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE;
+
+ALTER TABLE citations
+ADD CONSTRAINT citations_check CHECK (user_id IS NOT NULL OR license_plate IS NOT NULL);
+
+-- the anonymized logs cannot be given back to their users
+DELETE FROM parking_logs
+WHERE user_id IS NULL;
+
+ALTER TABLE parking_logs
+DROP CONSTRAINT parking_logs_user_id_fkey;
+
+ALTER TABLE parking_logs
+ADD CONSTRAINT parking_logs_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE;
+
+ALTER TABLE parking_logs
+ALTER COLUMN user_id SET NOT NULL;
+
+DROP INDEX users_deletion_scheduled_at_idx;
+
+ALTER TABLE users
+DROP COLUMN deletion_scheduled_at;
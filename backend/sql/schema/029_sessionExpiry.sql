@@ -0,0 +1,15 @@
+-- +goose Up
+-- a session ends at expires_at however often it is refreshed, its refresh
+-- tokens never outlive it
+ALTER TABLE sessions
+ADD COLUMN expires_at TIMESTAMPTZ;
+
+UPDATE sessions
+SET expires_at = created_at + INTERVAL '30 days';
+
+ALTER TABLE sessions
+ALTER COLUMN expires_at SET NOT NULL;
+
+-- +goose Down
+ALTER TABLE sessions
+DROP COLUMN expires_at;
@@ -0,0 +1,10 @@
+-- +goose Up
+-- months whose archive was restored, the retention policy leaves them in
+-- postgres until they are archived again by hand
+CREATE TABLE restored_log_months(
+    month TIMESTAMPTZ PRIMARY KEY,
+    restored_at TIMESTAMPTZ NOT NULL
+);
+
+-- +goose Down
+DROP TABLE restored_log_months;
@@ -0,0 +1,63 @@
+-- +goose Up
+-- archiving detaches the partition first and reads the detached table, so no
+-- log can be written to the month between the archive and the drop. a table
+-- left detached by an interrupted archive is archived by the next run
+-- +goose StatementBegin
+CREATE FUNCTION detach_parking_logs_partition(month TIMESTAMPTZ) RETURNS VOID AS $$
+DECLARE
+    partition_name TEXT := 'parking_logs_' || to_char(month AT TIME ZONE 'UTC', 'YYYY_MM');
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1 FROM pg_inherits
+        WHERE inhrelid = to_regclass(partition_name) AND inhparent = 'parking_logs'::regclass
+    ) THEN
+        RETURN;
+    END IF;
+
+    EXECUTE format('ALTER TABLE parking_logs DETACH PARTITION %I', partition_name);
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE FUNCTION detached_parking_logs(month TIMESTAMPTZ) RETURNS SETOF parking_logs AS $$
+DECLARE
+    partition_name TEXT := 'parking_logs_' || to_char(month AT TIME ZONE 'UTC', 'YYYY_MM');
+BEGIN
+    RETURN QUERY EXECUTE format(
+        'SELECT id, user_id, parking_lot_id, event_type, time FROM %I ORDER BY time',
+        partition_name
+    );
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION drop_parking_logs_partition(month TIMESTAMPTZ) RETURNS VOID AS $$
+DECLARE
+    partition_name TEXT := 'parking_logs_' || to_char(month AT TIME ZONE 'UTC', 'YYYY_MM');
+BEGIN
+    PERFORM detach_parking_logs_partition(month);
+    EXECUTE format('DROP TABLE IF EXISTS %I', partition_name);
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION drop_parking_logs_partition(month TIMESTAMPTZ) RETURNS VOID AS $$
+DECLARE
+    partition_name TEXT := 'parking_logs_' || to_char(month AT TIME ZONE 'UTC', 'YYYY_MM');
+BEGIN
+    IF to_regclass(partition_name) IS NULL THEN
+        RETURN;
+    END IF;
+
+    EXECUTE format('ALTER TABLE parking_logs DETACH PARTITION %I', partition_name);
+    EXECUTE format('DROP TABLE %I', partition_name);
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+DROP FUNCTION detached_parking_logs(TIMESTAMPTZ);
+DROP FUNCTION detach_parking_logs_partition(TIMESTAMPTZ);
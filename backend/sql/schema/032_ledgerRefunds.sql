@@ -0,0 +1,13 @@
+-- +goose Up
+-- a refund names the charge it gives back, so it is taken from the account the
+-- charge went to and never returns more than was charged
+ALTER TABLE ledger_transactions
+ADD COLUMN refund_of UUID REFERENCES ledger_transactions(id);
+
+CREATE INDEX ledger_transactions_refund_of_idx ON ledger_transactions(refund_of);
+
+-- +goose Down
+DROP INDEX ledger_transactions_refund_of_idx;
+
+ALTER TABLE ledger_transactions
+DROP COLUMN refund_of;
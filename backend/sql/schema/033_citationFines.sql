@@ -0,0 +1,9 @@
+-- +goose Up
+-- the fine a citation posted to the cited user's wallet, reversed when the
+-- citation is dismissed on appeal
+ALTER TABLE citations
+ADD COLUMN fine_transaction_id UUID REFERENCES ledger_transactions(id);
+
+-- +goose Down
+ALTER TABLE citations
+DROP COLUMN fine_transaction_id;
@@ -0,0 +1,37 @@
+-- +goose Up
+-- an archive that fails after the detach attaches the partition again, so the
+-- logs of the month do not drop out of every query until the next run
+-- +goose StatementBegin
+CREATE FUNCTION attach_parking_logs_partition(month TIMESTAMPTZ) RETURNS VOID AS $$
+DECLARE
+    start_time TIMESTAMPTZ := date_trunc('month', month AT TIME ZONE 'UTC') AT TIME ZONE 'UTC';
+    end_time TIMESTAMPTZ := (date_trunc('month', month AT TIME ZONE 'UTC') + INTERVAL '1 month') AT TIME ZONE 'UTC';
+    partition_name TEXT := 'parking_logs_' || to_char(month AT TIME ZONE 'UTC', 'YYYY_MM');
+BEGIN
+    IF to_regclass(partition_name) IS NULL OR EXISTS (
+        SELECT 1 FROM pg_inherits
+        WHERE inhrelid = to_regclass(partition_name) AND inhparent = 'parking_logs'::regclass
+    ) THEN
+        RETURN;
+    END IF;
+
+    -- logs of the month written while it was detached went to the default
+    -- partition and would block the attach
+    EXECUTE format(
+        'INSERT INTO %I SELECT * FROM parking_logs_default WHERE time >= %L AND time < %L',
+        partition_name, start_time, end_time
+    );
+
+    DELETE FROM parking_logs_default
+    WHERE time >= start_time AND time < end_time;
+
+    EXECUTE format(
+        'ALTER TABLE parking_logs ATTACH PARTITION %I FOR VALUES FROM (%L) TO (%L)',
+        partition_name, start_time, end_time
+    );
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- +goose Down
+DROP FUNCTION attach_parking_logs_partition(TIMESTAMPTZ);
@@ -0,0 +1,12 @@
+-- +goose Up
+-- a verification token only verifies the address it was sent to. tokens from
+-- before could verify an address the user changed to afterwards, they are
+-- dropped and users ask for a new email
+DELETE FROM email_verification_tokens;
+
+ALTER TABLE email_verification_tokens
+ADD COLUMN email TEXT NOT NULL;
+
+-- +goose Down
+ALTER TABLE email_verification_tokens
+DROP COLUMN email;
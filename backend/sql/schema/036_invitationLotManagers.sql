@@ -0,0 +1,17 @@
+-- +goose Up
+-- lot managers can be invited too, their lots are given after the signup
+ALTER TABLE admin_invitations
+DROP CONSTRAINT admin_invitations_role_check;
+
+ALTER TABLE admin_invitations
+ADD CONSTRAINT admin_invitations_role_check CHECK (role in ('admin', 'enforcement', 'lot_manager'));
+
+-- +goose Down
+DELETE FROM admin_invitations
+WHERE role = 'lot_manager';
+
+ALTER TABLE admin_invitations
+DROP CONSTRAINT admin_invitations_role_check;
+
+ALTER TABLE admin_invitations
+ADD CONSTRAINT admin_invitations_role_check CHECK (role in ('admin', 'enforcement'));
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLoadTimeZone(t *testing.T) {
+	tests := []struct {
+		name    string
+		zone    string
+		wantErr bool
+	}{
+		{"iana name", "America/Toronto", false},
+		{"utc", "UTC", false},
+		{"empty", "", true},
+		{"server local", "Local", true},
+		{"unknown", "America/Nowhere", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loc, err := loadTimeZone(tt.zone)
+
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("loadTimeZone(%q) = %v, want an error", tt.zone, loc)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("loadTimeZone(%q) returned %v", tt.zone, err)
+			}
+
+			if loc.String() != tt.zone {
+				t.Fatalf("loadTimeZone(%q) = %s", tt.zone, loc)
+			}
+		})
+	}
+}
+
+func TestParseExportTimeDST(t *testing.T) {
+	toronto, err := loadTimeZone("America/Toronto")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		raw  string
+		end  bool
+		want string
+	}{
+		// 2025-03-09 is 23 hours long, clocks jump from 2:00 to 3:00
+		{"spring forward start", "2025-03-09", false, "2025-03-09T05:00:00Z"},
+		{"spring forward end", "2025-03-09", true, "2025-03-10T04:00:00Z"},
+		// 2025-11-02 is 25 hours long, clocks fall back from 2:00 to 1:00
+		{"fall back start", "2025-11-02", false, "2025-11-02T04:00:00Z"},
+		{"fall back end", "2025-11-02", true, "2025-11-03T05:00:00Z"},
+		{"regular day start", "2025-07-01", false, "2025-07-01T04:00:00Z"},
+		{"rfc 3339 is not moved", "2025-03-09T02:30:00Z", false, "2025-03-09T02:30:00Z"},
+		{"rfc 3339 end is not extended", "2025-11-02T01:30:00-05:00", true, "2025-11-02T06:30:00Z"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseExportTime(tt.raw, tt.end, toronto)
+			if err != nil {
+				t.Fatalf("parseExportTime(%q) returned %v", tt.raw, err)
+			}
+
+			if !got.Valid || got.Time.UTC().Format(time.RFC3339) != tt.want {
+				t.Fatalf("parseExportTime(%q, %v) = %s, want %s", tt.raw, tt.end, got.Time.UTC().Format(time.RFC3339), tt.want)
+			}
+		})
+	}
+}
+
+func TestParseExportTimeDayLength(t *testing.T) {
+	toronto, err := loadTimeZone("America/Toronto")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		day  string
+		want time.Duration
+	}{
+		{"2025-03-09", 23 * time.Hour},
+		{"2025-11-02", 25 * time.Hour},
+		{"2025-07-01", 24 * time.Hour},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.day, func(t *testing.T) {
+			from, err := parseExportTime(tt.day, false, toronto)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			to, err := parseExportTime(tt.day, true, toronto)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := to.Time.Sub(from.Time); got != tt.want {
+				t.Fatalf("%s is %s long, want %s", tt.day, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseExportTimeInvalid(t *testing.T) {
+	tests := []string{"2025-13-01", "09/03/2025", "yesterday"}
+
+	for _, raw := range tests {
+		t.Run(raw, func(t *testing.T) {
+			if _, err := parseExportTime(raw, false, time.UTC); err == nil {
+				t.Fatalf("parseExportTime(%q) did not return an error", raw)
+			}
+		})
+	}
+
+	if got, err := parseExportTime("", false, time.UTC); err != nil || got.Valid {
+		t.Fatalf("parseExportTime(\"\") = %v, %v, want no time", got, err)
+	}
+}
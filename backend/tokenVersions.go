@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/Shayaan-Kashif/Database-Project/internal/database"
+	"github.com/google/uuid"
+)
+
+// how long a token version is trusted without asking the database. Changes
+// made by this server apply at once, changes made by another instance or the
+// command line within tokenVersionTTL.
+const tokenVersionTTL = 30 * time.Second
+
+var (
+	errTokenRevoked = errors.New("token has been revoked")
+	errUserDisabled = errors.New("account disabled")
+	errUserNotFound = errors.New("user no longer exists")
+)
+
+// tokenVersions caches users.token_version and disabled_at so authenticate
+// does not read the user on every request. It is kept in memory, so every
+// server instance has its own cache.
+type tokenVersions struct {
+	mu      sync.Mutex
+	entries map[uuid.UUID]tokenVersion
+	// bumped by forget, a read that started before it is not cached. Kept
+	// for every user ever forgotten, one counter each
+	generations map[uuid.UUID]uint64
+}
+
+type tokenVersion struct {
+	version  int32
+	disabled bool
+	missing  bool
+	loadedAt time.Time
+}
+
+func newTokenVersions() *tokenVersions {
+	return &tokenVersions{
+		entries:     map[uuid.UUID]tokenVersion{},
+		generations: map[uuid.UUID]uint64{},
+	}
+}
+
+// forget drops the cached version of userID, the next request reads it again.
+// Call it after the transaction that bumped the version is committed.
+func (t *tokenVersions) forget(userID uuid.UUID) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.entries, userID)
+	t.generations[userID]++
+}
+
+func (t *tokenVersions) get(ctx context.Context, db *database.Queries, userID uuid.UUID, now time.Time) (tokenVersion, error) {
+	t.mu.Lock()
+	entry, ok := t.entries[userID]
+	generation := t.generations[userID]
+	t.mu.Unlock()
+
+	if ok && now.Sub(entry.loadedAt) < tokenVersionTTL {
+		return entry, nil
+	}
+
+	row, err := db.GetTokenVersion(ctx, userID)
+
+	if err == sql.ErrNoRows {
+		entry = tokenVersion{missing: true, loadedAt: now}
+	} else if err != nil {
+		return tokenVersion{}, err
+	} else {
+		entry = tokenVersion{version: row.TokenVersion, disabled: row.DisabledAt.Valid, loadedAt: now}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	//forgotten while the query ran, it may have read the version from before
+	if t.generations[userID] != generation {
+		return entry, nil
+	}
+
+	//drop stale entries, so the map does not grow forever
+	if len(t.entries) > 10000 {
+		for id, e := range t.entries {
+			if now.Sub(e.loadedAt) >= tokenVersionTTL {
+				delete(t.entries, id)
+			}
+		}
+	}
+
+	t.entries[userID] = entry
+
+	return entry, nil
+}
+
+// checkTokenVersion returns errTokenRevoked, errUserDisabled or
+// errUserNotFound when an access token of userID made with version should no
+// longer be accepted. BumpTokenVersion revokes every access token of a user.
+func (cfg *apiConfig) checkTokenVersion(ctx context.Context, userID uuid.UUID, version int32) error {
+	entry, err := cfg.tokenVersions.get(ctx, cfg.dbQueries, userID, time.Now())
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case entry.missing:
+		return errUserNotFound
+	case entry.disabled:
+		return errUserDisabled
+	case entry.version != version:
+		return errTokenRevoked
+	}
+
+	return nil
+}
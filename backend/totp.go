@@ -0,0 +1,645 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/Shayaan-Kashif/Database-Project/internal/auth"
+	"github.com/Shayaan-Kashif/Database-Project/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	// time between a correct password and the second factor
+	loginChallengeTTL = 5 * time.Minute
+	recoveryCodeCount = 10
+	totpIssuer        = "ParkingGO"
+)
+
+// needsSecondFactor reports whether a login of the user has to go through
+// /api/login/totp. Admins cannot skip it, without TOTP they have to enroll.
+func needsSecondFactor(userDB database.User) bool {
+	return userDB.TotpEnabledAt.Valid || userDB.Role == "admin"
+}
+
+// createLoginChallenge returns the mfaToken that is exchanged for the session
+// at /api/login/totp together with a code.
+func (cfg *apiConfig) createLoginChallenge(ctx context.Context, userID uuid.UUID) (string, error) {
+	token, err := auth.MakeRefreshToken()
+
+	if err != nil {
+		return "", err
+	}
+
+	err = cfg.dbQueries.CreateLoginChallenge(ctx, database.CreateLoginChallengeParams{
+		TokenHash: auth.HashToken(token),
+		UserID:    userID,
+		ExpiresAt: time.Now().Add(loginChallengeTTL),
+	})
+
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// startLoginChallenge answers a correct password of a user with TOTP with the
+// mfaToken for the second step.
+func (cfg *apiConfig) startLoginChallenge(res http.ResponseWriter, req *http.Request, userDB database.User) {
+	token, err := cfg.createLoginChallenge(req.Context(), userDB.ID)
+
+	if err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondWithJSON(res, http.StatusOK, struct {
+		MFARequired        bool   `json:"mfaRequired"`
+		MFAToken           string `json:"mfaToken"`
+		EnrollmentRequired bool   `json:"enrollmentRequired"`
+	}{
+		MFARequired:        true,
+		MFAToken:           token,
+		EnrollmentRequired: !userDB.TotpEnabledAt.Valid,
+	})
+}
+
+// setMFACookie hands the mfaToken of a single sign-on login to the frontend,
+// only /api/login/totp and /api/login/totp/enroll receive it.
+func setMFACookie(res http.ResponseWriter, mfaToken string) {
+	http.SetCookie(res, &http.Cookie{
+		Name:     "mfa_token",
+		Value:    mfaToken,
+		HttpOnly: true,
+		Secure:   false, //only for dev side, true for production
+		SameSite: http.SameSiteLaxMode,
+		Path:     "/api/login/totp",
+		MaxAge:   int(loginChallengeTTL.Seconds()),
+	})
+}
+
+func clearMFACookie(res http.ResponseWriter) {
+	http.SetCookie(res, &http.Cookie{
+		Name:     "mfa_token",
+		Value:    "",
+		HttpOnly: true,
+		Secure:   false, //only for dev side, true for production
+		SameSite: http.SameSiteLaxMode,
+		Path:     "/api/login/totp",
+		MaxAge:   -1,
+	})
+}
+
+// requestMFAToken is the mfaToken of the request body or, after a single
+// sign-on login, of the mfa_token cookie.
+func requestMFAToken(req *http.Request, fromBody *string) (string, bool) {
+	if fromBody != nil {
+		return *fromBody, true
+	}
+
+	cookie, err := req.Cookie("mfa_token")
+	if err != nil || cookie.Value == "" {
+		return "", false
+	}
+
+	return cookie.Value, true
+}
+
+// challengeUser returns the user waiting on the login challenge of mfaToken.
+// ok is false if a response has already been written.
+func (cfg *apiConfig) challengeUser(res http.ResponseWriter, req *http.Request, mfaToken string) (database.User, bool) {
+	challenge, err := cfg.dbQueries.GetLoginChallenge(req.Context(), auth.HashToken(mfaToken))
+
+	if err == sql.ErrNoRows {
+		respondWithError(res, http.StatusUnauthorized, "invalid or expired mfaToken, login again")
+		return database.User{}, false
+	} else if err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return database.User{}, false
+	}
+
+	userDB, err := cfg.dbQueries.GetUserFromID(req.Context(), challenge.UserID)
+
+	if err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return database.User{}, false
+	}
+
+	if userDB.DisabledAt.Valid {
+		respondWithError(res, http.StatusForbidden, "account disabled")
+		return database.User{}, false
+	}
+
+	return userDB, true
+}
+
+// checkSecondFactor checks a TOTP code or, if code is empty, a recovery code of
+// a user with TOTP enabled. Both are single use. Wrong codes count as failed
+// logins so they are throttled like passwords, ok is false if a response has
+// already been written.
+func (cfg *apiConfig) checkSecondFactor(res http.ResponseWriter, req *http.Request, userDB database.User, code, recoveryCode string) bool {
+	if cfg.secondFactorLockedOut(res, req, userDB) {
+		return false
+	}
+
+	used := int64(0)
+	var err error
+
+	if code != "" {
+		step, valid := auth.CheckTOTP(userDB.TotpSecret.String, code, time.Now())
+
+		//the step is only taken if it is newer than the last one used, a code
+		//that was seen once cannot be replayed
+		if valid {
+			used, err = cfg.dbQueries.UseTOTPStep(req.Context(), database.UseTOTPStepParams{
+				ID:           userDB.ID,
+				TotpLastStep: sql.NullInt64{Int64: step, Valid: true},
+			})
+		}
+	} else if recoveryCode != "" {
+		used, err = cfg.dbQueries.UseRecoveryCode(req.Context(), database.UseRecoveryCodeParams{
+			CodeHash: auth.HashToken(auth.NormalizeRecoveryCode(recoveryCode)),
+			UserID:   userDB.ID,
+		})
+
+		if err == nil && used > 0 {
+			auditAfter(actorContext(req.Context(), userDB.ID, userDB.Role), cfg.dbQueries, auditEntry{
+				Action:     "totp.recovery_code_use",
+				TargetType: "user",
+				TargetID:   userDB.ID,
+				CampusID:   userDB.CampusID,
+			})
+		}
+	}
+
+	if err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return false
+	}
+
+	if used == 0 {
+		cfg.rejectSecondFactor(res, req, userDB)
+		return false
+	}
+
+	return true
+}
+
+// secondFactorLockedOut responds with 429 and reports true while the account
+// or the ip is locked out of login.
+func (cfg *apiConfig) secondFactorLockedOut(res http.ResponseWriter, req *http.Request, userDB database.User) bool {
+	wait, err := cfg.loginRetryAfter(req.Context(), userDB.Email, clientIP(req))
+
+	if err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return true
+	}
+
+	if wait > 0 {
+		respondLockedOut(res, wait)
+		return true
+	}
+
+	return false
+}
+
+// rejectSecondFactor counts a wrong code as a failed login and responds 401.
+func (cfg *apiConfig) rejectSecondFactor(res http.ResponseWriter, req *http.Request, userDB database.User) {
+	if err := cfg.recordLoginFailure(req.Context(), userDB.Email, clientIP(req)); err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondWithError(res, http.StatusUnauthorized, "invalid code")
+}
+
+// issueRecoveryCodes replaces the recovery codes of the user with new ones.
+func issueRecoveryCodes(ctx context.Context, queries *database.Queries, userID uuid.UUID) ([]string, error) {
+	if err := queries.DeleteUserRecoveryCodes(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, 0, recoveryCodeCount)
+
+	for range recoveryCodeCount {
+		code, err := auth.MakeRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+
+		err = queries.CreateRecoveryCode(ctx, database.CreateRecoveryCodeParams{
+			CodeHash: auth.HashToken(auth.NormalizeRecoveryCode(code)),
+			UserID:   userID,
+		})
+
+		if err != nil {
+			return nil, err
+		}
+
+		codes = append(codes, code)
+	}
+
+	return codes, nil
+}
+
+// beginEnrollment stores a new pending secret for the user and answers with it,
+// TOTP is only enabled once a code of it has been confirmed.
+func (cfg *apiConfig) beginEnrollment(res http.ResponseWriter, req *http.Request, userDB database.User) {
+	if userDB.TotpEnabledAt.Valid {
+		respondWithError(res, http.StatusConflict, "two-factor authentication already enabled")
+		return
+	}
+
+	secret, err := auth.MakeTOTPSecret()
+
+	if err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	err = cfg.dbQueries.SetTOTPSecret(req.Context(), database.SetTOTPSecretParams{
+		ID:         userDB.ID,
+		TotpSecret: sql.NullString{String: secret, Valid: true},
+	})
+
+	if err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondWithJSON(res, http.StatusOK, struct {
+		Secret string `json:"secret"`
+		URI    string `json:"uri"`
+	}{
+		Secret: secret,
+		URI:    auth.TOTPURI(secret, totpIssuer, userDB.Email),
+	})
+}
+
+// confirmEnrollment enables TOTP if code belongs to the pending secret of the
+// user and returns the new recovery codes. Wrong codes count as failed logins
+// like in checkSecondFactor, ok is false if a response has already been
+// written.
+func (cfg *apiConfig) confirmEnrollment(res http.ResponseWriter, req *http.Request, userDB database.User, code string) ([]string, bool) {
+	if userDB.TotpEnabledAt.Valid {
+		respondWithError(res, http.StatusConflict, "two-factor authentication already enabled")
+		return nil, false
+	}
+
+	if !userDB.TotpSecret.Valid {
+		respondWithError(res, http.StatusBadRequest, "no two-factor enrollment started")
+		return nil, false
+	}
+
+	if cfg.secondFactorLockedOut(res, req, userDB) {
+		return nil, false
+	}
+
+	step, valid := auth.CheckTOTP(userDB.TotpSecret.String, code, time.Now())
+
+	if !valid {
+		cfg.rejectSecondFactor(res, req, userDB)
+		return nil, false
+	}
+
+	tx, err := cfg.db.BeginTx(req.Context(), nil)
+
+	if err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return nil, false
+	}
+
+	defer tx.Rollback()
+
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	err = qtx.EnableTOTP(req.Context(), database.EnableTOTPParams{
+		ID:           userDB.ID,
+		TotpLastStep: sql.NullInt64{Int64: step, Valid: true},
+	})
+
+	if err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return nil, false
+	}
+
+	codes, err := issueRecoveryCodes(req.Context(), qtx, userDB.ID)
+
+	if err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return nil, false
+	}
+
+	err = audit(actorContext(req.Context(), userDB.ID, userDB.Role), qtx, auditEntry{
+		Action:     "totp.enable",
+		TargetType: "user",
+		TargetID:   userDB.ID,
+		CampusID:   userDB.CampusID,
+	})
+
+	if err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return nil, false
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return nil, false
+	}
+
+	return codes, true
+}
+
+func (cfg *apiConfig) loginEnrollTOTP(res http.ResponseWriter, req *http.Request) {
+	reqStruct := struct {
+		MFAToken *string `json:"mfaToken"`
+	}{}
+
+	if err := decodeJSON(req, &reqStruct); err != nil {
+		respondWithError(res, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	mfaToken, ok := requestMFAToken(req, reqStruct.MFAToken)
+	if !ok {
+		respondWithError(res, http.StatusBadRequest, "invalid JSON structure")
+		return
+	}
+
+	userDB, ok := cfg.challengeUser(res, req, mfaToken)
+	if !ok {
+		return
+	}
+
+	cfg.beginEnrollment(res, req, userDB)
+}
+
+func (cfg *apiConfig) loginTOTP(res http.ResponseWriter, req *http.Request) {
+	reqStruct := struct {
+		MFAToken     *string `json:"mfaToken"`
+		Code         string  `json:"code"`
+		RecoveryCode string  `json:"recoveryCode"`
+	}{}
+
+	if err := decodeJSON(req, &reqStruct); err != nil {
+		respondWithError(res, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	mfaToken, ok := requestMFAToken(req, reqStruct.MFAToken)
+
+	if !ok || (reqStruct.Code == "" && reqStruct.RecoveryCode == "") {
+		respondWithError(res, http.StatusBadRequest, "invalid JSON structure")
+		return
+	}
+
+	userDB, ok := cfg.challengeUser(res, req, mfaToken)
+	if !ok {
+		return
+	}
+
+	var recoveryCodes []string
+
+	if userDB.TotpEnabledAt.Valid {
+		if !cfg.checkSecondFactor(res, req, userDB, reqStruct.Code, reqStruct.RecoveryCode) {
+			return
+		}
+	} else {
+		//a forced enrollment is finished by the first code of the new secret
+		recoveryCodes, ok = cfg.confirmEnrollment(res, req, userDB, reqStruct.Code)
+		if !ok {
+			return
+		}
+	}
+
+	//deleting the challenge is what uses it up, two requests racing with the
+	//same mfaToken cannot both log in
+	deleted, err := cfg.dbQueries.DeleteLoginChallenge(req.Context(), auth.HashToken(mfaToken))
+
+	if err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if deleted == 0 {
+		respondWithError(res, http.StatusUnauthorized, "invalid or expired mfaToken, login again")
+		return
+	}
+
+	clearMFACookie(res)
+	cfg.startSession(res, req, userDB, recoveryCodes)
+}
+
+func (cfg *apiConfig) enrollTOTP(res http.ResponseWriter, req *http.Request) {
+	userID := req.Context().Value(ctxUserID).(uuid.UUID)
+
+	userDB, err := cfg.dbQueries.GetUserFromID(req.Context(), userID)
+
+	if err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	cfg.beginEnrollment(res, req, userDB)
+}
+
+func (cfg *apiConfig) verifyTOTP(res http.ResponseWriter, req *http.Request) {
+	userID := req.Context().Value(ctxUserID).(uuid.UUID)
+
+	reqStruct := struct {
+		Code *string `json:"code"`
+	}{}
+
+	if err := decodeJSON(req, &reqStruct); err != nil {
+		respondWithError(res, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if reqStruct.Code == nil {
+		respondWithError(res, http.StatusBadRequest, "invalid JSON structure")
+		return
+	}
+
+	userDB, err := cfg.dbQueries.GetUserFromID(req.Context(), userID)
+
+	if err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	recoveryCodes, ok := cfg.confirmEnrollment(res, req, userDB, *reqStruct.Code)
+	if !ok {
+		return
+	}
+
+	respondWithJSON(res, http.StatusOK, struct {
+		RecoveryCodes []string `json:"recoveryCodes"`
+	}{recoveryCodes})
+}
+
+func (cfg *apiConfig) disableTOTP(res http.ResponseWriter, req *http.Request) {
+	userID := req.Context().Value(ctxUserID).(uuid.UUID)
+	role := req.Context().Value(ctxRole).(string)
+
+	if role == "admin" {
+		respondWithError(res, http.StatusForbidden, "admins must keep two-factor authentication enabled")
+		return
+	}
+
+	reqStruct := struct {
+		Code         string `json:"code"`
+		RecoveryCode string `json:"recoveryCode"`
+	}{}
+
+	if err := decodeJSON(req, &reqStruct); err != nil {
+		respondWithError(res, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	userDB, err := cfg.dbQueries.GetUserFromID(req.Context(), userID)
+
+	if err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if !userDB.TotpEnabledAt.Valid {
+		respondWithError(res, http.StatusBadRequest, "two-factor authentication is not enabled")
+		return
+	}
+
+	if !cfg.checkSecondFactor(res, req, userDB, reqStruct.Code, reqStruct.RecoveryCode) {
+		return
+	}
+
+	tx, err := cfg.db.BeginTx(req.Context(), nil)
+
+	if err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	defer tx.Rollback()
+
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	if err := qtx.DisableTOTP(req.Context(), userID); err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if err := qtx.DeleteUserRecoveryCodes(req.Context(), userID); err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	err = audit(req.Context(), qtx, auditEntry{
+		Action:     "totp.disable",
+		TargetType: "user",
+		TargetID:   userID,
+		CampusID:   userDB.CampusID,
+	})
+
+	if err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondWithJSON(res, http.StatusOK, struct {
+		Status string `json:"status"`
+	}{"Two-factor authentication has been disabled"})
+}
+
+func (cfg *apiConfig) regenerateRecoveryCodes(res http.ResponseWriter, req *http.Request) {
+	userID := req.Context().Value(ctxUserID).(uuid.UUID)
+
+	reqStruct := struct {
+		Code *string `json:"code"`
+	}{}
+
+	if err := decodeJSON(req, &reqStruct); err != nil {
+		respondWithError(res, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if reqStruct.Code == nil {
+		respondWithError(res, http.StatusBadRequest, "invalid JSON structure")
+		return
+	}
+
+	userDB, err := cfg.dbQueries.GetUserFromID(req.Context(), userID)
+
+	if err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if !userDB.TotpEnabledAt.Valid {
+		respondWithError(res, http.StatusBadRequest, "two-factor authentication is not enabled")
+		return
+	}
+
+	//only a code from the authenticator, a recovery code cannot make new ones
+	if !cfg.checkSecondFactor(res, req, userDB, *reqStruct.Code, "") {
+		return
+	}
+
+	tx, err := cfg.db.BeginTx(req.Context(), nil)
+
+	if err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	defer tx.Rollback()
+
+	recoveryCodes, err := issueRecoveryCodes(req.Context(), cfg.dbQueries.WithTx(tx), userID)
+
+	if err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondWithJSON(res, http.StatusOK, struct {
+		RecoveryCodes []string `json:"recoveryCodes"`
+	}{recoveryCodes})
+}
+
+func (cfg *apiConfig) getTOTPStatus(res http.ResponseWriter, req *http.Request) {
+	userID := req.Context().Value(ctxUserID).(uuid.UUID)
+
+	userDB, err := cfg.dbQueries.GetUserFromID(req.Context(), userID)
+
+	if err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	unused, err := cfg.dbQueries.CountUnusedRecoveryCodes(req.Context(), userID)
+
+	if err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondWithJSON(res, http.StatusOK, struct {
+		Enabled           bool  `json:"enabled"`
+		RecoveryCodesLeft int64 `json:"recoveryCodesLeft"`
+	}{
+		Enabled:           userDB.TotpEnabledAt.Valid,
+		RecoveryCodesLeft: unused,
+	})
+}
@@ -0,0 +1,310 @@
+package main
+
+import (
+	"archive/zip"
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// userExport is everything the data export of a user contains. Passwords,
+// two-factor secrets, tokens and the hashes of API keys are left out, as are
+// the citations an officer issued to others and the audit log, which are
+// records of the campus rather than of the user.
+type userExport struct {
+	ExportedAt  time.Time            `json:"exportedAt"`
+	Profile     userProfileJSON      `json:"profile"`
+	ParkingLogs []userExportLog      `json:"parkingLogs"`
+	Reviews     []userExportReview   `json:"reviews"`
+	Sessions    []userExportSession  `json:"sessions"`
+	Wallet      userExportWallet     `json:"wallet"`
+	Citations   []citationJSON       `json:"citations"`
+	Identities  []userExportIdentity `json:"identities"`
+	APIKeys     []apiKeyJSON         `json:"apiKeys"`
+}
+
+type userExportLog struct {
+	ID           uuid.UUID `json:"id"`
+	ParkingLotID uuid.UUID `json:"parkingLotID"`
+	EventType    string    `json:"eventType"`
+	Time         time.Time `json:"time"`
+}
+
+type userExportReview struct {
+	LotID       uuid.UUID `json:"lotID"`
+	LotName     string    `json:"lotName"`
+	Title       string    `json:"title"`
+	Description *string   `json:"description"`
+	Score       int32     `json:"score"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+type userExportSession struct {
+	ID         uuid.UUID `json:"id"`
+	UserAgent  string    `json:"userAgent"`
+	IP         string    `json:"ip"`
+	CreatedAt  time.Time `json:"createdAt"`
+	LastUsedAt time.Time `json:"lastUsedAt"`
+}
+
+type userExportWallet struct {
+	Balance      int64                   `json:"balance"`
+	Transactions []userExportTransaction `json:"transactions"`
+}
+
+type userExportTransaction struct {
+	ID          uuid.UUID      `json:"id"`
+	Type        string         `json:"type"`
+	Description sql.NullString `json:"description"`
+	Reference   sql.NullString `json:"reference"`
+	Amount      int64          `json:"amount"`
+	Balance     int64          `json:"balance"`
+	CreatedAt   time.Time      `json:"createdAt"`
+}
+
+// userExportIdentity is a single sign-on account linked to the user.
+type userExportIdentity struct {
+	Issuer      string    `json:"issuer"`
+	Subject     string    `json:"subject"`
+	Email       string    `json:"email"`
+	CreatedAt   time.Time `json:"createdAt"`
+	LastLoginAt time.Time `json:"lastLoginAt"`
+}
+
+func (cfg *apiConfig) buildUserExport(req *http.Request, userID uuid.UUID) (userExport, error) {
+	ctx := req.Context()
+
+	userDB, err := cfg.dbQueries.GetUserFromID(ctx, userID)
+	if err != nil {
+		return userExport{}, err
+	}
+
+	logsDB, err := cfg.dbQueries.GetLogsFromUserID(ctx, uuid.NullUUID{UUID: userID, Valid: true})
+	if err != nil {
+		return userExport{}, err
+	}
+
+	reviewsDB, err := cfg.dbQueries.GetReviewsFromUserID(ctx, userID)
+	if err != nil {
+		return userExport{}, err
+	}
+
+	sessionsDB, err := cfg.dbQueries.GetLiveSessionsFromUserID(ctx, userID)
+	if err != nil {
+		return userExport{}, err
+	}
+
+	//no wallet yet is a balance of 0
+	walletDB, err := cfg.dbQueries.GetWalletFromUserID(ctx, uuid.NullUUID{UUID: userID, Valid: true})
+	if err != nil && err != sql.ErrNoRows {
+		return userExport{}, err
+	}
+
+	transactionsDB, err := cfg.dbQueries.GetWalletTransactionsFromUserID(ctx, uuid.NullUUID{UUID: userID, Valid: true})
+	if err != nil {
+		return userExport{}, err
+	}
+
+	citationsDB, err := cfg.dbQueries.GetCitationsFromUserID(ctx, uuid.NullUUID{UUID: userID, Valid: true})
+	if err != nil {
+		return userExport{}, err
+	}
+
+	identitiesDB, err := cfg.dbQueries.GetIdentitiesFromUserID(ctx, userID)
+	if err != nil {
+		return userExport{}, err
+	}
+
+	//a null createdBy would list the keys of everyone
+	apiKeysDB, err := cfg.dbQueries.GetAPIKeys(ctx, uuid.NullUUID{UUID: userID, Valid: true})
+	if err != nil {
+		return userExport{}, err
+	}
+
+	out := userExport{
+		ExportedAt:  time.Now(),
+		Profile:     toUserProfileJSON(userDB),
+		ParkingLogs: make([]userExportLog, 0, len(logsDB)),
+		Reviews:     make([]userExportReview, 0, len(reviewsDB)),
+		Sessions:    make([]userExportSession, 0, len(sessionsDB)),
+		Wallet: userExportWallet{
+			Balance:      walletDB.Balance,
+			Transactions: make([]userExportTransaction, 0, len(transactionsDB)),
+		},
+		Citations:  make([]citationJSON, 0, len(citationsDB)),
+		Identities: make([]userExportIdentity, 0, len(identitiesDB)),
+		APIKeys:    make([]apiKeyJSON, 0, len(apiKeysDB)),
+	}
+
+	for _, l := range logsDB {
+		out.ParkingLogs = append(out.ParkingLogs, userExportLog{
+			ID:           l.ID,
+			ParkingLotID: l.ParkingLotID,
+			EventType:    l.EventType,
+			Time:         l.Time,
+		})
+	}
+
+	for _, r := range reviewsDB {
+		review := userExportReview{
+			LotID:     r.Lotid,
+			LotName:   r.Lotname,
+			Title:     r.Title,
+			Score:     r.Score,
+			CreatedAt: r.CreatedAt,
+			UpdatedAt: r.UpdatedAt,
+		}
+
+		if r.Description.Valid {
+			review.Description = &r.Description.String
+		}
+
+		out.Reviews = append(out.Reviews, review)
+	}
+
+	for _, s := range sessionsDB {
+		out.Sessions = append(out.Sessions, userExportSession{
+			ID:         s.ID,
+			UserAgent:  s.UserAgent,
+			IP:         s.Ip,
+			CreatedAt:  s.CreatedAt,
+			LastUsedAt: s.LastUsedAt,
+		})
+	}
+
+	for _, t := range transactionsDB {
+		out.Wallet.Transactions = append(out.Wallet.Transactions, userExportTransaction{
+			ID:          t.ID,
+			Type:        t.Kind,
+			Description: t.Description,
+			Reference:   t.Reference,
+			Amount:      t.Amount,
+			Balance:     t.Balance,
+			CreatedAt:   t.CreatedAt,
+		})
+	}
+
+	for _, c := range citationsDB {
+		photosDB, err := cfg.dbQueries.GetCitationPhotos(ctx, c.ID)
+		if err != nil {
+			return userExport{}, err
+		}
+
+		citation := toCitationJSON(c)
+		citation.Photos = make([]string, 0, len(photosDB))
+
+		for _, p := range photosDB {
+			citation.Photos = append(citation.Photos, p.Url)
+		}
+
+		out.Citations = append(out.Citations, citation)
+	}
+
+	for _, i := range identitiesDB {
+		out.Identities = append(out.Identities, userExportIdentity{
+			Issuer:      i.Issuer,
+			Subject:     i.Subject,
+			Email:       i.Email,
+			CreatedAt:   i.CreatedAt,
+			LastLoginAt: i.LastLoginAt,
+		})
+	}
+
+	for _, k := range apiKeysDB {
+		out.APIKeys = append(out.APIKeys, toAPIKeyJSON(k))
+	}
+
+	return out, nil
+}
+
+// exportUser downloads the data the signed in user has, as one json file or a
+// zip with a json file per kind.
+func (cfg *apiConfig) exportUser(res http.ResponseWriter, req *http.Request) {
+	userID := req.Context().Value(ctxUserID).(uuid.UUID)
+
+	format := req.URL.Query().Get("format")
+	if format == "" {
+		format = "json"
+	}
+
+	if format != "json" && format != "zip" {
+		respondWithError(res, http.StatusBadRequest, "format must be json or zip")
+		return
+	}
+
+	export, err := cfg.buildUserExport(req, userID)
+
+	if err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	auditAfter(req.Context(), cfg.dbQueries, auditEntry{
+		Action:     "user.export",
+		TargetType: "user",
+		TargetID:   userID,
+		CampusID:   export.Profile.CampusID,
+	})
+
+	if format == "json" {
+		res.Header().Set("Content-Disposition", "attachment; filename=parkinggo-export.json")
+		respondWithJSON(res, http.StatusOK, export)
+		return
+	}
+
+	res.Header().Set("Content-Type", "application/zip")
+	res.Header().Set("Content-Disposition", "attachment; filename=parkinggo-export.zip")
+	res.WriteHeader(http.StatusOK)
+
+	zipWriter := zip.NewWriter(res)
+
+	files := []struct {
+		name string
+		data any
+	}{
+		{"profile.json", export.Profile},
+		{"parkingLogs.json", export.ParkingLogs},
+		{"reviews.json", export.Reviews},
+		{"sessions.json", export.Sessions},
+		{"wallet.json", export.Wallet},
+		{"citations.json", export.Citations},
+		{"identities.json", export.Identities},
+		{"apiKeys.json", export.APIKeys},
+	}
+
+	for _, f := range files {
+		if err = writeZipJSON(zipWriter, f.name, export.ExportedAt, f.data); err != nil {
+			break
+		}
+	}
+
+	if err == nil {
+		err = zipWriter.Close()
+	}
+
+	//the status is already sent, a failure can only cut the download short
+	if err != nil {
+		log.Printf("Error writing the data export of %s: %s", userID, err)
+	}
+}
+
+func writeZipJSON(zipWriter *zip.Writer, name string, modified time.Time, data any) error {
+	w, err := zipWriter.CreateHeader(&zip.FileHeader{
+		Name:     name,
+		Method:   zip.Deflate,
+		Modified: modified,
+	})
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "    ")
+
+	return encoder.Encode(data)
+}
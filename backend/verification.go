@@ -0,0 +1,209 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/Shayaan-Kashif/Database-Project/internal/auth"
+	"github.com/Shayaan-Kashif/Database-Project/internal/database"
+	"github.com/Shayaan-Kashif/Database-Project/internal/mail"
+	"github.com/google/uuid"
+)
+
+const (
+	verificationTokenTTL = 24 * time.Hour
+	// at most this many verification emails are sent to one account per
+	// verificationResendWindow
+	verificationResendLimit  = 3
+	verificationResendWindow = time.Hour
+)
+
+// mailSender picks how emails are delivered from MAIL_SENDER. smtp sends
+// through SMTP_ADDR (MailHog's localhost:1025 by default), anything else writes
+// the emails to MAIL_OUTBOX_DIR so no mail server is needed.
+func mailSender() mail.Sender {
+	from := os.Getenv("MAIL_FROM")
+	if from == "" {
+		from = "ParkingGO <no-reply@parkinggo.local>"
+	}
+
+	if os.Getenv("MAIL_SENDER") == "smtp" {
+		addr := os.Getenv("SMTP_ADDR")
+		if addr == "" {
+			addr = "localhost:1025"
+		}
+
+		return mail.SMTPSender{
+			Addr:     addr,
+			From:     from,
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+		}
+	}
+
+	dir := os.Getenv("MAIL_OUTBOX_DIR")
+	if dir == "" {
+		dir = "outbox"
+	}
+
+	return mail.OutboxSender{Dir: dir, From: from}
+}
+
+// appURL is where the frontend runs, links in emails point to it.
+func appURL() string {
+	if appURL := os.Getenv("APP_URL"); appURL != "" {
+		return appURL
+	}
+	return "http://localhost:3000"
+}
+
+// sendVerificationEmail emails a new verification token to the user. The token
+// only verifies email, older tokens of the user stay valid until they expire or
+// the email changes.
+func (cfg *apiConfig) sendVerificationEmail(ctx context.Context, userID uuid.UUID, email string) error {
+	token, err := auth.MakeRefreshToken()
+	if err != nil {
+		return err
+	}
+
+	err = cfg.dbQueries.CreateVerificationToken(ctx, database.CreateVerificationTokenParams{
+		TokenHash: auth.HashToken(token),
+		UserID:    userID,
+		ExpiresAt: time.Now().Add(verificationTokenTTL),
+		Email:     email,
+	})
+
+	if err != nil {
+		return err
+	}
+
+	return cfg.mail.Send(ctx, mail.Message{
+		To:      email,
+		Subject: "Verify your ParkingGO email",
+		Body: fmt.Sprintf(
+			"Welcome to ParkingGO!\n\nVerify your email by opening this link within 24 hours:\n%s/verify?token=%s\n\nOr use this code: %s\n",
+			appURL(), url.QueryEscape(token), token,
+		),
+	})
+}
+
+func (cfg *apiConfig) verifyEmail(res http.ResponseWriter, req *http.Request) {
+	reqStruct := struct {
+		Token *string `json:"token"`
+	}{}
+
+	if err := decodeJSON(req, &reqStruct); err != nil {
+		respondWithError(res, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if reqStruct.Token == nil {
+		respondWithError(res, http.StatusBadRequest, "invalid JSON structure")
+		return
+	}
+
+	tx, err := cfg.db.BeginTx(req.Context(), nil)
+
+	if err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	defer tx.Rollback()
+
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	//a token sent to an address the user no longer has verifies nothing
+	userID, err := qtx.MarkEmailVerifiedFromToken(req.Context(), auth.HashToken(*reqStruct.Token))
+
+	if err == sql.ErrNoRows {
+		respondWithError(res, http.StatusBadRequest, "invalid or expired token")
+		return
+	} else if err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if err := qtx.DeleteUserVerificationTokens(req.Context(), userID); err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondWithJSON(res, http.StatusOK, struct {
+		Status string `json:"status"`
+	}{"The email has been verified"})
+}
+
+// resendVerificationEmail emails a new verification token if email has an
+// unverified account that has not hit the rate limit. Nothing is reported
+// back, callers cannot tell the cases apart.
+func (cfg *apiConfig) resendVerificationEmail(ctx context.Context, email string) error {
+	userDB, err := cfg.dbQueries.GetUserFromEmail(ctx, email)
+	if err == sql.ErrNoRows {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if userDB.EmailVerifiedAt.Valid {
+		return nil
+	}
+
+	recent, err := cfg.dbQueries.CountRecentVerificationTokens(ctx, database.CountRecentVerificationTokensParams{
+		UserID:    userDB.ID,
+		CreatedAt: time.Now().Add(-verificationResendWindow),
+	})
+
+	if err != nil {
+		return err
+	}
+
+	if recent >= verificationResendLimit {
+		log.Printf("verification email rate limit reached for %s", userDB.ID)
+		return nil
+	}
+
+	return cfg.sendVerificationEmail(ctx, userDB.ID, userDB.Email)
+}
+
+// resendVerification always answers the same way, and as fast, so it cannot be
+// used to find out which emails have an unverified account.
+func (cfg *apiConfig) resendVerification(res http.ResponseWriter, req *http.Request) {
+	reqStruct := struct {
+		Email *string `json:"email"`
+	}{}
+
+	if err := decodeJSON(req, &reqStruct); err != nil {
+		respondWithError(res, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if reqStruct.Email == nil || *reqStruct.Email == "" {
+		respondWithError(res, http.StatusBadRequest, "invalid JSON structure")
+		return
+	}
+
+	go func(email string) {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		if err := cfg.resendVerificationEmail(ctx, email); err != nil {
+			log.Printf("Error sending the verification email: %s", err)
+		}
+	}(*reqStruct.Email)
+
+	respondWithJSON(res, http.StatusOK, struct {
+		Status string `json:"status"`
+	}{"If the email has an unverified account, a new verification email has been sent"})
+}
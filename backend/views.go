@@ -63,6 +63,18 @@ func (cfg *apiConfig) getReviewsFromLotID(res http.ResponseWriter, req *http.Req
 }
 
 func (cfg *apiConfig) getTopRatedLots(res http.ResponseWriter, req *http.Request) {
+	campusID, err := campusFilter(req)
+
+	if err != nil {
+		respondWithError(res, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if campusID.Valid {
+		cfg.getTopRatedLotsFromCampusID(res, req, campusID.UUID)
+		return
+	}
+
 	topRatedLotsDB, err := cfg.dbQueries.GetTopRatedLots(req.Context())
 
 	if err != nil {
@@ -91,6 +103,35 @@ func (cfg *apiConfig) getTopRatedLots(res http.ResponseWriter, req *http.Request
 	respondWithJSON(res, http.StatusOK, response)
 }
 
+func (cfg *apiConfig) getTopRatedLotsFromCampusID(res http.ResponseWriter, req *http.Request, campusID uuid.UUID) {
+	topRatedLotsDB, err := cfg.dbQueries.GetTopRatedLotsFromCampusID(req.Context(), campusID)
+
+	if err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	response := make([]struct {
+		ID       uuid.UUID `json:"id"`
+		Name     string    `json:"name"`
+		AvgScore string    `json:"avgScore"`
+	}, 0, len(topRatedLotsDB))
+
+	for _, u := range topRatedLotsDB {
+		response = append(response, struct {
+			ID       uuid.UUID `json:"id"`
+			Name     string    `json:"name"`
+			AvgScore string    `json:"avgScore"`
+		}{
+			ID:       u.Lotid,
+			Name:     u.Lotname,
+			AvgScore: u.Averagerating,
+		})
+	}
+
+	respondWithJSON(res, http.StatusOK, response)
+}
+
 func (cfg *apiConfig) getAvgTimeParkedFromUserID(res http.ResponseWriter, req *http.Request) {
 	userID := req.Context().Value(ctxUserID).(uuid.UUID)
 
@@ -113,7 +154,14 @@ func (cfg *apiConfig) getAvgTimeParkedFromUserID(res http.ResponseWriter, req *h
 }
 
 func (cfg *apiConfig) getCountOfLogsPerUser(res http.ResponseWriter, req *http.Request) {
-	countOfLogsDB, err := cfg.dbQueries.GetCountOfLogsPerUser(req.Context())
+	campusID, err := campusFilter(req)
+
+	if err != nil {
+		respondWithError(res, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	countOfLogsDB, err := cfg.dbQueries.GetCountOfLogsPerUser(req.Context(), campusID)
 
 	if err != nil {
 		respondWithError(res, http.StatusInternalServerError, err.Error())
@@ -121,20 +169,23 @@ func (cfg *apiConfig) getCountOfLogsPerUser(res http.ResponseWriter, req *http.R
 	}
 
 	response := make([]struct {
-		ID           uuid.UUID `json:"id"`
-		Name         string    `json:"name"`
-		TotalEntries int64     `json:"totalEntries"`
+		ID           uuid.UUID     `json:"id"`
+		Name         string        `json:"name"`
+		TotalEntries int64         `json:"totalEntries"`
+		CampusID     uuid.NullUUID `json:"campusID"`
 	}, 0, len(countOfLogsDB))
 
 	for _, u := range countOfLogsDB {
 		response = append(response, struct {
-			ID           uuid.UUID `json:"id"`
-			Name         string    `json:"name"`
-			TotalEntries int64     `json:"totalEntries"`
+			ID           uuid.UUID     `json:"id"`
+			Name         string        `json:"name"`
+			TotalEntries int64         `json:"totalEntries"`
+			CampusID     uuid.NullUUID `json:"campusID"`
 		}{
 			ID:           u.Userid.UUID,
 			Name:         u.Username.String,
 			TotalEntries: u.Totalentries,
+			CampusID:     u.CampusID,
 		})
 	}
 
@@ -220,7 +271,14 @@ func (cfg *apiConfig) getAverageLotRatingFromID(res http.ResponseWriter, req *ht
 }
 
 func (cfg *apiConfig) getCountOfReviewsPerUser(res http.ResponseWriter, req *http.Request) {
-	countOfReviewsDB, err := cfg.dbQueries.GetCountOfReviewsPerUser(req.Context())
+	campusID, err := campusFilter(req)
+
+	if err != nil {
+		respondWithError(res, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	countOfReviewsDB, err := cfg.dbQueries.GetCountOfReviewsPerUser(req.Context(), campusID)
 
 	if err != nil {
 		respondWithError(res, http.StatusInternalServerError, err.Error())
@@ -228,20 +286,23 @@ func (cfg *apiConfig) getCountOfReviewsPerUser(res http.ResponseWriter, req *htt
 	}
 
 	response := make([]struct {
-		ID           uuid.UUID `json:"id"`
-		Name         string    `json:"name"`
-		TotalReviews int64     `json:"totalReviews"`
+		ID           uuid.UUID     `json:"id"`
+		Name         string        `json:"name"`
+		TotalReviews int64         `json:"totalReviews"`
+		CampusID     uuid.NullUUID `json:"campusID"`
 	}, 0, len(countOfReviewsDB))
 
 	for _, u := range countOfReviewsDB {
 		response = append(response, struct {
-			ID           uuid.UUID `json:"id"`
-			Name         string    `json:"name"`
-			TotalReviews int64     `json:"totalReviews"`
+			ID           uuid.UUID     `json:"id"`
+			Name         string        `json:"name"`
+			TotalReviews int64         `json:"totalReviews"`
+			CampusID     uuid.NullUUID `json:"campusID"`
 		}{
 			ID:           u.Userid,
 			Name:         u.Username,
 			TotalReviews: u.Totalreviews,
+			CampusID:     u.CampusID,
 		})
 	}
 
@@ -249,7 +310,14 @@ func (cfg *apiConfig) getCountOfReviewsPerUser(res http.ResponseWriter, req *htt
 }
 
 func (cfg *apiConfig) getCountOfReviewsPerLot(res http.ResponseWriter, req *http.Request) {
-	countOfReviewsDB, err := cfg.dbQueries.GetCountOfReviewsPerLot(req.Context())
+	campusID, err := campusFilter(req)
+
+	if err != nil {
+		respondWithError(res, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	countOfReviewsDB, err := cfg.dbQueries.GetCountOfReviewsPerLot(req.Context(), campusID)
 
 	if err != nil {
 		respondWithError(res, http.StatusInternalServerError, err.Error())
@@ -260,6 +328,7 @@ func (cfg *apiConfig) getCountOfReviewsPerLot(res http.ResponseWriter, req *http
 		ID           uuid.UUID `json:"id"`
 		Name         string    `json:"name"`
 		TotalReviews int64     `json:"totalReviews"`
+		CampusID     uuid.UUID `json:"campusID"`
 	}, 0, len(countOfReviewsDB))
 
 	for _, u := range countOfReviewsDB {
@@ -267,10 +336,12 @@ func (cfg *apiConfig) getCountOfReviewsPerLot(res http.ResponseWriter, req *http
 			ID           uuid.UUID `json:"id"`
 			Name         string    `json:"name"`
 			TotalReviews int64     `json:"totalReviews"`
+			CampusID     uuid.UUID `json:"campusID"`
 		}{
 			ID:           u.Lotid,
 			Name:         u.Lotname,
 			TotalReviews: u.Totalreviews,
+			CampusID:     u.CampusID,
 		})
 	}
 
@@ -278,7 +349,14 @@ func (cfg *apiConfig) getCountOfReviewsPerLot(res http.ResponseWriter, req *http
 }
 
 func (cfg *apiConfig) getCountOfLogsPerLot(res http.ResponseWriter, req *http.Request) {
-	countOfLogsDB, err := cfg.dbQueries.GetCountOfLogsPerLot(req.Context())
+	campusID, err := campusFilter(req)
+
+	if err != nil {
+		respondWithError(res, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	countOfLogsDB, err := cfg.dbQueries.GetCountOfLogsPerLot(req.Context(), campusID)
 
 	if err != nil {
 		respondWithError(res, http.StatusInternalServerError, err.Error())
@@ -289,6 +367,7 @@ func (cfg *apiConfig) getCountOfLogsPerLot(res http.ResponseWriter, req *http.Re
 		ID           uuid.UUID `json:"id"`
 		Name         string    `json:"name"`
 		TotalEntries int64     `json:"totalEntries"`
+		CampusID     uuid.UUID `json:"campusID"`
 	}, 0, len(countOfLogsDB))
 
 	for _, u := range countOfLogsDB {
@@ -296,10 +375,12 @@ func (cfg *apiConfig) getCountOfLogsPerLot(res http.ResponseWriter, req *http.Re
 			ID           uuid.UUID `json:"id"`
 			Name         string    `json:"name"`
 			TotalEntries int64     `json:"totalEntries"`
+			CampusID     uuid.UUID `json:"campusID"`
 		}{
 			ID:           u.Lotid,
 			Name:         u.Lotname,
 			TotalEntries: u.Totalentries,
+			CampusID:     u.CampusID,
 		})
 	}
 
@@ -307,7 +388,14 @@ func (cfg *apiConfig) getCountOfLogsPerLot(res http.ResponseWriter, req *http.Re
 }
 
 func (cfg *apiConfig) getFullLots(res http.ResponseWriter, req *http.Request) {
-	fullLotsDB, err := cfg.dbQueries.GetFullLots(req.Context())
+	campusID, err := campusFilter(req)
+
+	if err != nil {
+		respondWithError(res, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	fullLotsDB, err := cfg.dbQueries.GetFullLots(req.Context(), campusID)
 
 	if err != nil {
 		respondWithError(res, http.StatusInternalServerError, err.Error())
@@ -319,6 +407,7 @@ func (cfg *apiConfig) getFullLots(res http.ResponseWriter, req *http.Request) {
 		Name          string    `json:"name"`
 		Slots         int32     `json:"slots"`
 		OccupiedSlots int32     `json:"occupiedSlots"`
+		CampusID      uuid.UUID `json:"campusID"`
 	}, 0, len(fullLotsDB))
 
 	for _, u := range fullLotsDB {
@@ -327,11 +416,13 @@ func (cfg *apiConfig) getFullLots(res http.ResponseWriter, req *http.Request) {
 			Name          string    `json:"name"`
 			Slots         int32     `json:"slots"`
 			OccupiedSlots int32     `json:"occupiedSlots"`
+			CampusID      uuid.UUID `json:"campusID"`
 		}{
 			ID:            u.ID,
 			Name:          u.Name,
 			Slots:         u.Slots,
 			OccupiedSlots: u.Occupiedslots,
+			CampusID:      u.CampusID,
 		})
 	}
 
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/Shayaan-Kashif/Database-Project/internal/database"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+	"github.com/pressly/goose/v3"
+)
+
+// openTestDB connects to TEST_DB_URL and migrates it to the latest schema.
+// Tests using it are skipped when TEST_DB_URL is not set, never point it at a
+// database with data you want to keep.
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	dbURL := os.Getenv("TEST_DB_URL")
+	if dbURL == "" {
+		t.Skip("TEST_DB_URL is not set")
+	}
+
+	config, err := pgx.ParseConfig(dbURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.DefaultQueryExecMode = pgx.QueryExecModeSimpleProtocol
+
+	db := stdlib.OpenDB(*config)
+	t.Cleanup(func() { db.Close() })
+
+	dir, err := useMigrations("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	goose.SetLogger(goose.NopLogger())
+
+	if err := goose.Up(db, dir); err != nil {
+		t.Fatal(err)
+	}
+
+	return db
+}
+
+// TestDailyLotEntriesDST checks the view counts entries in the day they have
+// in the campus' time zone, on days that are 23 and 25 hours long.
+func TestDailyLotEntriesDST(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+
+	qtx := database.New(db).WithTx(tx)
+
+	toronto, err := loadTimeZone("America/Toronto")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	campusDB, err := qtx.CreateCampus(ctx, database.CreateCampusParams{
+		Name:     "DST test " + uuid.NewString(),
+		TimeZone: "America/Toronto",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lotDB, err := qtx.CreateParkingLot(ctx, database.CreateParkingLotParams{
+		Name:     "DST lot",
+		Slots:    10,
+		CampusID: campusDB.ID,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	userDB, err := qtx.CreateUser(ctx, database.CreateUserParams{
+		Name:           "DST test",
+		Email:          uuid.NewString() + "@dst.test",
+		HashedPassword: "unused",
+		Role:           "user",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		day string
+		// local wall clock times of the entries
+		times [][2]int
+	}{
+		{"2025-03-09", [][2]int{{0, 30}, {1, 30}, {23, 30}}},
+		{"2025-11-02", [][2]int{{0, 30}, {1, 30}, {23, 30}}},
+	}
+
+	for _, tt := range tests {
+		day, err := time.ParseInLocation("2006-01-02", tt.day, toronto)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, hm := range tt.times {
+			at := time.Date(day.Year(), day.Month(), day.Day(), hm[0], hm[1], 0, 0, toronto)
+
+			_, err := qtx.CreateLogAt(ctx, database.CreateLogAtParams{
+				UserID:       uuid.NullUUID{UUID: userDB.ID, Valid: true},
+				ParkingLotID: lotDB.ID,
+				EventType:    "entry",
+				Time:         at,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	entriesDB, err := qtx.GetDailyEntriesFromLotID(ctx, lotDB.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := map[string]int64{}
+	for _, e := range entriesDB {
+		got[e.Day.Format("2006-01-02")] = e.Totalentries
+	}
+
+	if len(got) != len(tests) {
+		t.Fatalf("entries landed on the days %v, want only %d days", got, len(tests))
+	}
+
+	for _, tt := range tests {
+		if got[tt.day] != int64(len(tt.times)) {
+			t.Errorf("%s has %d entries, want %d (all days: %v)", tt.day, got[tt.day], len(tt.times), got)
+		}
+	}
+}
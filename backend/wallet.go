@@ -0,0 +1,403 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/Shayaan-Kashif/Database-Project/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	ledgerProviderAccount = "system:payment_provider"
+	ledgerParkingAccount  = "system:parking_revenue"
+	ledgerFineAccount     = "system:fine_revenue"
+)
+
+var (
+	errInsufficientFunds   = errors.New("insufficient wallet balance")
+	errNotRefundable       = errors.New("refundOf is not a parking fee or fine of that user")
+	errRefundExceedsCharge = errors.New("refund exceeds the amount charged")
+)
+
+func walletAccountName(userID uuid.UUID) string {
+	return "wallet:" + userID.String()
+}
+
+// postLedgerTransaction moves amount from one ledger account to another as a
+// single double-entry transaction. It must be called inside a db transaction.
+func postLedgerTransaction(ctx context.Context, qtx *database.Queries, kind, from, to string, amount int64, allowOverdraft bool, description, reference string, refundOf uuid.NullUUID) (database.LedgerTransaction, error) {
+	//lock accounts in name order so concurrent postings cannot deadlock
+	names := []string{from, to}
+	sort.Strings(names)
+
+	accounts := map[string]database.LedgerAccount{}
+
+	for _, name := range names {
+		account, err := qtx.GetLedgerAccountForUpdate(ctx, name)
+		if err != nil {
+			return database.LedgerTransaction{}, err
+		}
+		accounts[name] = account
+	}
+
+	fromAccount := accounts[from]
+	toAccount := accounts[to]
+
+	if fromAccount.UserID.Valid && !allowOverdraft && fromAccount.Balance < amount {
+		return database.LedgerTransaction{}, errInsufficientFunds
+	}
+
+	ledgerTx, err := qtx.CreateLedgerTransaction(ctx, database.CreateLedgerTransactionParams{
+		Kind: kind,
+		Description: sql.NullString{
+			String: description,
+			Valid:  description != "",
+		},
+		Reference: sql.NullString{
+			String: reference,
+			Valid:  reference != "",
+		},
+		RefundOf: refundOf,
+	})
+
+	if err != nil {
+		return database.LedgerTransaction{}, err
+	}
+
+	for _, entry := range []struct {
+		account database.LedgerAccount
+		amount  int64
+	}{
+		{fromAccount, -amount},
+		{toAccount, amount},
+	} {
+		balance := entry.account.Balance + entry.amount
+
+		_, err = qtx.CreateLedgerEntry(ctx, database.CreateLedgerEntryParams{
+			TransactionID: ledgerTx.ID,
+			AccountID:     entry.account.ID,
+			Amount:        entry.amount,
+			Balance:       balance,
+		})
+
+		if err != nil {
+			return database.LedgerTransaction{}, err
+		}
+
+		err = qtx.UpdateLedgerAccountBalance(ctx, database.UpdateLedgerAccountBalanceParams{
+			Balance: balance,
+			ID:      entry.account.ID,
+		})
+
+		if err != nil {
+			return database.LedgerTransaction{}, err
+		}
+	}
+
+	return ledgerTx, nil
+}
+
+// refundableCharge finds the parking fee or fine chargeID of userID and how
+// much of it is not refunded yet. The charge stays locked until commit, so
+// refunds of it cannot race.
+func refundableCharge(ctx context.Context, qtx *database.Queries, userID, chargeID uuid.UUID) (database.GetRefundableChargeRow, int64, error) {
+	charge, err := qtx.GetRefundableCharge(ctx, database.GetRefundableChargeParams{
+		ID:     chargeID,
+		UserID: uuid.NullUUID{UUID: userID, Valid: true},
+	})
+
+	if err == sql.ErrNoRows {
+		return charge, 0, errNotRefundable
+	} else if err != nil {
+		return charge, 0, err
+	}
+
+	refunded, err := qtx.GetRefundedAmount(ctx, uuid.NullUUID{UUID: chargeID, Valid: true})
+	if err != nil {
+		return charge, 0, err
+	}
+
+	return charge, charge.Amount - refunded, nil
+}
+
+// postWalletTransaction opens a db transaction and runs
+// postWalletTransactionTx in it.
+func (cfg *apiConfig) postWalletTransaction(ctx context.Context, userID uuid.UUID, kind string, amount int64, description, reference string, refundOf uuid.NullUUID) (database.LedgerTransaction, error) {
+	tx, err := cfg.db.BeginTx(ctx, nil)
+	if err != nil {
+		return database.LedgerTransaction{}, err
+	}
+
+	defer tx.Rollback()
+
+	ledgerTx, err := postWalletTransactionTx(ctx, cfg.dbQueries.WithTx(tx), userID, kind, amount, description, reference, refundOf)
+	if err != nil {
+		return database.LedgerTransaction{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return database.LedgerTransaction{}, err
+	}
+
+	return ledgerTx, nil
+}
+
+// postWalletTransactionTx makes sure the user's wallet exists and posts a
+// single ledger transaction against it. A refund names the charge in refundOf
+// and goes back from the account that charge was paid to.
+func postWalletTransactionTx(ctx context.Context, qtx *database.Queries, userID uuid.UUID, kind string, amount int64, description, reference string, refundOf uuid.NullUUID) (database.LedgerTransaction, error) {
+	wallet := walletAccountName(userID)
+
+	err := qtx.CreateLedgerAccount(ctx, database.CreateLedgerAccountParams{
+		Name:   wallet,
+		UserID: uuid.NullUUID{UUID: userID, Valid: true},
+	})
+
+	if err != nil {
+		return database.LedgerTransaction{}, err
+	}
+
+	from, to, allowOverdraft := wallet, ledgerParkingAccount, false
+
+	switch kind {
+	case "topup":
+		from, to = ledgerProviderAccount, wallet
+	case "parking_fee":
+	case "fine":
+		//fines are recorded even when the wallet cannot cover them
+		to, allowOverdraft = ledgerFineAccount, true
+	case "refund":
+		charge, remaining, err := refundableCharge(ctx, qtx, userID, refundOf.UUID)
+		if err != nil {
+			return database.LedgerTransaction{}, err
+		}
+
+		if amount > remaining {
+			return database.LedgerTransaction{}, errRefundExceedsCharge
+		}
+
+		from, to = charge.AccountName, wallet
+	default:
+		return database.LedgerTransaction{}, errors.New("incorrect transaction type")
+	}
+
+	if kind != "refund" {
+		refundOf = uuid.NullUUID{}
+	}
+
+	return postLedgerTransaction(ctx, qtx, kind, from, to, amount, allowOverdraft, description, reference, refundOf)
+}
+
+func (cfg *apiConfig) getWallet(res http.ResponseWriter, req *http.Request) {
+	userID := req.Context().Value(ctxUserID).(uuid.UUID)
+
+	balance := int64(0)
+
+	walletDB, err := cfg.dbQueries.GetWalletFromUserID(req.Context(), uuid.NullUUID{UUID: userID, Valid: true})
+
+	if err == nil {
+		balance = walletDB.Balance
+	} else if err != sql.ErrNoRows {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondWithJSON(res, http.StatusOK, struct {
+		Balance int64 `json:"balance"`
+	}{balance})
+}
+
+func (cfg *apiConfig) getWalletTransactions(res http.ResponseWriter, req *http.Request) {
+	userID := req.Context().Value(ctxUserID).(uuid.UUID)
+
+	transactionsDB, err := cfg.dbQueries.GetWalletTransactionsFromUserID(req.Context(), uuid.NullUUID{UUID: userID, Valid: true})
+
+	if err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	response := make([]struct {
+		ID          uuid.UUID      `json:"id"`
+		Type        string         `json:"type"`
+		Description sql.NullString `json:"description"`
+		Reference   sql.NullString `json:"reference"`
+		Amount      int64          `json:"amount"`
+		Balance     int64          `json:"balance"`
+		CreatedAt   time.Time      `json:"createdAt"`
+	}, 0, len(transactionsDB))
+
+	for _, u := range transactionsDB {
+		response = append(response, struct {
+			ID          uuid.UUID      `json:"id"`
+			Type        string         `json:"type"`
+			Description sql.NullString `json:"description"`
+			Reference   sql.NullString `json:"reference"`
+			Amount      int64          `json:"amount"`
+			Balance     int64          `json:"balance"`
+			CreatedAt   time.Time      `json:"createdAt"`
+		}{
+			ID:          u.ID,
+			Type:        u.Kind,
+			Description: u.Description,
+			Reference:   u.Reference,
+			Amount:      u.Amount,
+			Balance:     u.Balance,
+			CreatedAt:   u.CreatedAt,
+		})
+	}
+
+	respondWithJSON(res, http.StatusOK, response)
+}
+
+func (cfg *apiConfig) topUpWallet(res http.ResponseWriter, req *http.Request) {
+	userID := req.Context().Value(ctxUserID).(uuid.UUID)
+
+	reqStruct := struct {
+		Amount *int64 `json:"amount"`
+	}{}
+
+	if err := decodeJSON(req, &reqStruct); err != nil {
+		respondWithError(res, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if reqStruct.Amount == nil {
+		respondWithError(res, http.StatusBadRequest, "invalid JSON structure")
+		return
+	}
+
+	if *reqStruct.Amount <= 0 {
+		respondWithError(res, http.StatusBadRequest, "amount must be positive")
+		return
+	}
+
+	reference, err := cfg.payments.Charge(req.Context(), userID, *reqStruct.Amount)
+
+	if err != nil {
+		respondWithError(res, http.StatusPaymentRequired, err.Error())
+		return
+	}
+
+	ledgerTx, err := cfg.postWalletTransaction(req.Context(), userID, "topup", *reqStruct.Amount, "wallet top up", reference, uuid.NullUUID{})
+
+	if err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondWithJSON(res, http.StatusCreated, struct {
+		ID        uuid.UUID `json:"id"`
+		Reference string    `json:"reference"`
+	}{ledgerTx.ID, reference})
+}
+
+func (cfg *apiConfig) createWalletTransaction(res http.ResponseWriter, req *http.Request) {
+	reqStruct := struct {
+		UserID      *uuid.UUID `json:"userID"`
+		Type        *string    `json:"type"`
+		Amount      *int64     `json:"amount"`
+		Description string     `json:"description"`
+		Reference   string     `json:"reference"`
+		RefundOf    *uuid.UUID `json:"refundOf"`
+	}{}
+
+	if err := decodeJSON(req, &reqStruct); err != nil {
+		respondWithError(res, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if reqStruct.UserID == nil || reqStruct.Type == nil || reqStruct.Amount == nil {
+		respondWithError(res, http.StatusBadRequest, "invalid JSON structure")
+		return
+	}
+
+	if *reqStruct.Type != "parking_fee" && *reqStruct.Type != "fine" && *reqStruct.Type != "refund" {
+		respondWithError(res, http.StatusBadRequest, "incorrect type input")
+		return
+	}
+
+	if *reqStruct.Amount <= 0 {
+		respondWithError(res, http.StatusBadRequest, "amount must be positive")
+		return
+	}
+
+	refundOf := uuid.NullUUID{}
+
+	if *reqStruct.Type == "refund" {
+		if reqStruct.RefundOf == nil {
+			respondWithError(res, http.StatusBadRequest, "refunds need the refundOf transaction")
+			return
+		}
+		refundOf = uuid.NullUUID{UUID: *reqStruct.RefundOf, Valid: true}
+	}
+
+	userDB, err := cfg.dbQueries.GetUserFromID(req.Context(), *reqStruct.UserID)
+
+	if err == sql.ErrNoRows {
+		respondWithError(res, http.StatusNotFound, "no user exist for that userID")
+		return
+	} else if err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	allowed, err := cfg.canManageUser(req, userDB)
+
+	if err != nil {
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if !allowed {
+		respondWithError(res, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	ledgerTx, err := cfg.postWalletTransaction(req.Context(), userDB.ID, *reqStruct.Type, *reqStruct.Amount, reqStruct.Description, reqStruct.Reference, refundOf)
+
+	if err == errInsufficientFunds {
+		respondWithError(res, http.StatusPaymentRequired, err.Error())
+		return
+	}
+
+	if err == errNotRefundable || err == errRefundExceedsCharge {
+		respondWithError(res, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err != nil {
+		hasPgErr, message := handlePgConstraints(err)
+		if hasPgErr {
+			respondWithError(res, http.StatusBadRequest, message)
+			return
+		}
+
+		respondWithError(res, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	auditAfter(req.Context(), cfg.dbQueries, auditEntry{
+		Action:     "wallet.adjust",
+		TargetType: "wallet_transaction",
+		TargetID:   ledgerTx.ID,
+		CampusID:   userDB.CampusID,
+		After: struct {
+			UserID      uuid.UUID     `json:"userID"`
+			Type        string        `json:"type"`
+			Amount      int64         `json:"amount"`
+			Description string        `json:"description"`
+			Reference   string        `json:"reference"`
+			RefundOf    uuid.NullUUID `json:"refundOf"`
+		}{userDB.ID, *reqStruct.Type, *reqStruct.Amount, reqStruct.Description, reqStruct.Reference, ledgerTx.RefundOf},
+	})
+
+	respondWithJSON(res, http.StatusCreated, struct {
+		ID uuid.UUID `json:"id"`
+	}{ledgerTx.ID})
+}
@@ -1,6 +1,6 @@
 "use client";
 
-import { useState } from "react";
+import { useEffect, useState } from "react";
 import { useRouter } from "next/navigation";
 import { cn } from "@/lib/utils";
 import { Button } from "@/components/ui/button";
@@ -28,6 +28,120 @@ export function LoginForm({
   const [submitting, setSubmitting] = useState(false);
   const [error, setError] = useState<string | null>(null);
 
+  // second step, for users with two-factor authentication and every admin.
+  // after campus SSO the token is in an HttpOnly cookie instead, kept as ""
+  const [mfaToken, setMfaToken] = useState<string | null>(null);
+  const [enrollment, setEnrollment] = useState<{ secret: string; uri: string } | null>(null);
+  const [code, setCode] = useState("");
+  const [recoveryCodes, setRecoveryCodes] = useState<string[] | null>(null);
+
+  const [methods, setMethods] = useState({ password: true, sso: false });
+
+  useEffect(() => {
+    fetch("http://localhost:8080/api/auth/methods")
+      .then((res) => res.json())
+      .then(setMethods)
+      .catch(() => {});
+
+    // campus SSO comes back here with the second step or the reason it failed
+    const params = new URLSearchParams(window.location.search);
+    const ssoError = params.get("ssoError");
+
+    if (ssoError) {
+      setError(ssoError);
+    }
+
+    if (params.get("mfaRequired") === "true") {
+      setMfaToken("");
+
+      if (params.get("enrollmentRequired") === "true") {
+        startEnrollment("").catch((err) => setError(err.message));
+      }
+    }
+  }, []);
+
+  function finishLogin(data: { access_token?: string; name: string; role: string }) {
+    const token = data.access_token;
+
+    if (!token) {
+      throw new Error("No access token returned");
+    }
+
+    // ⭐ Store in Zustand (in-memory)
+    setAuth({
+      token,
+      name: data.name,
+      role: data.role,
+    });
+
+    // ⭐ Optional: middleware cookie (not httpOnly)
+    document.cookie = `access_token=${token}; path=/; max-age=900; SameSite=Lax`;
+  }
+
+  function redirect() {
+    const redirectParam = new URLSearchParams(window.location.search).get("redirect");
+    router.push(redirectParam || "/dashboard");
+  }
+
+  async function startEnrollment(token: string) {
+    const res = await fetch("http://localhost:8080/api/login/totp/enroll", {
+      method: "POST",
+      headers: { "Content-Type": "application/json" },
+      body: JSON.stringify({ mfaToken: token || undefined }),
+      credentials: "include",
+    });
+
+    const data = await res.json();
+
+    if (!res.ok) {
+      throw new Error(data.error || "Two-factor enrollment failed");
+    }
+
+    setEnrollment(data);
+  }
+
+  async function onSubmitCode(e: React.FormEvent<HTMLFormElement>) {
+    e.preventDefault();
+    setError(null);
+    setSubmitting(true);
+
+    try {
+      // 6 digits are from the authenticator app, anything else is a recovery code
+      const token = mfaToken || undefined;
+      const body = /^\d{6}$/.test(code.trim())
+        ? { mfaToken: token, code: code.trim() }
+        : { mfaToken: token, recoveryCode: code.trim() };
+
+      const res = await fetch("http://localhost:8080/api/login/totp", {
+        method: "POST",
+        headers: { "Content-Type": "application/json" },
+        body: JSON.stringify(body),
+        credentials: "include",
+      });
+
+      const data = await res.json();
+
+      if (!res.ok) {
+        throw new Error(data.error || "Login failed");
+      }
+
+      finishLogin(data);
+
+      // the recovery codes of a new enrollment are only shown once
+      if (data.recoveryCodes) {
+        setRecoveryCodes(data.recoveryCodes);
+        return;
+      }
+
+      redirect();
+    } catch (err) {
+      const msg = err instanceof Error ? err.message : "Unexpected error";
+      setError(msg);
+    } finally {
+      setSubmitting(false);
+    }
+  }
+
   async function onSubmit(e: React.FormEvent<HTMLFormElement>) {
     e.preventDefault();
     setError(null);
@@ -48,30 +162,21 @@ export function LoginForm({
         throw new Error(data.error || "Login failed");
       }
 
-      // ⭐ Correct fields based on your backend response
-      const token = data.access_token;
-      const name = data.name;
-      const role = data.role;
+      if (data.mfaRequired) {
+        setMfaToken(data.mfaToken);
 
-      if (!token) {
-        throw new Error("No access token returned");
+        if (data.enrollmentRequired) {
+          await startEnrollment(data.mfaToken);
+        }
+        return;
       }
 
-      // ⭐ Store in Zustand (in-memory)
-      setAuth({
-        token,
-        name,
-        role,
-      });
+      finishLogin(data);
 
       //setUserSession(name, role); //Storing in session 
 
-      // ⭐ Optional: middleware cookie (not httpOnly)
-      document.cookie = `access_token=${token}; path=/; max-age=900; SameSite=Lax`;
-
       // Redirect user
-      const redirectParam = new URLSearchParams(window.location.search).get("redirect");
-      router.push(redirectParam || "/dashboard");
+      redirect();
 
     } catch (err) {
       const msg = err instanceof Error ? err.message : "Unexpected error";
@@ -85,6 +190,77 @@ export function LoginForm({
     <div className={cn("flex flex-col gap-6", className)} {...props}>
       <Card className="overflow-hidden p-0">
         <CardContent className="grid p-0 md:grid-cols-2">
+          {recoveryCodes ? (
+            <div className="p-6 md:p-8">
+              <FieldGroup>
+                <div className="flex flex-col items-center gap-2 text-center">
+                  <h1 className="text-2xl font-bold">Recovery codes</h1>
+                  <p className="text-muted-foreground text-balance">
+                    Save these codes, each one can replace a code from your
+                    authenticator app once. They will not be shown again.
+                  </p>
+                </div>
+
+                <pre className="rounded bg-muted p-4 text-center font-mono text-sm">
+                  {recoveryCodes.join("\n")}
+                </pre>
+
+                <Field>
+                  <Button type="button" onClick={redirect}>
+                    Continue
+                  </Button>
+                </Field>
+              </FieldGroup>
+            </div>
+          ) : mfaToken !== null ? (
+          <form className="p-6 md:p-8" onSubmit={onSubmitCode}>
+            <FieldGroup>
+              <div className="flex flex-col items-center gap-2 text-center">
+                <h1 className="text-2xl font-bold">Two-factor authentication</h1>
+                <p className="text-muted-foreground text-balance">
+                  {enrollment
+                    ? "Admins need two-factor authentication. Add this key to your authenticator app, then enter the code it shows."
+                    : "Enter the code from your authenticator app or a recovery code."}
+                </p>
+              </div>
+
+              {enrollment && (
+                <Field>
+                  <FieldLabel>Key</FieldLabel>
+                  <p className="break-all font-mono text-sm">{enrollment.secret}</p>
+                  <FieldDescription>
+                    <a href={enrollment.uri} className="text-blue-500 hover:underline">
+                      Open in authenticator app
+                    </a>
+                  </FieldDescription>
+                </Field>
+              )}
+
+              <Field>
+                <FieldLabel htmlFor="code">Code</FieldLabel>
+                <Input
+                  id="code"
+                  autoComplete="one-time-code"
+                  required
+                  value={code}
+                  onChange={(e) => setCode(e.target.value)}
+                />
+              </Field>
+
+              {error && (
+                <p className="text-sm text-red-600" role="alert">
+                  {error}
+                </p>
+              )}
+
+              <Field>
+                <Button type="submit" disabled={submitting}>
+                  {submitting ? "Verifying..." : "Verify"}
+                </Button>
+              </Field>
+            </FieldGroup>
+          </form>
+          ) : (
           <form className="p-6 md:p-8" onSubmit={onSubmit}>
             <FieldGroup>
               <div className="flex flex-col items-center gap-2 text-center">
@@ -94,6 +270,18 @@ export function LoginForm({
                 </p>
               </div>
 
+              {methods.sso && (
+                <Field>
+                  <Button variant="outline" asChild>
+                    <a href="http://localhost:8080/api/auth/oidc/login">
+                      Sign in with campus SSO
+                    </a>
+                  </Button>
+                </Field>
+              )}
+
+              {methods.password && (
+              <>
               <Field>
                 <FieldLabel htmlFor="email">Email</FieldLabel>
                 <Input
@@ -119,17 +307,19 @@ export function LoginForm({
                 />
               </Field>
 
-              {error && (
-                <p className="text-sm text-red-600" role="alert">
-                  {error}
-                </p>
-              )}
-
               <Field>
                 <Button type="submit" disabled={submitting}>
                   {submitting ? "Logging in..." : "Login"}
                 </Button>
               </Field>
+              </>
+              )}
+
+              {error && (
+                <p className="text-sm text-red-600" role="alert">
+                  {error}
+                </p>
+              )}
 
               <FieldDescription className="text-center">
                 Don&apos;t have an account?{" "}
@@ -139,6 +329,7 @@ export function LoginForm({
               </FieldDescription>
             </FieldGroup>
           </form>
+          )}
 
           <div className="bg-muted relative hidden md:block">
             <img
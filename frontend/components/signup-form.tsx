@@ -1,6 +1,6 @@
 "use client"
 
-import { useState } from "react"
+import { useEffect, useState } from "react"
 import { useRouter } from "next/navigation"
 import { cn } from "@/lib/utils"
 import { Button } from "@/components/ui/button"
@@ -19,13 +19,6 @@ import {
   FieldLabel,
 } from "@/components/ui/field"
 import { Input } from "@/components/ui/input"
-import {
-  Select,
-  SelectContent,
-  SelectItem,
-  SelectTrigger,
-  SelectValue,
-} from "@/components/ui/select"
 
 export function SignupForm({
   className,
@@ -36,11 +29,18 @@ export function SignupForm({
   const [email, setEmail] = useState("")
   const [password, setPassword] = useState("")
   const [confirmPassword, setConfirmPassword] = useState("")
-  const [role, setRole] = useState<"student" | "admin">("student")
-  const [adminCode, setAdminCode] = useState("")
+  const [invitation, setInvitation] = useState("")
   const [submitting, setSubmitting] = useState(false)
   const [error, setError] = useState<string | null>(null)
 
+  // admin and enforcement accounts sign up through an invitation link
+  useEffect(() => {
+    const invitationParam = new URLSearchParams(window.location.search).get("invitation")
+    if (invitationParam) {
+      setInvitation(invitationParam)
+    }
+  }, [])
+
   async function onSubmit(e: React.FormEvent<HTMLFormElement>) {
     e.preventDefault()
     setError(null)
@@ -57,12 +57,6 @@ export function SignupForm({
       return
     }
 
-    // Validate admin code if admin role is selected
-    if (role === "admin" && !adminCode.trim()) {
-      setError("Admin code is required for admin accounts")
-      return
-    }
-
     setSubmitting(true)
     try {
       const res = await fetch("http://localhost:8080/api/users", {
@@ -72,7 +66,7 @@ export function SignupForm({
           name,
           email, 
           password,
-          adminCode: role === "admin" ? adminCode : "" // Send admin code only if admin role is selected
+          invitation: invitation.trim()
         }),
         credentials: "include",
       })
@@ -129,38 +123,18 @@ export function SignupForm({
                 />
               </Field>
               <Field>
-                <FieldLabel htmlFor="role">Role</FieldLabel>
-                <Select value={role} onValueChange={(value: "student" | "admin") => {
-                  setRole(value)
-                  if (value === "student") {
-                    setAdminCode("") // Clear admin code when switching to student
-                  }
-                }}>
-                  <SelectTrigger id="role" className="w-full">
-                    <SelectValue placeholder="Select role" />
-                  </SelectTrigger>
-                  <SelectContent>
-                    <SelectItem value="student">Student</SelectItem>
-                    <SelectItem value="admin">Admin</SelectItem>
-                  </SelectContent>
-                </Select>
+                <FieldLabel htmlFor="invitation">Invitation Code</FieldLabel>
+                <Input
+                  id="invitation"
+                  type="text"
+                  placeholder="Optional"
+                  value={invitation}
+                  onChange={(e) => setInvitation(e.target.value)}
+                />
+                <FieldDescription>
+                  Only needed for admin and enforcement accounts, it comes with the invitation link.
+                </FieldDescription>
               </Field>
-              {role === "admin" && (
-                <Field>
-                  <FieldLabel htmlFor="adminCode">Admin Code</FieldLabel>
-                  <Input
-                    id="adminCode"
-                    type="text"
-                    placeholder="Enter admin code"
-                    required
-                    value={adminCode}
-                    onChange={(e) => setAdminCode(e.target.value)}
-                  />
-                  <FieldDescription>
-                    Enter the admin code to create an admin account.
-                  </FieldDescription>
-                </Field>
-              )}
               <Field>
                 <Field className="grid grid-cols-2 gap-4">
                   <Field>
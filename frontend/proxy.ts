@@ -34,6 +34,11 @@ export async function proxy(request: NextRequest) {
       credentials: "include",
       headers: {
         cookie: request.headers.get("cookie") || "",
+        // The backend records the device of the session, pass on the
+        // browser's instead of this server's (the backend only trusts
+        // x-forwarded-for from an address in TRUSTED_PROXIES)
+        "user-agent": request.headers.get("user-agent") || "",
+        "x-forwarded-for": request.headers.get("x-forwarded-for") || "",
       },
     });
 
@@ -62,6 +67,12 @@ export async function proxy(request: NextRequest) {
       sameSite: "lax",
     });
 
+    // The backend rotates the refresh token on every refresh, pass the new
+    // one on or the browser keeps presenting the old (now revoked) token
+    for (const cookie of refreshResponse.headers.getSetCookie()) {
+      response.headers.append("set-cookie", cookie);
+    }
+
     return response;
   } catch (err) {
     console.error("REFRESH ERROR:", err);